@@ -7,11 +7,23 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"time"
+	// The runtime image has no zoneinfo, embed it for TIMEZONE.
+	_ "time/tzdata"
 
+	"github.com/RTae/assessment/app/src/apperr"
 	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/metrics"
+	"github.com/RTae/assessment/app/src/migrations"
+	"github.com/RTae/assessment/app/src/openapi"
+	"github.com/RTae/assessment/app/src/services/budgets"
 	"github.com/RTae/assessment/app/src/services/expenses"
+	"github.com/RTae/assessment/app/src/services/recurring"
+	"github.com/RTae/assessment/app/src/services/users"
 	"github.com/RTae/assessment/app/src/settings"
+	"github.com/RTae/assessment/app/src/storage"
+	"github.com/RTae/assessment/app/src/tracing"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
@@ -32,45 +44,255 @@ func printBanner() {
 	)
 }
 
-func initRoute(e *echo.Echo, db *sql.DB) {
+func adminOnly(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if c.Get("role") != "admin" {
+			return c.JSON(
+				http.StatusForbidden,
+				apperr.ErrorResponse{Code: http.StatusForbidden, Message: "Admin permission required"},
+			)
+		}
+		return next(c)
+	}
+}
+
+func initRoute(e *echo.Echo, repo expenses.ExpenseRepository, userRepo users.UserRepository, budgetRepo budgets.BudgetRepository, recurringRepo recurring.RecurringRepository, attachmentRepo expenses.AttachmentRepository, idempotencyRepo expenses.IdempotencyRepository, files storage.Storage, tokens *users.Tokens, settings settings.Config, location *time.Location) {
+
+	tracker := budgets.NewTracker(budgetRepo, repo, location)
+	expensesHandler := expenses.CreateHandler(repo, expenses.Options{
+		Location:        location,
+		Budgets:         tracker,
+		Attachments:     attachmentRepo,
+		Files:           files,
+		AttachmentLimit: settings.AttachmentMaxSize,
+		Idempotency:     idempotencyRepo,
+		IdempotencyTTL:  settings.IdempotencyTTL,
+		RequireIfMatch:  settings.RequireIfMatch,
+		Audit:           true,
+	})
+	usersHandler := users.CreateHandler(userRepo, tokens)
+	budgetsHandler := budgets.CreateHandler(tracker)
+	recurringHandler := recurring.CreateHandler(recurringRepo, location)
 
-	expensesHandler := expenses.CreateHandler(db)
+	auth := e.Group("auth")
+	auth.POST("/signup", usersHandler.Signup)
+	auth.POST("/login", usersHandler.Login)
+	auth.POST("/refresh", usersHandler.Refresh)
 
-	g := e.Group("expenses")
+	g := e.Group("expenses", tokens.Authenticate)
 	g.POST("", expensesHandler.CreateExpense)
+	g.POST("/import", expensesHandler.ImportExpenses)
+	g.POST("/import/statement", expensesHandler.ImportStatement)
+	g.GET("/summary", expensesHandler.GetExpensesSummary)
+	g.GET("/export", expensesHandler.ExportExpenses)
 	g.GET("/:id", expensesHandler.GetExpenseByID)
 	g.PUT("/:id", expensesHandler.UpdateExpenseByID)
+	g.PATCH("/:id", expensesHandler.PatchExpenseByID)
 	g.GET("", expensesHandler.GetExpenses)
+	g.DELETE("/:id", expensesHandler.DeleteExpenseByID)
+	g.POST("/:id/restore", expensesHandler.RestoreExpenseByID)
+	g.GET("/:id/history", expensesHandler.GetExpenseHistory)
+	g.GET("/:id/history/:revision", expensesHandler.GetExpenseRevision)
+	g.POST("/:id/history/:revision/revert", expensesHandler.RevertExpense)
+	g.POST("/:id/attachments", expensesHandler.CreateAttachment)
+	g.GET("/:id/attachments", expensesHandler.GetAttachments)
+	g.GET("/:id/attachments/:attachment_id", expensesHandler.GetAttachmentByID)
+	g.DELETE("/:id/attachments/:attachment_id", expensesHandler.DeleteAttachmentByID)
+
+	b := e.Group("budgets", tokens.Authenticate)
+	b.POST("", budgetsHandler.CreateBudget)
+	b.GET("", budgetsHandler.GetBudgets)
+	b.GET("/:id", budgetsHandler.GetBudgetByID)
+	b.PUT("/:id", budgetsHandler.UpdateBudgetByID)
+	b.DELETE("/:id", budgetsHandler.DeleteBudgetByID)
+	b.GET("/:id/status", budgetsHandler.GetBudgetStatus)
+
+	r := e.Group("recurring-expenses", tokens.Authenticate)
+	r.POST("", recurringHandler.CreateRecurring)
+	r.GET("", recurringHandler.GetRecurring)
+	r.GET("/:id", recurringHandler.GetRecurringByID)
+	r.PUT("/:id", recurringHandler.UpdateRecurringByID)
+	r.DELETE("/:id", recurringHandler.DeleteRecurringByID)
+
+	a := e.Group("admin", tokens.Authenticate, adminOnly)
+	a.GET("/expenses/unowned", expensesHandler.GetUnownedExpenses)
+	a.DELETE("/expenses/:id", expensesHandler.PurgeExpenseByID)
+	a.PUT("/expenses/:id/owner", expensesHandler.AssignExpenseOwner)
 
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, "OK")
 	})
+	e.GET("/openapi.json", openapi.Spec)
+	e.GET("/docs", openapi.Docs)
+	e.GET("/metrics", metrics.Default.Handler)
 }
 
-func initMiddleware(e *echo.Echo, db *sql.DB) {
+func initMiddleware(e *echo.Echo, db *sql.DB, tracer *tracing.Tracer, settings settings.Config) {
 	e.Logger.SetLevel(log.INFO)
+	e.Validator = expenses.NewValidator()
+	e.HTTPErrorHandler = apperr.HTTPErrorHandler
+	if db != nil {
+		metrics.RegisterDB(metrics.Default, db)
+	}
+	e.Use(middleware.RequestID())
+	if tracer != nil {
+		e.Use(tracing.Middleware(tracer))
+	}
+	e.Use(metrics.Middleware(metrics.Default))
 	e.Use(middleware.Logger())
+	e.Use(apperr.ProblemDetails)
 	e.Use(middleware.Recover())
-	e.Use(middleware.BasicAuth(func(username, password string, c echo.Context) (bool, error) {
-		if username == "user" || password == "123qweasdzxc" {
-			return true, nil
+}
+
+// newTracer returns the tracer TRACES_EXPORTER asks for, or nil for none.
+func newTracer(settings settings.Config) *tracing.Tracer {
+	var exporter tracing.Exporter
+	switch settings.TracesExporter {
+	case "none", "":
+		return nil
+	case "otlp":
+		exporter = tracing.NewOTLPExporter(settings.OTLPEndpoint, settings.ServiceName)
+	case "stdout":
+		exporter = tracing.NewWriterExporter(os.Stdout, settings.ServiceName)
+	case "file":
+		var err error
+		if exporter, err = tracing.NewFileExporter(settings.TracesFile, settings.ServiceName); err != nil {
+			log.Fatalf("can't open TRACES_FILE: %v", err)
 		}
-		return false, nil
-	}))
+	default:
+		log.Fatalf("invalid TRACES_EXPORTER %q", settings.TracesExporter)
+	}
+	return tracing.NewTracer(exporter, settings.TracesSampleRatio)
+}
 
+// runMigrate implements `server migrate status|up|down [steps]`.
+func runMigrate(settings settings.Config, args []string) {
+	database, close := handlers.OpenDB(settings, nil)
+	defer close()
+
+	migrator, err := migrations.New(database)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatal("migrate down steps must be a positive integer")
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+	default:
+		log.Fatalf("unknown migrate command %q, expected status, up or down", command)
+	}
 }
 
 func main() {
 	settings := settings.Setting()
-	database, close := handlers.InitDB(settings)
-	defer close()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(settings, os.Args[2:])
+		return
+	}
+	if settings.JWTSecret == "" {
+		log.Fatal("JWT_SECRET must be set")
+	}
+	location, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		log.Fatalf("invalid TIMEZONE %q: %v", settings.Timezone, err)
+	}
+
+	tracer := newTracer(settings)
+
+	// Memory storage runs the server without a database for demos.
+	var database *sql.DB
+	repo := expenses.NewMemoryRepository()
+	userRepo := users.NewMemoryRepository()
+	budgetRepo := budgets.NewMemoryRepository()
+	recurringRepo := recurring.NewMemoryRepository(repo)
+	attachmentRepo := expenses.NewMemoryAttachmentRepository()
+	idempotencyRepo := expenses.NewMemoryIdempotencyRepository()
+	if settings.Storage != "memory" {
+		var close func()
+		database, close = handlers.InitDB(settings, tracer)
+		defer close()
+		repo = expenses.NewPostgresRepository(database)
+		userRepo = users.NewPostgresRepository(database)
+		budgetRepo = budgets.NewPostgresRepository(database)
+		recurringRepo = recurring.NewPostgresRepository(database)
+		attachmentRepo = expenses.NewPostgresAttachmentRepository(database)
+		idempotencyRepo = expenses.NewPostgresIdempotencyRepository(database)
+	}
+
+	var files storage.Storage
+	switch settings.AttachmentStorage {
+	case "memory":
+		files = storage.NewMemory()
+	case "s3":
+		if settings.S3Bucket == "" {
+			log.Fatal("S3_BUCKET must be set for ATTACHMENT_STORAGE=s3")
+		}
+		files = storage.NewS3(storage.S3Config{
+			Endpoint:        settings.S3Endpoint,
+			Region:          settings.S3Region,
+			Bucket:          settings.S3Bucket,
+			AccessKeyID:     settings.S3AccessKeyID,
+			SecretAccessKey: settings.S3SecretAccessKey,
+		})
+	default:
+		files = storage.NewLocal(settings.AttachmentDir)
+	}
+
+	if settings.AdminUsername != "" && settings.AdminPassword != "" {
+		if err := users.EnsureAdmin(context.Background(), userRepo, settings.AdminUsername, settings.AdminPassword); err != nil {
+			log.Fatal(err)
+		}
+	}
+	tokens := users.NewTokens(settings.JWTSecret, settings.AccessTokenTTL, settings.RefreshTokenTTL)
 
 	e := echo.New()
 	e.HideBanner = true
 	printBanner()
 
-	initMiddleware(e, database)
-	initRoute(e, database)
+	initMiddleware(e, database, tracer, settings)
+	initRoute(e, repo, userRepo, budgetRepo, recurringRepo, attachmentRepo, idempotencyRepo, files, tokens, settings, location)
+
+	worker := recurring.NewWorker(recurringRepo, location, settings.RecurringInterval)
+	worker.Start()
 
 	go func() {
 		if err := e.Start(settings.Port); err != nil && err != http.ErrServerClosed {
@@ -83,8 +305,14 @@ func main() {
 	<-shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	if err := worker.Stop(ctx); err != nil {
+		e.Logger.Error(err)
+	}
 	if err := e.Shutdown(ctx); err != nil {
 		e.Logger.Fatal(err)
 	}
+	if err := tracer.Shutdown(ctx); err != nil {
+		e.Logger.Error(err)
+	}
 	log.Print("Server stopped")
 }
@@ -0,0 +1,225 @@
+//go:build unit
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/openapi"
+	"github.com/RTae/assessment/app/src/services/budgets"
+	"github.com/RTae/assessment/app/src/services/expenses"
+	"github.com/RTae/assessment/app/src/services/recurring"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/RTae/assessment/app/src/settings"
+	"github.com/RTae/assessment/app/src/storage"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+// specPath turns an echo route path such as expenses/:id into the
+// template the document uses, /expenses/{id}. Routes added to a group
+// named without a leading slash keep that form in echo.
+func specPath(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return pathParam.ReplaceAllString(path, "{$1}")
+}
+
+// apiTest drives the routes of initRoute and checks every response against
+// the OpenAPI document.
+type apiTest struct {
+	t      *testing.T
+	e      *echo.Echo
+	doc    *openapi.Document
+	served map[openapi.Operation]bool
+	path   string
+	token  string
+}
+
+func newAPITest(t *testing.T) (*apiTest, users.UserRepository, *users.Tokens) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	initMiddleware(e, nil, nil, settings.Config{})
+	repo := expenses.NewMemoryRepository()
+	userRepo := users.NewMemoryRepository()
+	tokens := users.NewTokens("api test secret", time.Minute, time.Hour)
+	initRoute(e, repo, userRepo, budgets.NewMemoryRepository(), recurring.NewMemoryRepository(repo),
+		expenses.NewMemoryAttachmentRepository(), expenses.NewMemoryIdempotencyRepository(), storage.NewMemory(),
+		tokens, settings.Config{}, time.UTC)
+
+	a := &apiTest{t: t, e: e, doc: doc, served: map[openapi.Operation]bool{}}
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			a.path = specPath(c.Path())
+			a.served[openapi.Operation{Method: c.Request().Method, Path: a.path}] = true
+			return next(c)
+		}
+	})
+	return a, userRepo, tokens
+}
+
+// routes lists the operations registered on the router, leaving out the
+// catch-all routes echo adds for group middleware.
+func (a *apiTest) routes() []openapi.Operation {
+	notFound := runtime.FuncForPC(reflect.ValueOf(echo.NotFoundHandler).Pointer()).Name()
+	var ops []openapi.Operation
+	for _, r := range a.e.Routes() {
+		if r.Name == notFound {
+			continue
+		}
+		ops = append(ops, openapi.Operation{Method: r.Method, Path: specPath(r.Path)})
+	}
+	return ops
+}
+
+// do sends a request and checks its response against the document.
+func (a *apiTest) do(method, target, contentType string, body []byte, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	if a.token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+a.token)
+	}
+	for i := 0; i < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	res := httptest.NewRecorder()
+	a.e.ServeHTTP(res, req)
+
+	err := a.doc.ValidateResponse(method, a.path, res.Code, res.Header().Get(echo.HeaderContentType), res.Body.Bytes())
+	assert.NoError(a.t, err, "%s %s answered %d %s", method, target, res.Code, res.Body.String())
+	return res
+}
+
+func (a *apiTest) json(method, target string, body string, header ...string) *httptest.ResponseRecorder {
+	return a.do(method, target, echo.MIMEApplicationJSON, []byte(body), header...)
+}
+
+func (a *apiTest) decode(res *httptest.ResponseRecorder, v interface{}) {
+	if err := json.Unmarshal(res.Body.Bytes(), v); err != nil {
+		a.t.Fatal(err)
+	}
+}
+
+func (a *apiTest) upload(target, filename string, content []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", filename)
+	part.Write(content)
+	form.Close()
+	return a.do(http.MethodPost, target, form.FormDataContentType(), body.Bytes())
+}
+
+const testQIF = "!Type:Bank\nD01/02/2024\nT-50.25\nPTops\n^\n"
+
+func TestOpenAPI(t *testing.T) {
+	a, userRepo, tokens := newAPITest(t)
+
+	t.Run("Should describe every registered route and nothing else", func(t *testing.T) {
+		// Act
+		registered := a.routes()
+
+		// Assert
+		assert.ElementsMatch(t, a.doc.Operations(), registered)
+	})
+
+	t.Run("Should answer every route as described", func(t *testing.T) {
+		// Arrange
+		admin := users.User{Username: "admin", PasswordHash: "not used", Role: users.RoleAdmin}
+		if err := userRepo.Create(context.Background(), &admin); err != nil {
+			t.Fatal(err)
+		}
+		adminPair, _ := tokens.Issue(admin)
+
+		// Act
+		a.do(http.MethodGet, "/health", "", nil)
+		a.do(http.MethodGet, "/openapi.json", "", nil)
+		a.do(http.MethodGet, "/docs", "", nil)
+		a.do(http.MethodGet, "/metrics", "", nil)
+
+		credentials := `{"username":"somchai","password":"correct horse"}`
+		a.json(http.MethodPost, "/auth/signup", credentials)
+		a.json(http.MethodPost, "/auth/signup", credentials)
+		var pair users.TokenPair
+		a.decode(a.json(http.MethodPost, "/auth/login", credentials), &pair)
+		a.json(http.MethodPost, "/auth/refresh", fmt.Sprintf(`{"refresh_token":%q}`, pair.RefreshToken))
+		a.do(http.MethodGet, "/expenses", "", nil)
+		a.token = pair.AccessToken
+
+		a.json(http.MethodPost, "/expenses", `{"title":"rice","amount":50,"tags":["food"]}`)
+		a.json(http.MethodPost, "/expenses", `{"title":""}`)
+		a.do(http.MethodGet, "/expenses", "", nil)
+		a.do(http.MethodGet, "/expenses?limit=1", "", nil)
+		a.do(http.MethodGet, "/expenses/1", "", nil)
+		a.do(http.MethodGet, "/expenses/1", "", nil, expenses.HeaderIfNoneMatch, `"1"`)
+		a.do(http.MethodGet, "/expenses/abc", "", nil)
+		a.do(http.MethodGet, "/expenses/9", "", nil, echo.HeaderAccept, apperr.MIMEProblemJSON)
+		a.json(http.MethodPut, "/expenses/1", `{"title":"rice","amount":60,"tags":["food"]}`)
+		a.json(http.MethodPut, "/expenses/1", `{"title":"rice","amount":60}`, expenses.HeaderIfMatch, `"1"`)
+		a.do(http.MethodPatch, "/expenses/1", expenses.MIMEMergePatch, []byte(`{"note":"lunch"}`))
+		a.do(http.MethodPatch, "/expenses/1", expenses.MIMEJSONPatch, []byte(`[{"op":"test","path":"/note","value":"x"}]`))
+		a.do(http.MethodGet, "/expenses/1/history", "", nil)
+		a.do(http.MethodGet, "/expenses/1/history/1", "", nil)
+		a.do(http.MethodPost, "/expenses/1/history/1/revert", "", nil)
+		a.do(http.MethodGet, "/expenses/summary?group_by=tag,month", "", nil)
+		a.do(http.MethodGet, "/expenses/export?format=csv", "", nil)
+		a.do(http.MethodGet, "/expenses/export?format=jsonl", "", nil)
+		a.do(http.MethodPost, "/expenses/import", "text/csv", []byte("title,amount\nrice,50\ntea,-1\n"))
+		a.do(http.MethodPost, "/expenses/import/statement?format=qif&date_order=mdy", "application/octet-stream", []byte(testQIF))
+		a.upload("/expenses/1/attachments", "receipt.pdf", []byte("%PDF-1.4\n%receipt\n"))
+		a.upload("/expenses/1/attachments", "receipt.txt", []byte("not a receipt"))
+		a.do(http.MethodGet, "/expenses/1/attachments", "", nil)
+		a.do(http.MethodGet, "/expenses/1/attachments/1", "", nil)
+		a.do(http.MethodDelete, "/expenses/1/attachments/1", "", nil)
+		a.do(http.MethodDelete, "/expenses/1", "", nil)
+		a.do(http.MethodPost, "/expenses/1/restore", "", nil)
+
+		a.json(http.MethodPost, "/budgets", `{"tag":"food","limit":100}`)
+		a.json(http.MethodPost, "/budgets", `{"tag":"food","limit":100}`)
+		a.do(http.MethodGet, "/budgets", "", nil)
+		a.do(http.MethodGet, "/budgets/1", "", nil)
+		a.json(http.MethodPut, "/budgets/1", `{"tag":"food","period":"week","limit":20}`)
+		a.do(http.MethodGet, "/budgets/1/status", "", nil)
+		a.do(http.MethodDelete, "/budgets/1", "", nil)
+
+		a.json(http.MethodPost, "/recurring-expenses", `{"title":"rent","amount":100,"schedule":"0 9 1 * *"}`)
+		a.json(http.MethodPost, "/recurring-expenses", `{"title":"rent","amount":100,"schedule":"daily"}`)
+		a.do(http.MethodGet, "/recurring-expenses", "", nil)
+		a.do(http.MethodGet, "/recurring-expenses/1", "", nil)
+		a.json(http.MethodPut, "/recurring-expenses/1", `{"title":"rent","amount":120,"schedule":"0 9 1 * *"}`)
+		a.do(http.MethodDelete, "/recurring-expenses/1", "", nil)
+
+		a.do(http.MethodDelete, "/admin/expenses/1", "", nil)
+		a.token = adminPair.AccessToken
+		a.do(http.MethodDelete, "/admin/expenses/1", "", nil)
+		a.do(http.MethodGet, "/admin/expenses/unowned", "", nil)
+		a.json(http.MethodPut, "/admin/expenses/1/owner", `{"owner_id":1}`)
+		a.json(http.MethodPut, "/admin/expenses/1/owner", `{"owner_id":0}`)
+
+		// Assert
+		for _, op := range a.routes() {
+			assert.True(t, a.served[op], "%s %s is not exercised", op.Method, op.Path)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+// Package apperr classifies the failures of every service into a few kinds
+// and answers them the same way on every route.
+package apperr
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+// Kind is what went wrong, whatever store or driver reported it. It decides
+// the status a failure is answered with.
+type Kind int
+
+const (
+	Internal Kind = iota
+	NotFound
+	InvalidID
+	Conflict
+	Validation
+	Unavailable
+)
+
+// Error is a failure of a known Kind. Message is safe to show to clients and
+// Err is the cause, if any.
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %v", e.Message, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is makes every Error match the sentinel of its Kind, so
+// errors.Is(err, ErrNotFound) holds for any missing record.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Err == nil && t.Kind == e.Kind
+}
+
+// The sentinels of each Kind, returned as they are or wrapped with a cause.
+var (
+	ErrNotFound    = &Error{Kind: NotFound, Message: "Record not found"}
+	ErrInvalidID   = &Error{Kind: InvalidID, Message: "Param id must be integer"}
+	ErrConflict    = &Error{Kind: Conflict, Message: "Record was changed by another request"}
+	ErrValidation  = &Error{Kind: Validation, Message: "Record breaks a constraint"}
+	ErrUnavailable = &Error{Kind: Unavailable, Message: "Database is unavailable"}
+)
+
+// sqlStates maps the Postgres error codes with a meaning of their own.
+var sqlStates = map[pq.ErrorCode]*Error{
+	"23505": ErrConflict,    // unique_violation
+	"23P01": ErrConflict,    // exclusion_violation
+	"40001": ErrConflict,    // serialization_failure
+	"40P01": ErrConflict,    // deadlock_detected
+	"55P03": ErrConflict,    // lock_not_available
+	"57P01": ErrUnavailable, // admin_shutdown
+	"57P02": ErrUnavailable, // crash_shutdown
+	"57P03": ErrUnavailable, // cannot_connect_now
+}
+
+// sqlClasses maps the remaining codes by their class.
+var sqlClasses = map[pq.ErrorClass]*Error{
+	"08": ErrUnavailable, // connection_exception
+	"22": ErrValidation,  // data_exception
+	"23": ErrValidation,  // integrity_constraint_violation
+	"53": ErrUnavailable, // insufficient_resources
+}
+
+// AsError classifies err. sql.ErrNoRows is NotFound, Postgres errors go by
+// their SQLSTATE and a lost connection is Unavailable. Anything else is
+// Internal, with a message that keeps the details of err from clients.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var kind *Error
+	var pqErr *pq.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		kind = ErrNotFound
+	case errors.As(err, &pqErr):
+		if kind = sqlStates[pqErr.Code]; kind == nil {
+			kind = sqlClasses[pqErr.Code.Class()]
+		}
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), errors.As(err, &netErr):
+		kind = ErrUnavailable
+	}
+	if kind == nil {
+		return &Error{Kind: Internal, Message: http.StatusText(http.StatusInternalServerError), Err: err}
+	}
+	return &Error{Kind: kind.Kind, Message: kind.Message, Err: err}
+}
+
+// ByID classifies the error of a statement that looks a record up by the id
+// from the path. The id is the only text such a statement casts to an
+// integer, so Postgres failing to read one there means the id is malformed.
+// Any other error is returned as it is.
+func ByID(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "22P02" { // invalid_text_representation
+		return &Error{Kind: InvalidID, Message: ErrInvalidID.Message, Err: err}
+	}
+	return err
+}
+
+// Status is the HTTP status a failure of kind is answered with.
+func (k Kind) Status() int {
+	switch k {
+	case NotFound:
+		return http.StatusNotFound
+	case InvalidID:
+		return http.StatusUnprocessableEntity
+	case Conflict:
+		return http.StatusConflict
+	case Validation:
+		return http.StatusBadRequest
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
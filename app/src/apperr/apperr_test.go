@@ -0,0 +1,137 @@
+//go:build unit
+
+package apperr
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected *Error
+	}{
+		{"Should report no rows as not found", fmt.Errorf("get: %w", sql.ErrNoRows), ErrNotFound},
+		{"Should report malformed text as validation", &pq.Error{Code: "22P02"}, ErrValidation},
+		{"Should report a unique violation as conflict", &pq.Error{Code: "23505"}, ErrConflict},
+		{"Should report a serialization failure as conflict", &pq.Error{Code: "40001"}, ErrConflict},
+		{"Should report a check violation as validation", &pq.Error{Code: "23514"}, ErrValidation},
+		{"Should report a data exception as validation", &pq.Error{Code: "22003"}, ErrValidation},
+		{"Should report a connection exception as unavailable", &pq.Error{Code: "08006"}, ErrUnavailable},
+		{"Should report a shutdown as unavailable", &pq.Error{Code: "57P01"}, ErrUnavailable},
+		{"Should report a bad connection as unavailable", driver.ErrBadConn, ErrUnavailable},
+		{"Should keep a classified error", fmt.Errorf("history: %w", ErrInvalidID), ErrInvalidID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			e := AsError(tt.err)
+
+			// Assert
+			assert.Equal(t, tt.expected.Kind, e.Kind)
+			assert.Equal(t, tt.expected.Message, e.Message)
+			assert.True(t, errors.Is(e, tt.expected))
+		})
+	}
+
+	t.Run("Should report anything else as internal without its message", func(t *testing.T) {
+		// Act
+		e := AsError(&pq.Error{Code: "XX000", Message: "internal_error"})
+
+		// Assert
+		assert.Equal(t, Internal, e.Kind)
+		assert.Equal(t, "Internal Server Error", e.Message)
+		assert.Equal(t, "Internal Server Error: pq: internal_error", e.Error())
+		assert.False(t, errors.Is(e, ErrNotFound))
+	})
+}
+
+func TestByID(t *testing.T) {
+	t.Run("Should report malformed text as invalid id", func(t *testing.T) {
+		// Act
+		err := ByID(&pq.Error{Code: "22P02", Message: "invalid input syntax for type integer"})
+
+		// Assert
+		assert.True(t, errors.Is(err, ErrInvalidID))
+		assert.Equal(t, "Param id must be integer", AsError(err).Message)
+	})
+
+	t.Run("Should keep any other error", func(t *testing.T) {
+		// Arrange
+		for _, err := range []error{nil, sql.ErrNoRows, &pq.Error{Code: "22003"}} {
+			// Act
+			got := ByID(err)
+
+			// Assert
+			assert.Equal(t, err, got)
+		}
+	})
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		err      error
+		code     int
+		expected string
+	}{
+		{
+			"Should answer an echo error with its message",
+			http.MethodGet,
+			echo.ErrNotFound,
+			http.StatusNotFound,
+			`{"statusCode":404,"message":"Not Found"}`,
+		},
+		{
+			"Should answer a validation error with its details",
+			http.MethodPost,
+			&ValidationError{Details: []FieldError{{Field: "title", Message: "is required"}}},
+			http.StatusBadRequest,
+			`{"statusCode":400,"message":"Validation failed","details":[{"field":"title","message":"is required"}]}`,
+		},
+		{
+			"Should answer a classified error by its kind",
+			http.MethodGet,
+			&pq.Error{Code: "57P03"},
+			http.StatusServiceUnavailable,
+			`{"statusCode":503,"message":"Database is unavailable"}`,
+		},
+		{
+			"Should answer a head request without a body",
+			http.MethodHead,
+			sql.ErrNoRows,
+			http.StatusNotFound,
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			res := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(tt.method, "/expenses/1", nil), res)
+
+			// Act
+			HTTPErrorHandler(tt.err, c)
+
+			// Assert
+			assert.Equal(t, tt.code, res.Code)
+			assert.Equal(t, tt.expected, strings.TrimSpace(res.Body.String()))
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package apperr
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEProblemJSON is the media type of a Problem.
+const MIMEProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem detail, the form an error is sent in to
+// clients that ask for it.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// RequestID is the X-Request-ID of the request, which the error is
+	// logged with.
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// newProblem restates res, which the request to c was answered with.
+func newProblem(c echo.Context, res ErrorResponse) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(res.Code),
+		Status:    res.Code,
+		Detail:    res.Message,
+		Instance:  c.Request().URL.Path,
+		RequestID: RequestID(c),
+		Errors:    res.Details,
+	}
+}
+
+// acceptsProblem tells whether the client prefers application/problem+json
+// to application/json. Clients that name neither get the ErrorResponse they
+// always got.
+func acceptsProblem(req *http.Request) bool {
+	problem, plain := 0.0, 0.0
+	for _, part := range strings.Split(req.Header.Get(echo.HeaderAccept), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case MIMEProblemJSON:
+			problem = q
+		case echo.MIMEApplicationJSON:
+			plain = q
+		}
+	}
+	return problem > 0 && problem >= plain
+}
+
+// problemWriter holds back an error response sent as JSON so it can be
+// restated as a Problem once it is complete.
+type problemWriter struct {
+	http.ResponseWriter
+	status int
+	held   bool
+	body   bytes.Buffer
+}
+
+func (w *problemWriter) WriteHeader(code int) {
+	if code >= http.StatusBadRequest && strings.HasPrefix(w.Header().Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		w.status, w.held = code, true
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *problemWriter) Write(b []byte) (int, error) {
+	if w.held {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *problemWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.held {
+		f.Flush()
+	}
+}
+
+// ProblemDetails answers the clients that accept application/problem+json
+// with a Problem instead of an ErrorResponse, for every error of the routes
+// it wraps and of HTTPErrorHandler, whichever service they belong to.
+func ProblemDetails(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !acceptsProblem(c.Request()) {
+			return next(c)
+		}
+		res := c.Response()
+		w := &problemWriter{ResponseWriter: res.Writer}
+		res.Writer = w
+		if err := next(c); err != nil {
+			c.Error(err)
+		}
+		res.Writer = w.ResponseWriter
+		if !w.held {
+			return nil
+		}
+
+		body := w.body.Bytes()
+		var legacy ErrorResponse
+		if err := json.Unmarshal(body, &legacy); err == nil && legacy.Message != "" {
+			legacy.Code = w.status
+			if body, err = json.Marshal(newProblem(c, legacy)); err != nil {
+				return err
+			}
+			res.Header().Set(echo.HeaderContentType, MIMEProblemJSON)
+		}
+		w.ResponseWriter.WriteHeader(w.status)
+		_, err := w.ResponseWriter.Write(body)
+		return err
+	}
+}
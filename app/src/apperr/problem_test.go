@@ -0,0 +1,86 @@
+//go:build unit
+
+package apperr
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func problemRequest(t *testing.T, accept string, route echo.HandlerFunc) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.GET("/expenses/:id", route, ProblemDetails)
+	req := httptest.NewRequest(http.MethodGet, "/expenses/1", nil)
+	req.Header.Set(echo.HeaderAccept, accept)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	res := httptest.NewRecorder()
+	e.ServeHTTP(res, req)
+	return res
+}
+
+func TestProblemDetails(t *testing.T) {
+	notFound := func(c echo.Context) error {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Code: http.StatusNotFound, Message: "Record not found"})
+	}
+
+	t.Run("Should restate an error response as a problem", func(t *testing.T) {
+		// Act
+		res := problemRequest(t, MIMEProblemJSON, notFound)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, res.Code)
+		assert.Equal(t, MIMEProblemJSON, res.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"Record not found","instance":"/expenses/1","request_id":"req-1"}`, strings.TrimSpace(res.Body.String()))
+	})
+
+	t.Run("Should restate a returned error with its field errors", func(t *testing.T) {
+		// Act
+		res := problemRequest(t, "application/problem+json, application/json;q=0.5", func(c echo.Context) error {
+			return &ValidationError{Details: []FieldError{{Field: "title", Message: "is required"}}}
+		})
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		assert.Contains(t, res.Body.String(), `"detail":"Validation failed"`)
+		assert.Contains(t, res.Body.String(), `"errors":[{"field":"title","message":"is required"}]`)
+	})
+
+	t.Run("Should keep the details of a server error from the client", func(t *testing.T) {
+		// Act
+		res := problemRequest(t, MIMEProblemJSON, func(c echo.Context) error {
+			return sql.ErrConnDone
+		})
+
+		// Assert
+		assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+		assert.NotContains(t, res.Body.String(), sql.ErrConnDone.Error())
+	})
+
+	t.Run("Should keep the legacy shape unless a problem is preferred", func(t *testing.T) {
+		for _, accept := range []string{"", "*/*", echo.MIMEApplicationJSON, "application/json, application/problem+json;q=0.5"} {
+			// Act
+			res := problemRequest(t, accept, notFound)
+
+			// Assert
+			assert.Equal(t, `{"statusCode":404,"message":"Record not found"}`, strings.TrimSpace(res.Body.String()), accept)
+		}
+	})
+
+	t.Run("Should pass a successful response through", func(t *testing.T) {
+		// Act
+		res := problemRequest(t, MIMEProblemJSON, func(c echo.Context) error {
+			return c.JSON(http.StatusOK, map[string]int{"id": 1})
+		})
+
+		// Assert
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Contains(t, res.Body.String(), `"id":1`)
+	})
+}
@@ -0,0 +1,97 @@
+package apperr
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ValidationError struct {
+	Details []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Details))
+	for i, d := range e.Details {
+		messages[i] = d.Field + ": " + d.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+type ErrorResponse struct {
+	Code    int          `json:"statusCode"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// NewErrorResponse describes err the way every route answers it.
+func NewErrorResponse(err error) ErrorResponse {
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		message, ok := he.Message.(string)
+		if !ok {
+			message = http.StatusText(he.Code)
+		}
+		return ErrorResponse{Code: he.Code, Message: message}
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return ErrorResponse{Code: http.StatusBadRequest, Message: "Validation failed", Details: verr.Details}
+	}
+	e := AsError(err)
+	return ErrorResponse{Code: e.Kind.Status(), Message: e.Message}
+}
+
+// HTTPErrorHandler answers the errors returned by routes, middleware and
+// echo itself with an ErrorResponse. Server errors are logged with their
+// request id; clients only see their status.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	res := NewErrorResponse(err)
+	if res.Code >= http.StatusInternalServerError {
+		c.Logger().Errorf("request %s: %v", RequestID(c), err)
+	}
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(res.Code)
+	} else {
+		err = c.JSON(res.Code, res)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+// Respond answers err from within a handler and returns nil, so wrappers
+// such as the idempotency middleware see the status it is answered with.
+func Respond(c echo.Context, err error) error {
+	HTTPErrorHandler(err, c)
+	return nil
+}
+
+// InvalidBody answers a request whose body could not be bound. The cause is
+// logged rather than sent, since it describes the decoder and not the API.
+func InvalidBody(c echo.Context, err error) error {
+	c.Logger().Warnf("request %s: invalid body: %v", RequestID(c), err)
+	return c.JSON(
+		http.StatusUnprocessableEntity,
+		ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Invalid request body"},
+	)
+}
+
+// RequestID is the id the RequestID middleware gave the request, or the one
+// the client sent.
+func RequestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
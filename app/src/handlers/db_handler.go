@@ -1,45 +1,51 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/migrations"
 	"github.com/RTae/assessment/app/src/settings"
-	_ "github.com/lib/pq"
+	"github.com/RTae/assessment/app/src/tracing"
+	"github.com/lib/pq"
 )
 
 func migrateDB(db *sql.DB) {
-	var err error
-
-	createTb := `
-	CREATE TABLE IF NOT EXISTS expenses (
-		id SERIAL PRIMARY KEY,
-		title TEXT,
-		amount FLOAT,
-		note TEXT,
-		tags TEXT[]
-	);
-	`
-	_, err = db.Exec(createTb)
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatal("can't load migrations", err)
+	}
 
+	applied, err := migrator.Up(context.Background())
 	if err != nil {
-		log.Fatal("can't create table", err)
+		log.Fatal("can't migrate database", err)
+	}
+	for _, m := range applied {
+		log.Printf("Applied migration %d_%s", m.Version, m.Name)
 	}
 }
 
-func InitDB(settings settings.Config) (*sql.DB, func()) {
-	var err error
-	var db *sql.DB
-	db, err = sql.Open("postgres", settings.DatabaseUrl)
+// OpenDB opens the database, tracing its statements with tracer unless it
+// is nil.
+func OpenDB(settings settings.Config, tracer *tracing.Tracer) (*sql.DB, func()) {
+	connector, err := pq.NewConnector(settings.DatabaseUrl)
 	if err != nil {
 		log.Fatal("Connect to database error", err)
 	}
+	db := tracing.OpenDB(tracer, "postgresql", connector)
+
+	return db, func() { db.Close() }
+}
+
+func InitDB(settings settings.Config, tracer *tracing.Tracer) (*sql.DB, func()) {
+	db, close := OpenDB(settings, tracer)
 	migrateDB(db)
 	log.Println("Database Initialized")
 
-	return db, func() { db.Close() }
+	return db, close
 
 }
 
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unmatched is the route label of requests that match no route, so unknown
+// paths do not each add a series.
+const unmatched = "unmatched"
+
+var notFoundHandler = reflect.ValueOf(echo.NotFoundHandler).Pointer()
+
+// Route is the template of the route c matched, such as /expenses/:id, or
+// empty when it matched none. Echo reports the request path when no route
+// matched and the catch-all route of a group when no route of the group did.
+func Route(c echo.Context) string {
+	path := c.Path()
+	if path == "" || strings.HasSuffix(path, "/*") || reflect.ValueOf(c.Handler()).Pointer() == notFoundHandler {
+		return ""
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// Middleware counts and times every request on r by its route template,
+// such as /expenses/:id, rather than by its path.
+func Middleware(r *Registry) echo.MiddlewareFunc {
+	requests := r.NewCounter("http_requests_total",
+		"HTTP requests answered, by method, route and status.", "method", "route", "status")
+	duration := r.NewHistogram("http_request_duration_seconds",
+		"Time taken to answer HTTP requests, by method, route and status.", DefaultBuckets, "method", "route", "status")
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			route := Route(c)
+			if route == "" {
+				route = unmatched
+			}
+			method, status := c.Request().Method, strconv.Itoa(c.Response().Status)
+			requests.Inc(method, route, status)
+			duration.Observe(time.Since(start).Seconds(), method, route, status)
+			return nil
+		}
+	}
+}
+
+// RegisterDB registers gauges and counters for the connection pool of db.
+func RegisterDB(r *Registry, db *sql.DB) {
+	stat := func(fn func(s sql.DBStats) float64) func() float64 {
+		return func() float64 { return fn(db.Stats()) }
+	}
+	r.NewGaugeFunc("db_max_open_connections", "Maximum number of open connections to the database.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	r.NewGaugeFunc("db_open_connections", "Established connections, in use and idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	r.NewGaugeFunc("db_in_use_connections", "Connections currently in use.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	r.NewGaugeFunc("db_idle_connections", "Idle connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	r.NewCounterFunc("db_wait_count_total", "Connections waited for.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	r.NewCounterFunc("db_wait_duration_seconds_total", "Time spent waiting for a connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	r.NewCounterFunc("db_max_idle_closed_total", "Connections closed because of the idle pool limit.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	r.NewCounterFunc("db_max_idle_time_closed_total", "Connections closed because they stayed idle too long.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }))
+	r.NewCounterFunc("db_max_lifetime_closed_total", "Connections closed because they reached their maximum lifetime.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
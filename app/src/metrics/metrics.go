@@ -0,0 +1,290 @@
+// Package metrics keeps counters, gauges and histograms and exposes them in
+// the Prometheus text format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ContentType is the media type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency
+// histograms.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default is the registry served at /metrics.
+var Default = NewRegistry()
+
+// collector writes the samples of one metric family.
+type collector interface {
+	write(w io.Writer, name string)
+}
+
+type family struct {
+	help, kind string
+	collector  collector
+}
+
+// Registry holds metric families by name. Registering a name again
+// replaces the family.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]family{}}
+}
+
+func (r *Registry) register(name, help, kind string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families[name] = family{help: help, kind: kind, collector: c}
+}
+
+// NewCounter registers a counter partitioned by labels.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{vec: newVec(labels)}
+	r.register(name, help, "counter", c)
+	return c
+}
+
+// NewHistogram registers a histogram with the given bucket upper bounds,
+// partitioned by labels.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{vec: newVec(labels), buckets: buckets}
+	r.register(name, help, "histogram", h)
+	return h
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn when scraped.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, help, "gauge", funcCollector(fn))
+}
+
+// NewCounterFunc registers a counter whose value is read from fn when
+// scraped, for totals kept elsewhere.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, help, "counter", funcCollector(fn))
+}
+
+// WriteTo writes every family ordered by name.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	families := make(map[string]family, len(r.families))
+	for name, f := range r.families {
+		families[name] = f
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		f := families[name]
+		fmt.Fprintf(&buf, "# HELP %s %s\n", name, escapeHelp(f.help))
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", name, f.kind)
+		f.collector.write(&buf, name)
+	}
+	return buf.WriteTo(w)
+}
+
+// Handler answers with the samples of the registry.
+func (r *Registry) Handler(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderContentType, ContentType)
+	c.Response().WriteHeader(http.StatusOK)
+	_, err := r.WriteTo(c.Response())
+	return err
+}
+
+// Counter is a monotonically increasing value per combination of label
+// values.
+type Counter struct {
+	*vec
+}
+
+// Inc adds one to the series of values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds v, which must not be negative, to the series of values.
+func (c *Counter) Add(v float64, values ...string) {
+	s := c.series(values)
+	c.mu.Lock()
+	s.value += v
+	c.mu.Unlock()
+}
+
+// Value returns the current value of the series of values.
+func (c *Counter) Value(values ...string) float64 {
+	s := c.series(values)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return s.value
+}
+
+func (c *Counter) write(w io.Writer, name string) {
+	c.each(func(s *series) {
+		writeSample(w, name, c.labels, s.values, "", "", s.value)
+	})
+}
+
+// Histogram counts observations in cumulative buckets per combination of
+// label values.
+type Histogram struct {
+	*vec
+	buckets []float64
+}
+
+// Observe records v in the series of values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	s := h.series(values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s.counts == nil {
+		s.counts = make([]uint64, len(h.buckets))
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.value += v
+}
+
+func (h *Histogram) write(w io.Writer, name string) {
+	h.each(func(s *series) {
+		for i, bound := range h.buckets {
+			var n uint64
+			if s.counts != nil {
+				n = s.counts[i]
+			}
+			writeSample(w, name+"_bucket", h.labels, s.values, "le", formatFloat(bound), float64(n))
+		}
+		writeSample(w, name+"_bucket", h.labels, s.values, "le", "+Inf", float64(s.count))
+		writeSample(w, name+"_sum", h.labels, s.values, "", "", s.value)
+		writeSample(w, name+"_count", h.labels, s.values, "", "", float64(s.count))
+	})
+}
+
+type funcCollector func() float64
+
+func (fn funcCollector) write(w io.Writer, name string) {
+	writeSample(w, name, nil, nil, "", "", fn())
+}
+
+// series is the state of one combination of label values.
+type series struct {
+	values []string
+	value  float64
+	count  uint64
+	counts []uint64
+}
+
+// vec keeps the series of a metric by their label values.
+type vec struct {
+	labels []string
+
+	mu    sync.Mutex
+	byKey map[string]*series
+}
+
+func newVec(labels []string) *vec {
+	return &vec{labels: labels, byKey: map[string]*series{}}
+}
+
+func (v *vec) series(values []string) *series {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: got %d label values for labels %v", len(values), v.labels))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.byKey[key]
+	if !ok {
+		s = &series{values: append([]string(nil), values...)}
+		v.byKey[key] = s
+	}
+	return s
+}
+
+// each calls fn with every series ordered by label values while holding
+// the lock.
+func (v *vec) each(fn func(s *series)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	keys := make([]string, 0, len(v.byKey))
+	for key := range v.byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fn(v.byKey[key])
+	}
+}
+
+func writeSample(w io.Writer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", label, escapeLabel(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", extraLabel, extraValue)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(v))
+	b.WriteByte('\n')
+	io.WriteString(w, b.String())
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
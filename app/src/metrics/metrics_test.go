@@ -0,0 +1,127 @@
+//go:build unit
+
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(r *Registry) string {
+	var b strings.Builder
+	r.WriteTo(&b)
+	return b.String()
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("Should write counters per label values", func(t *testing.T) {
+		// Arrange
+		r := NewRegistry()
+		c := r.NewCounter("expenses_created_total", "Expenses recorded.", "source")
+		c.Inc("api")
+		c.Add(2, "import")
+		c.Inc("api")
+
+		// Act
+		got := scrape(r)
+
+		// Assert
+		assert.Equal(t, `# HELP expenses_created_total Expenses recorded.
+# TYPE expenses_created_total counter
+expenses_created_total{source="api"} 2
+expenses_created_total{source="import"} 2
+`, got)
+	})
+
+	t.Run("Should write cumulative histogram buckets", func(t *testing.T) {
+		// Arrange
+		r := NewRegistry()
+		h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+		h.Observe(0.05, "/a")
+		h.Observe(0.5, "/a")
+		h.Observe(3, "/a")
+
+		// Act
+		got := scrape(r)
+
+		// Assert
+		assert.Equal(t, `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 1
+latency_seconds_bucket{route="/a",le="1"} 2
+latency_seconds_bucket{route="/a",le="+Inf"} 3
+latency_seconds_sum{route="/a"} 3.55
+latency_seconds_count{route="/a"} 3
+`, got)
+	})
+
+	t.Run("Should escape label values and help", func(t *testing.T) {
+		// Arrange
+		r := NewRegistry()
+		r.NewCounter("odd_total", "Back\\slash\nand newline.", "value").Inc("\"quoted\"\n")
+
+		// Act
+		got := scrape(r)
+
+		// Assert
+		assert.Contains(t, got, `# HELP odd_total Back\\slash\nand newline.`)
+		assert.Contains(t, got, `odd_total{value="\"quoted\"\n"} 1`)
+	})
+
+	t.Run("Should read database pool statistics when scraped", func(t *testing.T) {
+		// Arrange
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		db.SetMaxOpenConns(7)
+		r := NewRegistry()
+		RegisterDB(r, db)
+
+		// Act
+		got := scrape(r)
+
+		// Assert
+		assert.Contains(t, got, "# TYPE db_max_open_connections gauge\ndb_max_open_connections 7\n")
+		assert.Contains(t, got, "# TYPE db_wait_count_total counter\ndb_wait_count_total 0\n")
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	// Arrange
+	r := NewRegistry()
+	e := echo.New()
+	e.Use(Middleware(r))
+	e.GET("/expenses/:id", func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return errors.New("boom")
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.GET("/metrics", r.Handler)
+
+	// Act
+	for _, path := range []string{"/expenses/1", "/expenses/2", "/expenses/0", "/nowhere/1"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	res := httptest.NewRecorder()
+	e.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, ContentType, res.Header().Get(echo.HeaderContentType))
+	got := res.Body.String()
+	assert.Contains(t, got, `http_requests_total{method="GET",route="/expenses/:id",status="204"} 2`+"\n")
+	assert.Contains(t, got, `http_requests_total{method="GET",route="/expenses/:id",status="500"} 1`+"\n")
+	assert.Contains(t, got, `http_requests_total{method="GET",route="unmatched",status="404"} 1`+"\n")
+	assert.Contains(t, got, `http_request_duration_seconds_count{method="GET",route="/expenses/:id",status="204"} 2`+"\n")
+	assert.NotContains(t, got, "/nowhere")
+}
@@ -0,0 +1,183 @@
+//go:build unit
+
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("Should load migrations ordered by version", func(t *testing.T) {
+		// Arrange
+		fsys := fstest.MapFS{
+			"0002_second.up.sql":   {Data: []byte("UP 2")},
+			"0001_first.up.sql":    {Data: []byte("UP 1")},
+			"0001_first.down.sql":  {Data: []byte("DOWN 1")},
+			"README.md":            {Data: []byte("ignored")},
+			"0002_second.down.sql": {Data: []byte("DOWN 2")},
+		}
+
+		// Act
+		migrations, err := Load(fsys)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, []Migration{
+				{Version: 1, Name: "first", Up: "UP 1", Down: "DOWN 1"},
+				{Version: 2, Name: "second", Up: "UP 2", Down: "DOWN 2"},
+			}, migrations)
+		}
+	})
+
+	t.Run("Should return error if up script is missing", func(t *testing.T) {
+		// Arrange
+		fsys := fstest.MapFS{"0001_first.down.sql": {Data: []byte("DOWN 1")}}
+
+		// Act
+		_, err := Load(fsys)
+
+		// Assert
+		assert.EqualError(t, err, "migration 1_first has no up script")
+	})
+
+	t.Run("Should load embedded migrations with contiguous versions", func(t *testing.T) {
+		// Act
+		m, err := New(nil)
+
+		// Assert
+		if assert.NoError(t, err) {
+			for i, mig := range m.migrations {
+				assert.Equal(t, i+1, mig.Version)
+				assert.NotEmpty(t, mig.Down)
+			}
+		}
+	})
+}
+
+func mockDatabase(t *testing.T) (*sql.DB, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	return db, mock, func() { db.Close() }
+}
+
+func expectLock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SELECT pg_advisory_lock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMigrator(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "first", Up: "CREATE TABLE first", Down: "DROP TABLE first"},
+		{Version: 2, Name: "second", Up: "CREATE TABLE second", Down: "DROP TABLE second"},
+	}
+
+	t.Run("Should apply only pending migrations", func(t *testing.T) {
+		// Arrange
+		db, mock, close := mockDatabase(t)
+		defer close()
+
+		expectLock(mock)
+		mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+			WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TABLE second").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(2, "second").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		m := &Migrator{db: db, migrations: migrations}
+
+		// Act
+		applied, err := m.Up(context.Background())
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, migrations[1:], applied)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("Should revert the latest applied migration", func(t *testing.T) {
+		// Arrange
+		db, mock, close := mockDatabase(t)
+		defer close()
+
+		expectLock(mock)
+		mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+			WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()).AddRow(2, time.Now()))
+		mock.ExpectBegin()
+		mock.ExpectExec("DROP TABLE second").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		m := &Migrator{db: db, migrations: migrations}
+
+		// Act
+		reverted, err := m.Down(context.Background(), 1)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, migrations[1:], reverted)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("Should report applied and pending migrations", func(t *testing.T) {
+		// Arrange
+		db, mock, close := mockDatabase(t)
+		defer close()
+
+		appliedAt := time.Date(2023, 1, 7, 0, 0, 0, 0, time.UTC)
+		expectLock(mock)
+		mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+			WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, appliedAt))
+		mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		m := &Migrator{db: db, migrations: migrations}
+
+		// Act
+		statuses, err := m.Status(context.Background())
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, []Status{
+				{Version: 1, Name: "first", AppliedAt: &appliedAt},
+				{Version: 2, Name: "second"},
+			}, statuses)
+		}
+	})
+
+	t.Run("Should stop and roll back when a migration fails", func(t *testing.T) {
+		// Arrange
+		db, mock, close := mockDatabase(t)
+		defer close()
+
+		expectLock(mock)
+		mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+			WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TABLE first").WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+		mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		m := &Migrator{db: db, migrations: migrations}
+
+		// Act
+		applied, err := m.Up(context.Background())
+
+		// Assert
+		assert.EqualError(t, err, "migration 1_first up: canceling query due to user request")
+		assert.Empty(t, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,2 @@
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS owner_id INTEGER REFERENCES users (id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS expenses_owner_id_idx ON expenses (owner_id);
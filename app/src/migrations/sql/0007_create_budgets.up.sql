@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS budgets (
+	id SERIAL PRIMARY KEY,
+	owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	period TEXT NOT NULL,
+	currency TEXT NOT NULL,
+	amount_limit NUMERIC(19, 4) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (owner_id, tag, period, currency)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS expenses_recurring_occurrence_idx;
+ALTER TABLE expenses DROP COLUMN IF EXISTS occurrence_at;
+ALTER TABLE expenses DROP COLUMN IF EXISTS recurring_id;
+DROP TABLE IF EXISTS recurring_expenses;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+	id SERIAL PRIMARY KEY,
+	owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	title TEXT NOT NULL,
+	amount NUMERIC(19, 4) NOT NULL,
+	currency TEXT NOT NULL,
+	note TEXT NOT NULL DEFAULT '',
+	tags TEXT[],
+	schedule TEXT NOT NULL,
+	starts_at TIMESTAMPTZ NOT NULL,
+	ends_at TIMESTAMPTZ,
+	next_run_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS recurring_expenses_next_run_at_idx ON recurring_expenses (next_run_at);
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS recurring_id INTEGER REFERENCES recurring_expenses (id) ON DELETE SET NULL;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS occurrence_at TIMESTAMPTZ;
+CREATE UNIQUE INDEX IF NOT EXISTS expenses_recurring_occurrence_idx ON expenses (recurring_id, occurrence_at);
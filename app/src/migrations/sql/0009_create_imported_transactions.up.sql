@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS imported_transactions (
+	owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	external_id TEXT NOT NULL,
+	imported_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (owner_id, external_id)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	key TEXT NOT NULL,
+	request_hash TEXT NOT NULL,
+	status_code INTEGER,
+	response BYTEA,
+	expires_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (owner_id, key)
+);
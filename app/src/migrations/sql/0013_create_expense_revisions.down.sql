@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS expense_revisions;
+DROP FUNCTION IF EXISTS expense_revisions_append_only();
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS expense_revisions (
+	id SERIAL PRIMARY KEY,
+	expense_id INTEGER NOT NULL,
+	owner_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	actor_id INTEGER NOT NULL,
+	request_id TEXT NOT NULL DEFAULT '',
+	reverts INTEGER,
+	before JSONB,
+	after JSONB,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS expense_revisions_expense_id_idx ON expense_revisions (expense_id, id);
+
+-- Revisions are an audit trail, so they may only ever be added.
+CREATE OR REPLACE FUNCTION expense_revisions_append_only() RETURNS TRIGGER AS $$
+BEGIN
+	RAISE EXCEPTION 'expense_revisions is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS expense_revisions_append_only ON expense_revisions;
+CREATE TRIGGER expense_revisions_append_only
+	BEFORE UPDATE OR DELETE ON expense_revisions
+	FOR EACH ROW EXECUTE PROCEDURE expense_revisions_append_only();
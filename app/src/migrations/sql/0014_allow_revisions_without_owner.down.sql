@@ -0,0 +1,4 @@
+ALTER TABLE expense_revisions DISABLE TRIGGER expense_revisions_append_only;
+DELETE FROM expense_revisions WHERE owner_id IS NULL;
+ALTER TABLE expense_revisions ENABLE TRIGGER expense_revisions_append_only;
+ALTER TABLE expense_revisions ALTER COLUMN owner_id SET NOT NULL;
@@ -0,0 +1,3 @@
+-- Expenses stored before owners were kept have none, and neither do the
+-- revisions of their purge.
+ALTER TABLE expense_revisions ALTER COLUMN owner_id DROP NOT NULL;
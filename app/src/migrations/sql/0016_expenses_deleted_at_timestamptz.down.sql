@@ -0,0 +1 @@
+ALTER TABLE expenses ALTER COLUMN deleted_at TYPE TIMESTAMP;
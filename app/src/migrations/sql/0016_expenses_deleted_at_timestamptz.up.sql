@@ -0,0 +1,4 @@
+-- deleted_at was the only timestamp without a time zone, so comparing it
+-- with NOW() depended on the session time zone. Rows written so far are read
+-- in the session time zone, the one NOW() was stored in.
+ALTER TABLE expenses ALTER COLUMN deleted_at TYPE TIMESTAMPTZ;
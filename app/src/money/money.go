@@ -0,0 +1,200 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of fractional digits an Amount keeps. It covers the
+// minor units of every supported currency and matches the NUMERIC(19,4)
+// column the amounts are stored in.
+const Scale = 4
+
+const DefaultCurrency = "THB"
+
+var scaleFactor = pow10(Scale)
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// minorUnits lists the supported ISO 4217 currencies with the number of
+// decimal places their minor unit allows.
+var minorUnits = map[string]int{
+	"AUD": 2, "BHD": 3, "CAD": 2, "CHF": 2, "CNY": 2, "DKK": 2,
+	"EUR": 2, "GBP": 2, "HKD": 2, "IDR": 2, "INR": 2, "JOD": 3,
+	"JPY": 0, "KHR": 2, "KRW": 0, "KWD": 3, "LAK": 2, "MMK": 2,
+	"MYR": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PHP": 2, "SEK": 2,
+	"SGD": 2, "THB": 2, "TND": 3, "TWD": 2, "USD": 2, "VND": 0,
+}
+
+// MinorUnits returns the decimal places allowed for currency and whether the
+// currency is supported.
+func MinorUnits(currency string) (int, bool) {
+	units, ok := minorUnits[currency]
+	return units, ok
+}
+
+// Amount is an exact decimal amount of money stored as an integer number of
+// 10^-Scale units.
+type Amount int64
+
+func pow10(n int) int64 {
+	v := int64(1)
+	for i := 0; i < n; i++ {
+		v *= 10
+	}
+	return v
+}
+
+// Parse reads a plain decimal such as "66900.10" or "-5" without going
+// through floating point.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" && frac == "" {
+		return 0, ErrInvalidAmount
+	}
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > Scale {
+		return 0, fmt.Errorf("%w: more than %d decimal places", ErrInvalidAmount, Scale)
+	}
+	for _, part := range []string{whole, frac} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return 0, ErrInvalidAmount
+			}
+		}
+	}
+
+	var units, fracUnits int64
+	var err error
+	if whole != "" {
+		units, err = strconv.ParseInt(whole, 10, 64)
+		if err != nil || units > (1<<63-1)/scaleFactor-1 {
+			return 0, fmt.Errorf("%w: out of range", ErrInvalidAmount)
+		}
+	}
+	if frac != "" {
+		fracUnits, _ = strconv.ParseInt(frac, 10, 64)
+		fracUnits *= pow10(Scale - len(frac))
+	}
+
+	a := Amount(units*scaleFactor + fracUnits)
+	if neg {
+		a = -a
+	}
+	return a, nil
+}
+
+// Decimals returns the number of significant fractional digits.
+func (a Amount) Decimals() int {
+	frac := int64(a) % scaleFactor
+	if frac < 0 {
+		frac = -frac
+	}
+	if frac == 0 {
+		return 0
+	}
+	n := Scale
+	for frac%10 == 0 {
+		frac /= 10
+		n--
+	}
+	return n
+}
+
+func (a Amount) String() string {
+	v := int64(a)
+	sign := ""
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	whole, frac := v/scaleFactor, v%scaleFactor
+	if frac == 0 {
+		return sign + strconv.FormatInt(whole, 10)
+	}
+	fracStr := fmt.Sprintf("%0*d", Scale, frac)
+	return sign + strconv.FormatInt(whole, 10) + "." + strings.TrimRight(fracStr, "0")
+}
+
+// Float64 returns the amount as a float for reporting, such as in metrics,
+// where losing precision on large amounts is acceptable.
+func (a Amount) Float64() float64 {
+	return float64(a) / float64(scaleFactor)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		s = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	v, err := Parse(s)
+	if err != nil {
+		kind := "number"
+		if strings.HasPrefix(s, `"`) {
+			kind = "string"
+		} else if s == "true" || s == "false" {
+			kind = "bool"
+		} else if strings.HasPrefix(s, "[") {
+			kind = "array"
+		} else if strings.HasPrefix(s, "{") {
+			kind = "object"
+		}
+		return &json.UnmarshalTypeError{Value: kind, Type: reflect.TypeOf(a).Elem()}
+	}
+	*a = v
+	return nil
+}
+
+// Scan reads NUMERIC values, which lib/pq returns as text.
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.parseInto(string(v))
+	case string:
+		return a.parseInto(v)
+	case int64:
+		*a = Amount(v * scaleFactor)
+		return nil
+	case float64:
+		return a.parseInto(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return fmt.Errorf("cannot scan %T into money.Amount", src)
+}
+
+func (a *Amount) parseInto(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
@@ -0,0 +1,98 @@
+//go:build unit
+
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Amount
+	}{
+		{"79", 790000},
+		{"66900.10", 669001000},
+		{"0.0001", 1},
+		{"-5.5", -55000},
+		{".5", 5000},
+	}
+
+	for _, tt := range tests {
+		t.Run("Should parse "+tt.input, func(t *testing.T) {
+			a, err := Parse(tt.input)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected, a)
+			}
+		})
+	}
+
+	for _, input := range []string{"", ".", "1.00001", "1e3", "ten", "1.2.3"} {
+		t.Run("Should reject "+input, func(t *testing.T) {
+			_, err := Parse(input)
+
+			assert.ErrorIs(t, err, ErrInvalidAmount)
+		})
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	assert.Equal(t, "66900.1", Amount(669001000).String())
+	assert.Equal(t, "79", Amount(790000).String())
+	assert.Equal(t, "-0.05", Amount(-500).String())
+	assert.Equal(t, 2, Amount(669001500).Decimals())
+	assert.Equal(t, 0, Amount(790000).Decimals())
+}
+
+func TestAmountJSON(t *testing.T) {
+	t.Run("Should round trip exact values", func(t *testing.T) {
+		var v struct {
+			Amount Amount `json:"amount"`
+		}
+
+		err := json.Unmarshal([]byte(`{"amount": 66900.10}`), &v)
+		raw, _ := json.Marshal(v)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, Amount(669001000), v.Amount)
+			assert.Equal(t, `{"amount":66900.1}`, string(raw))
+		}
+	})
+
+	t.Run("Should reject string amount", func(t *testing.T) {
+		var a Amount
+
+		err := json.Unmarshal([]byte(`"79"`), &a)
+
+		assert.EqualError(t, err, "json: cannot unmarshal string into Go value of type money.Amount")
+	})
+}
+
+func TestAmountScan(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      interface{}
+		expected Amount
+	}{
+		{"Should scan numeric text", []byte("66900.1000"), 669001000},
+		{"Should scan float", 79.0, 790000},
+		{"Should scan integer", int64(12), 120000},
+		{"Should scan null", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a Amount
+
+			err := a.Scan(tt.src)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Package openapi serves the OpenAPI description of the API and checks
+// responses against it.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+//go:embed docs.html
+var docs []byte
+
+// Spec answers with the OpenAPI document.
+func Spec(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, spec)
+}
+
+// Docs answers with a page that renders the OpenAPI document.
+func Docs(c echo.Context) error {
+	return c.HTMLBlob(http.StatusOK, docs)
+}
+
+// Document is the parsed OpenAPI document.
+type Document struct {
+	root map[string]interface{}
+}
+
+// Load parses the OpenAPI document served by Spec.
+func Load() (*Document, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(spec, &root); err != nil {
+		return nil, err
+	}
+	return &Document{root: root}, nil
+}
+
+// Operation is a method on a path template such as /expenses/{id}.
+type Operation struct {
+	Method string
+	Path   string
+}
+
+// Operations lists every operation the document describes, ordered by path
+// and method.
+func (d *Document) Operations() []Operation {
+	var ops []Operation
+	paths, _ := d.root["paths"].(map[string]interface{})
+	for path, item := range paths {
+		methods, _ := item.(map[string]interface{})
+		for method := range methods {
+			ops = append(ops, Operation{Method: strings.ToUpper(method), Path: path})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+	return ops
+}
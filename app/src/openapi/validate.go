@@ -0,0 +1,198 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ValidateResponse checks that the document describes a response with
+// status and contentType to method on path, and that body matches its
+// schema. Only application/json and +json bodies are checked against their schema.
+func (d *Document) ValidateResponse(method, path string, status int, contentType string, body []byte) error {
+	op, ok := lookup(d.root, "paths", path, strings.ToLower(method)).(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s %s is not described", method, path)
+	}
+	responses, _ := op["responses"].(map[string]interface{})
+	res, ok := responses[strconv.Itoa(status)]
+	if !ok {
+		if res, ok = responses["default"]; !ok {
+			return fmt.Errorf("%s %s does not describe status %d", method, path, status)
+		}
+	}
+	response, _ := d.resolve(res).(map[string]interface{})
+
+	content, _ := response["content"].(map[string]interface{})
+	if len(content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("%s %s %d describes no body", method, path, status)
+		}
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("%s %s %d: content type %q: %v", method, path, status, contentType, err)
+	}
+	media, ok := content[mediaType].(map[string]interface{})
+	if !ok {
+		if media, ok = content["*/*"].(map[string]interface{}); !ok {
+			return fmt.Errorf("%s %s %d does not describe content type %s", method, path, status, mediaType)
+		}
+	}
+	if mediaType != echo.MIMEApplicationJSON && !strings.HasSuffix(mediaType, "+json") {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("%s %s %d: %v", method, path, status, err)
+	}
+	if err := d.validate(media["schema"], value, "body"); err != nil {
+		return fmt.Errorf("%s %s %d: %v", method, path, status, err)
+	}
+	return nil
+}
+
+// lookup follows keys down nested objects.
+func lookup(node interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = m[key]
+	}
+	return node
+}
+
+// resolve follows a local $ref such as #/components/schemas/Expense.
+func (d *Document) resolve(node interface{}) interface{} {
+	for {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return node
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return node
+		}
+		node = lookup(d.root, strings.Split(strings.TrimPrefix(ref, "#/"), "/")...)
+	}
+}
+
+// validate checks value against the subset of JSON Schema the document
+// uses: type, enum, properties, required, additionalProperties, items,
+// oneOf and anyOf.
+func (d *Document) validate(node, value interface{}, at string) error {
+	schema, _ := d.resolve(node).(map[string]interface{})
+	if schema == nil {
+		return nil
+	}
+
+	if options, ok := schema["oneOf"].([]interface{}); ok {
+		matched := 0
+		for _, option := range options {
+			if d.validate(option, value, at) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s matches %d of the oneOf schemas", at, matched)
+		}
+	}
+	if options, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, option := range options {
+			matched = matched || d.validate(option, value, at) == nil
+		}
+		if !matched {
+			return fmt.Errorf("%s matches none of the anyOf schemas", at)
+		}
+	}
+
+	if t, ok := schema["type"]; ok && !hasType(t, value) {
+		return fmt.Errorf("%s is %s, not of type %v", at, kind(value), t)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == value
+		}
+		if !found {
+			return fmt.Errorf("%s is %v, not one of %v", at, value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := v[name.(string)]; !ok {
+				return fmt.Errorf("%s misses required property %s", at, name)
+			}
+		}
+		for name, field := range v {
+			property, ok := properties[name]
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s has undescribed property %s", at, name)
+				}
+				continue
+			}
+			if err := d.validate(property, field, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if err := d.validate(schema["items"], item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasType tells whether value is of t, a type name or a list of them.
+func hasType(t, value interface{}) bool {
+	if types, ok := t.([]interface{}); ok {
+		for _, t := range types {
+			if hasType(t, value) {
+				return true
+			}
+		}
+		return false
+	}
+	got := kind(value)
+	if t == "number" && got == "integer" {
+		return true
+	}
+	return t == got
+}
+
+// kind is the JSON Schema type of a decoded JSON value.
+func kind(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
@@ -0,0 +1,62 @@
+//go:build unit
+
+package openapi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateResponse(t *testing.T) {
+	doc, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expense := `{"id":1,"title":"rice","amount":50,"currency":"THB","note":"","tags":["food"],"spent_at":"2024-01-01T00:00:00Z","created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z"}`
+
+	t.Run("Should accept a response the document describes", func(t *testing.T) {
+		// Act
+		err := doc.ValidateResponse(http.MethodPost, "/expenses", http.StatusCreated, echo.MIMEApplicationJSONCharsetUTF8, []byte(expense))
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("Should accept an error as a problem", func(t *testing.T) {
+		// Act
+		err := doc.ValidateResponse(http.MethodGet, "/expenses/{id}", http.StatusNotFound, "application/problem+json",
+			[]byte(`{"type":"about:blank","title":"Not Found","status":404,"detail":"Record not found"}`))
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("Should reject a response that drifted from the document", func(t *testing.T) {
+		cases := []struct {
+			name        string
+			method      string
+			path        string
+			status      int
+			contentType string
+			body        string
+		}{
+			{"undescribed operation", http.MethodPost, "/expenses/{id}/archive", http.StatusOK, echo.MIMEApplicationJSON, `{}`},
+			{"undescribed status", http.MethodPost, "/expenses", http.StatusTeapot, echo.MIMEApplicationJSON, `{}`},
+			{"undescribed content type", http.MethodPost, "/expenses", http.StatusCreated, echo.MIMETextPlain, `rice`},
+			{"missing property", http.MethodPost, "/expenses", http.StatusCreated, echo.MIMEApplicationJSON, `{"id":1}`},
+			{"undescribed property", http.MethodPost, "/expenses", http.StatusCreated, echo.MIMEApplicationJSON, expense[:len(expense)-1] + `,"owner":"somchai"}`},
+			{"wrong type", http.MethodGet, "/expenses/{id}", http.StatusNotFound, echo.MIMEApplicationJSON, `{"statusCode":"404","message":"Record not found"}`},
+			{"body without content", http.MethodDelete, "/expenses/{id}", http.StatusNoContent, "", `{}`},
+		}
+		for _, c := range cases {
+			// Act
+			err := doc.ValidateResponse(c.method, c.path, c.status, c.contentType, []byte(c.body))
+
+			// Assert
+			assert.Error(t, err, c.name)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package budgets
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+)
+
+const maxTagLength = 30
+
+type handler struct {
+	tracker *Tracker
+}
+
+// Budget caps how much of currency may be spent on expenses tagged with Tag
+// in each period.
+type Budget struct {
+	ID        int          `json:"id"`
+	Tag       string       `json:"tag"`
+	Period    string       `json:"period"`
+	Currency  string       `json:"currency"`
+	Limit     money.Amount `json:"limit"`
+	OwnerID   int          `json:"-"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// Status is where a budget stands in the period from From (inclusive) to To
+// (exclusive). Remaining turns negative once the budget is overspent.
+type Status struct {
+	Budget
+	From      time.Time    `json:"from"`
+	To        time.Time    `json:"to"`
+	Spent     money.Amount `json:"spent"`
+	Remaining money.Amount `json:"remaining"`
+	Overspent bool         `json:"overspent"`
+}
+
+func CreateHandler(tracker *Tracker) *handler {
+	return &handler{tracker}
+}
+
+// window returns the period of the given kind that at falls in, using loc
+// to decide where days start. Weeks start on Monday like ISO weeks.
+func window(period string, at time.Time, loc *time.Location) (time.Time, time.Time) {
+	at = at.In(loc)
+	year, month, day := at.Date()
+	switch period {
+	case PeriodDay:
+		from := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		return from, from.AddDate(0, 0, 1)
+	case PeriodWeek:
+		from := time.Date(year, month, day-(int(at.Weekday())+6)%7, 0, 0, 0, 0, loc)
+		return from, from.AddDate(0, 0, 7)
+	case PeriodYear:
+		from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+		return from, from.AddDate(1, 0, 0)
+	}
+	from := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	return from, from.AddDate(0, 1, 0)
+}
+
+func (b Budget) validate() []apperr.FieldError {
+	var details []apperr.FieldError
+	add := func(field, format string, args ...interface{}) {
+		details = append(details, apperr.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(b.Tag) == "" {
+		add("tag", "is required")
+	} else if utf8.RuneCountInString(b.Tag) > maxTagLength {
+		add("tag", "must be at most %d characters", maxTagLength)
+	}
+
+	switch b.Period {
+	case PeriodDay, PeriodWeek, PeriodMonth, PeriodYear:
+	default:
+		add("period", "must be one of day, week, month, year")
+	}
+
+	minorUnits, knownCurrency := money.MinorUnits(b.Currency)
+	if b.Limit <= 0 {
+		add("limit", "must be greater than 0")
+	} else if knownCurrency && b.Limit.Decimals() > minorUnits {
+		add("limit", "must have at most %d decimal places for %s", minorUnits, b.Currency)
+	}
+	if !knownCurrency {
+		add("currency", "must be a supported ISO 4217 code")
+	}
+	return details
+}
+
+// bind reads a budget from the request body, defaulting the period to month
+// and the currency like expenses do.
+func bind(c echo.Context) (Budget, *apperr.ErrorResponse) {
+	var b Budget
+	if err := c.Bind(&b); err != nil {
+		return b, &apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Invalid request body"}
+	}
+	if b.Period == "" {
+		b.Period = PeriodMonth
+	}
+	if b.Currency == "" {
+		b.Currency = money.DefaultCurrency
+	}
+	if details := b.validate(); len(details) > 0 {
+		return b, &apperr.ErrorResponse{Code: http.StatusBadRequest, Message: "Validation failed", Details: details}
+	}
+	return b, nil
+}
+
+func currentOwner(c echo.Context) (int, *apperr.ErrorResponse) {
+	user, ok := users.CurrentUser(c)
+	if !ok {
+		return 0, &apperr.ErrorResponse{Code: http.StatusUnauthorized, Message: "Authentication required"}
+	}
+	return user.ID, nil
+}
+
+func (h *handler) localize(b *Budget) {
+	b.CreatedAt = b.CreatedAt.In(h.tracker.zone())
+	b.UpdatedAt = b.UpdatedAt.In(h.tracker.zone())
+}
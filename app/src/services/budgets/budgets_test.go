@@ -0,0 +1,131 @@
+//go:build unit
+
+package budgets
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testUser = users.User{ID: 1, Username: "somchai", Role: users.RoleUser}
+	testTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+)
+
+// ledger answers Spent from a fixed amount per tag and records the periods
+// it was asked about.
+type ledger struct {
+	spent   map[string]money.Amount
+	periods [][2]time.Time
+}
+
+func (l *ledger) Spent(ctx context.Context, owner int, tag, currency string, from, to time.Time) (money.Amount, error) {
+	l.periods = append(l.periods, [2]time.Time{from, to})
+	return l.spent[tag], nil
+}
+
+func TestWindow(t *testing.T) {
+	bangkok, _ := time.LoadLocation("Asia/Bangkok")
+	// 2024-03-31T20:00:00Z is Monday 1 April 03:00 in Bangkok.
+	at := time.Date(2024, 3, 31, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		period   string
+		from, to time.Time
+	}{
+		{PeriodDay, time.Date(2024, 4, 1, 0, 0, 0, 0, bangkok), time.Date(2024, 4, 2, 0, 0, 0, 0, bangkok)},
+		{PeriodWeek, time.Date(2024, 4, 1, 0, 0, 0, 0, bangkok), time.Date(2024, 4, 8, 0, 0, 0, 0, bangkok)},
+		{PeriodMonth, time.Date(2024, 4, 1, 0, 0, 0, 0, bangkok), time.Date(2024, 5, 1, 0, 0, 0, 0, bangkok)},
+		{PeriodYear, time.Date(2024, 1, 1, 0, 0, 0, 0, bangkok), time.Date(2025, 1, 1, 0, 0, 0, 0, bangkok)},
+	}
+
+	for _, tt := range tests {
+		t.Run("Should find the "+tt.period+" in the configured zone", func(t *testing.T) {
+			from, to := window(tt.period, at, bangkok)
+
+			assert.True(t, tt.from.Equal(from), "from %s", from)
+			assert.True(t, tt.to.Equal(to), "to %s", to)
+		})
+	}
+
+	t.Run("Should start weeks on monday", func(t *testing.T) {
+		sunday := time.Date(2024, 4, 7, 23, 0, 0, 0, time.UTC)
+
+		from, to := window(PeriodWeek, sunday, time.UTC)
+
+		assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), from)
+		assert.Equal(t, time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC), to)
+	})
+}
+
+func TestBudgetValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   Budget
+		expected []apperr.FieldError
+	}{
+		{
+			"Should accept a valid budget",
+			Budget{Tag: "food", Period: PeriodMonth, Currency: "THB", Limit: 50000000},
+			nil,
+		},
+		{
+			"Should reject missing fields",
+			Budget{Currency: "XXX"},
+			[]apperr.FieldError{
+				{Field: "tag", Message: "is required"},
+				{Field: "period", Message: "must be one of day, week, month, year"},
+				{Field: "limit", Message: "must be greater than 0"},
+				{Field: "currency", Message: "must be a supported ISO 4217 code"},
+			},
+		},
+		{
+			"Should reject a limit finer than the currency allows",
+			Budget{Tag: "food", Period: PeriodDay, Currency: "JPY", Limit: 5000},
+			[]apperr.FieldError{{Field: "limit", Message: "must have at most 0 decimal places for JPY"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.budget.validate())
+		})
+	}
+}
+
+func TestTrackerExceeded(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	for _, b := range []Budget{
+		{OwnerID: testUser.ID, Tag: "food", Period: PeriodMonth, Currency: "THB", Limit: 1000000},
+		{OwnerID: testUser.ID, Tag: "food", Period: PeriodMonth, Currency: "USD", Limit: 1000000},
+		{OwnerID: testUser.ID, Tag: "travel", Period: PeriodMonth, Currency: "THB", Limit: 1000000},
+		{OwnerID: testUser.ID, Tag: "beverage", Period: PeriodWeek, Currency: "THB", Limit: 1000000},
+		{OwnerID: 2, Tag: "food", Period: PeriodMonth, Currency: "THB", Limit: 1},
+	} {
+		b := b
+		assert.NoError(t, repo.Create(ctx, &b))
+	}
+	l := &ledger{spent: map[string]money.Amount{"food": 1000001, "travel": 2000000, "beverage": 1000000}}
+	tracker := NewTracker(repo, l, time.UTC)
+
+	// Act
+	exceeded, err := tracker.Exceeded(ctx, testUser.ID, []string{"food", "beverage"}, "THB", testTime)
+
+	// Assert
+	if assert.NoError(t, err) && assert.Len(t, exceeded, 1) {
+		assert.Equal(t, 1, exceeded[0].ID)
+		assert.Equal(t, money.Amount(1000001), exceeded[0].Spent)
+		assert.Equal(t, money.Amount(-1), exceeded[0].Remaining)
+		assert.True(t, exceeded[0].Overspent)
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), exceeded[0].From)
+		assert.Len(t, l.periods, 2)
+	}
+}
@@ -0,0 +1,28 @@
+package budgets
+
+import (
+	"net/http"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *handler) CreateBudget(c echo.Context) error {
+	ownerID, errRes := currentOwner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	b, errRes := bind(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	b.OwnerID = ownerID
+	if err := h.tracker.repo.Create(c.Request().Context(), &b); err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	h.localize(&b)
+	return c.JSON(http.StatusCreated, b)
+}
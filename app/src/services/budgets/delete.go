@@ -0,0 +1,21 @@
+package budgets
+
+import (
+	"net/http"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *handler) DeleteBudgetByID(c echo.Context) error {
+	ownerID, errRes := currentOwner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	if err := h.tracker.repo.Delete(c.Request().Context(), ownerID, c.Param("id")); err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
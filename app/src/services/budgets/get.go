@@ -0,0 +1,40 @@
+package budgets
+
+import (
+	"net/http"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *handler) GetBudgetByID(c echo.Context) error {
+	ownerID, errRes := currentOwner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	b, err := h.tracker.repo.Get(c.Request().Context(), ownerID, c.Param("id"))
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	h.localize(&b)
+	return c.JSON(http.StatusOK, b)
+}
+
+func (h *handler) GetBudgets(c echo.Context) error {
+	ownerID, errRes := currentOwner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	budgets, err := h.tracker.repo.List(c.Request().Context(), ownerID)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	for i := range budgets {
+		h.localize(&budgets[i])
+	}
+	return c.JSON(http.StatusOK, budgets)
+}
@@ -0,0 +1,286 @@
+//go:build unit
+
+package budgets
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTimestamps = `,"created_at":"2024-01-02T03:04:05Z","updated_at":"2024-01-02T03:04:05Z"`
+
+func TestCreateBudgetHandler(t *testing.T) {
+	t.Run("Should create a monthly budget in the default currency", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/budgets", strings.NewReader(`{"tag": "food", "limit": 5000}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("INSERT INTO budgets").
+			WithArgs(testUser.ID, "food", PeriodMonth, "THB", money.Amount(50000000)).
+			WillReturnRows(mock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, testTime, testTime))
+
+		h := handler{NewTracker(NewPostgresRepository(db), &ledger{}, nil)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		expected := `{"id":1,"tag":"food","period":"month","currency":"THB","limit":5000` + testTimestamps + `}`
+
+		// Act
+		err := h.CreateBudget(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusCreated, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("Should return conflict error if the budget already exists", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/budgets", strings.NewReader(`{"tag": "food", "limit": 5000}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("INSERT INTO budgets").WillReturnError(&pq.Error{Code: "23505"})
+
+		h := handler{NewTracker(NewPostgresRepository(db), &ledger{}, nil)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		expected := `{"statusCode":409,"message":"A budget for this tag, period and currency already exists"}`
+
+		// Act
+		err := h.CreateBudget(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusConflict, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+
+	t.Run("Should return bad request error if budget is not valid", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/budgets", strings.NewReader(`{"tag": "food", "period": "decade", "limit": 5000}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		h := handler{NewTracker(NewMemoryRepository(), &ledger{}, nil)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		expected := `{"statusCode":400,"message":"Validation failed","details":[{"field":"period","message":"must be one of day, week, month, year"}]}`
+
+		// Act
+		err := h.CreateBudget(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+}
+
+func TestBudgetByIDHandlers(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	food := Budget{OwnerID: testUser.ID, Tag: "food", Period: PeriodMonth, Currency: "THB", Limit: 50000000}
+	assert.NoError(t, repo.Create(ctx, &food))
+	other := Budget{OwnerID: 2, Tag: "food", Period: PeriodMonth, Currency: "THB", Limit: 1}
+	assert.NoError(t, repo.Create(ctx, &other))
+	h := handler{NewTracker(repo, &ledger{}, nil)}
+
+	tests := []struct {
+		name   string
+		method string
+		id     string
+		body   string
+		code   int
+		call   func(c echo.Context) error
+	}{
+		{"Should get own budget", http.MethodGet, "1", "", http.StatusOK, h.GetBudgetByID},
+		{"Should return not found error for someone else's budget", http.MethodGet, "2", "", http.StatusNotFound, h.GetBudgetByID},
+		{"Should return unprocess entity error if id is not integer", http.MethodGet, "x", "", http.StatusUnprocessableEntity, h.GetBudgetByID},
+		{"Should update own budget", http.MethodPut, "1", `{"tag": "food", "period": "week", "limit": 1000}`, http.StatusOK, h.UpdateBudgetByID},
+		{"Should not update someone else's budget", http.MethodPut, "2", `{"tag": "food", "limit": 1000}`, http.StatusNotFound, h.UpdateBudgetByID},
+		{"Should not delete someone else's budget", http.MethodDelete, "2", "", http.StatusNotFound, h.DeleteBudgetByID},
+		{"Should delete own budget", http.MethodDelete, "1", "", http.StatusNoContent, h.DeleteBudgetByID},
+		{"Should return not found error for a deleted budget", http.MethodGet, "1", "", http.StatusNotFound, h.GetBudgetByID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			req := httptest.NewRequest(tt.method, "/budgets", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			res := httptest.NewRecorder()
+			c := e.NewContext(req, res)
+			users.SetCurrentUser(c, testUser)
+			c.SetPath("/budgets/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+
+			// Act
+			err := tt.call(c)
+
+			// Assert
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.code, res.Code)
+			}
+		})
+	}
+}
+
+func TestGetBudgetByIDHandlerPostgres(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		expect   func(mock sqlmock.Sqlmock)
+		code     int
+		expected string
+	}{
+		{
+			"Should return unprocess entity error if id is not integer",
+			"x",
+			func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT (.+) FROM budgets").WithArgs("x", testUser.ID).
+					WillReturnError(&pq.Error{Code: "22P02"})
+			},
+			http.StatusUnprocessableEntity,
+			`{"statusCode":422,"message":"Param id must be integer"}`,
+		},
+		{
+			"Should return not found error if the budget does not exist",
+			"9",
+			func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT (.+) FROM budgets").WithArgs("9", testUser.ID).WillReturnError(sql.ErrNoRows)
+			},
+			http.StatusNotFound,
+			`{"statusCode":404,"message":"Record not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			db, mock, close := handlers.MockDatabase(t)
+			defer close()
+			tt.expect(mock)
+
+			e := echo.New()
+			res := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/budgets", nil), res)
+			users.SetCurrentUser(c, testUser)
+			c.SetPath("/budgets/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+			h := handler{NewTracker(NewPostgresRepository(db), &ledger{}, nil)}
+
+			// Act
+			err := h.GetBudgetByID(c)
+
+			// Assert
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.code, res.Code)
+				assert.Equal(t, tt.expected, strings.TrimSpace(res.Body.String()))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestGetBudgetStatusHandler(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	food := Budget{OwnerID: testUser.ID, Tag: "food", Period: PeriodMonth, Currency: "THB", Limit: 50000000}
+	assert.NoError(t, repo.Create(ctx, &food))
+
+	t.Run("Should report spent against limit for the requested period", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		bangkok, _ := time.LoadLocation("Asia/Bangkok")
+		req := httptest.NewRequest(http.MethodGet, "/budgets/1/status?at=2024-02-10", nil)
+		res := httptest.NewRecorder()
+
+		l := &ledger{spent: map[string]money.Amount{"food": 62500000}}
+		h := handler{NewTracker(repo, l, bangkok)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/budgets/:id/status")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		// Act
+		err := h.GetBudgetStatus(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Contains(t, res.Body.String(), `"from":"2024-02-01T00:00:00+07:00","to":"2024-03-01T00:00:00+07:00","spent":6250,"remaining":-1250,"overspent":true`)
+		}
+	})
+
+	t.Run("Should return unprocess entity error if at is not a date", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/budgets/1/status?at=yesterday", nil)
+		res := httptest.NewRecorder()
+
+		h := handler{NewTracker(repo, &ledger{}, nil)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/budgets/:id/status")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		expected := `{"statusCode":422,"message":"Query param at must be RFC 3339 time or YYYY-MM-DD date"}`
+
+		// Act
+		err := h.GetBudgetStatus(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+}
+
+func TestPostgresList(t *testing.T) {
+	db, mock, close := handlers.MockDatabase(t)
+	defer close()
+
+	mock.ExpectQuery("SELECT (.+) FROM budgets WHERE owner_id = (.+) ORDER BY id").
+		WithArgs(testUser.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "tag", "period", "currency", "amount_limit", "created_at", "updated_at"}).
+			AddRow(1, testUser.ID, "food", PeriodMonth, "THB", "5000.0000", testTime, testTime))
+
+	budgets, err := NewPostgresRepository(db).List(context.Background(), testUser.ID)
+
+	if assert.NoError(t, err) && assert.Len(t, budgets, 1) {
+		assert.Equal(t, money.Amount(50000000), budgets[0].Limit)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
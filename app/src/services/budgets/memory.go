@@ -0,0 +1,124 @@
+package budgets
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+)
+
+// memoryRepository keeps budgets in process memory alongside the in-memory
+// expense repository.
+type memoryRepository struct {
+	mu      sync.RWMutex
+	lastID  int
+	budgets map[int]Budget
+	now     func() time.Time
+}
+
+func NewMemoryRepository() BudgetRepository {
+	return &memoryRepository{budgets: map[int]Budget{}, now: time.Now}
+}
+
+// parseID reads the id of a budget from its path parameter.
+func parseID(id string) (int, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, apperr.ErrInvalidID
+	}
+	return n, nil
+}
+
+func (r *memoryRepository) find(owner int, id string) (Budget, error) {
+	n, err := parseID(id)
+	if err != nil {
+		return Budget{}, err
+	}
+	b, ok := r.budgets[n]
+	if !ok || b.OwnerID != owner {
+		return Budget{}, apperr.ErrNotFound
+	}
+	return b, nil
+}
+
+// taken reports whether another budget of b's owner has the same tag,
+// period and currency.
+func (r *memoryRepository) taken(b Budget) bool {
+	for _, other := range r.budgets {
+		if other.ID != b.ID && other.OwnerID == b.OwnerID &&
+			other.Tag == b.Tag && other.Period == b.Period && other.Currency == b.Currency {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryRepository) Create(ctx context.Context, b *Budget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.taken(*b) {
+		return ErrBudgetExists
+	}
+	r.lastID++
+	b.ID = r.lastID
+	b.CreatedAt = r.now()
+	b.UpdatedAt = b.CreatedAt
+	r.budgets[b.ID] = *b
+	return nil
+}
+
+func (r *memoryRepository) Get(ctx context.Context, owner int, id string) (Budget, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.find(owner, id)
+}
+
+func (r *memoryRepository) List(ctx context.Context, owner int) ([]Budget, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	budgets := []Budget{}
+	for _, b := range r.budgets {
+		if b.OwnerID == owner {
+			budgets = append(budgets, b)
+		}
+	}
+	sort.Slice(budgets, func(i, j int) bool { return budgets[i].ID < budgets[j].ID })
+	return budgets, nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, owner int, id string, b *Budget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.find(owner, id)
+	if err != nil {
+		return err
+	}
+	b.ID = stored.ID
+	b.OwnerID = stored.OwnerID
+	if r.taken(*b) {
+		return ErrBudgetExists
+	}
+	b.CreatedAt = stored.CreatedAt
+	b.UpdatedAt = r.now()
+	r.budgets[b.ID] = *b
+	return nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, owner int, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, err := r.find(owner, id)
+	if err != nil {
+		return err
+	}
+	delete(r.budgets, b.ID)
+	return nil
+}
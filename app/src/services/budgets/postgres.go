@@ -0,0 +1,113 @@
+package budgets
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/lib/pq"
+)
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) BudgetRepository {
+	return &postgresRepository{db}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBudget reads the columns id, owner_id, tag, period, currency,
+// amount_limit, created_at and updated_at in that order.
+func scanBudget(row scanner, b *Budget) error {
+	return row.Scan(&b.ID, &b.OwnerID, &b.Tag, &b.Period, &b.Currency, &b.Limit, &b.CreatedAt, &b.UpdatedAt)
+}
+
+// classify reports err as the apperr.Error it is, telling a duplicate budget
+// apart from other conflicts.
+func classify(err error) error {
+	var pqErr *pq.Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		return ErrBudgetExists
+	}
+	return apperr.AsError(err)
+}
+
+func (r *postgresRepository) Create(ctx context.Context, b *Budget) error {
+	sql := `
+	INSERT INTO
+		budgets (owner_id, tag, period, currency, amount_limit)
+	VALUES
+		($1, $2, $3, $4, $5)
+	RETURNING id, created_at, updated_at;
+	`
+	row := r.db.QueryRowContext(ctx, sql, b.OwnerID, b.Tag, b.Period, b.Currency, b.Limit)
+	return classify(row.Scan(&b.ID, &b.CreatedAt, &b.UpdatedAt))
+}
+
+func (r *postgresRepository) Get(ctx context.Context, owner int, id string) (Budget, error) {
+	var b Budget
+	sql := `
+	SELECT id, owner_id, tag, period, currency, amount_limit, created_at, updated_at
+	FROM budgets
+	WHERE id = $1 AND owner_id = $2
+	`
+	err := scanBudget(r.db.QueryRowContext(ctx, sql, id, owner), &b)
+	return b, classify(apperr.ByID(err))
+}
+
+func (r *postgresRepository) List(ctx context.Context, owner int) ([]Budget, error) {
+	budgets := []Budget{}
+	sql := `
+	SELECT id, owner_id, tag, period, currency, amount_limit, created_at, updated_at
+	FROM budgets
+	WHERE owner_id = $1
+	ORDER BY id
+	`
+	rows, err := r.db.QueryContext(ctx, sql, owner)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b Budget
+		if err := scanBudget(rows, &b); err != nil {
+			return nil, classify(err)
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, classify(rows.Err())
+}
+
+func (r *postgresRepository) Update(ctx context.Context, owner int, id string, b *Budget) error {
+	b.OwnerID = owner
+	sql := `
+	UPDATE
+		budgets SET tag = $1, period = $2, currency = $3, amount_limit = $4, updated_at = NOW()
+	WHERE
+		id = $5 AND owner_id = $6
+	RETURNING id, created_at, updated_at
+	`
+	row := r.db.QueryRowContext(ctx, sql, b.Tag, b.Period, b.Currency, b.Limit, id, owner)
+	return classify(apperr.ByID(row.Scan(&b.ID, &b.CreatedAt, &b.UpdatedAt)))
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, owner int, id string) error {
+	sql := `
+	DELETE FROM
+		budgets
+	WHERE
+		id = $1 AND owner_id = $2
+	RETURNING id
+	`
+	var deleted int
+	return classify(apperr.ByID(r.db.QueryRowContext(ctx, sql, id, owner).Scan(&deleted)))
+}
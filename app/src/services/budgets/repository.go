@@ -0,0 +1,35 @@
+package budgets
+
+import (
+	"context"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+)
+
+// ErrBudgetExists is the Conflict of a budget that duplicates another one's
+// tag, period and currency.
+var ErrBudgetExists = &apperr.Error{Kind: apperr.Conflict, Message: "A budget for this tag, period and currency already exists"}
+
+// BudgetRepository stores budgets. Ids that are not integers are reported
+// with apperr.ErrInvalidID. Lookups by id are scoped to owner and report
+// missing or someone else's budgets with apperr.ErrNotFound. Creating or
+// updating a budget that duplicates another one's tag, period and currency
+// returns ErrBudgetExists.
+type BudgetRepository interface {
+	// Create stores b for b.OwnerID.
+	Create(ctx context.Context, b *Budget) error
+	Get(ctx context.Context, owner int, id string) (Budget, error)
+	List(ctx context.Context, owner int) ([]Budget, error)
+	Update(ctx context.Context, owner int, id string, b *Budget) error
+	Delete(ctx context.Context, owner int, id string) error
+}
+
+// Ledger tells how much was spent, which budgets compare against their
+// limit. The expense repositories implement it.
+type Ledger interface {
+	// Spent sums the live expenses of owner in currency tagged with tag and
+	// spent from from (inclusive) to to (exclusive).
+	Spent(ctx context.Context, owner int, tag, currency string, from, to time.Time) (money.Amount, error)
+}
@@ -0,0 +1,45 @@
+package budgets
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+// GetBudgetStatus reports the budget for the current period, or for the
+// period containing the at query param, which is an RFC 3339 time or a
+// YYYY-MM-DD date.
+func (h *handler) GetBudgetStatus(c echo.Context) error {
+	ownerID, errRes := currentOwner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	at := h.tracker.now()
+	if raw := c.QueryParam("at"); raw != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, raw); err != nil {
+			at, err = time.ParseInLocation("2006-01-02", raw, h.tracker.zone())
+		}
+		if err != nil {
+			return c.JSON(
+				http.StatusUnprocessableEntity,
+				apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Query param at must be RFC 3339 time or YYYY-MM-DD date"},
+			)
+		}
+	}
+
+	ctx := c.Request().Context()
+	b, err := h.tracker.repo.Get(ctx, ownerID, c.Param("id"))
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	status, err := h.tracker.Status(ctx, b, at)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	return c.JSON(http.StatusOK, status)
+}
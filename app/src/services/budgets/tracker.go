@@ -0,0 +1,80 @@
+package budgets
+
+import (
+	"context"
+	"time"
+)
+
+// Tracker computes budget statuses from the budgets and the expenses in the
+// ledger.
+type Tracker struct {
+	repo   BudgetRepository
+	ledger Ledger
+	// location decides where periods start, UTC when nil.
+	location *time.Location
+	now      func() time.Time
+}
+
+func NewTracker(repo BudgetRepository, ledger Ledger, location *time.Location) *Tracker {
+	return &Tracker{repo: repo, ledger: ledger, location: location, now: time.Now}
+}
+
+func (t *Tracker) zone() *time.Location {
+	if t.location == nil {
+		return time.UTC
+	}
+	return t.location
+}
+
+// Status reports b for the period at falls in.
+func (t *Tracker) Status(ctx context.Context, b Budget, at time.Time) (Status, error) {
+	s := Status{Budget: b}
+	s.CreatedAt = b.CreatedAt.In(t.zone())
+	s.UpdatedAt = b.UpdatedAt.In(t.zone())
+	s.From, s.To = window(b.Period, at, t.zone())
+
+	spent, err := t.ledger.Spent(ctx, b.OwnerID, b.Tag, b.Currency, s.From, s.To)
+	if err != nil {
+		return s, err
+	}
+	s.Spent = spent
+	s.Remaining = b.Limit - spent
+	s.Overspent = spent > b.Limit
+	return s, nil
+}
+
+// Exceeded returns the budgets of owner that an expense in currency with
+// tags spent at at counts toward and that are over their limit.
+func (t *Tracker) Exceeded(ctx context.Context, owner int, tags []string, currency string, at time.Time) ([]Status, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	budgets, err := t.repo.List(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var exceeded []Status
+	for _, b := range budgets {
+		if b.Currency != currency || !contains(tags, b.Tag) {
+			continue
+		}
+		s, err := t.Status(ctx, b, at)
+		if err != nil {
+			return nil, err
+		}
+		if s.Overspent {
+			exceeded = append(exceeded, s)
+		}
+	}
+	return exceeded, nil
+}
+
+func contains(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
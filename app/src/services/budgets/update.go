@@ -0,0 +1,27 @@
+package budgets
+
+import (
+	"net/http"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *handler) UpdateBudgetByID(c echo.Context) error {
+	ownerID, errRes := currentOwner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	b, errRes := bind(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	if err := h.tracker.repo.Update(c.Request().Context(), ownerID, c.Param("id"), &b); err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	h.localize(&b)
+	return c.JSON(http.StatusOK, b)
+}
@@ -0,0 +1,323 @@
+package expenses
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultMaxAttachmentSize is the size limit of an attachment when none is
+// configured.
+const DefaultMaxAttachmentSize = 10 << 20
+
+// attachmentTypes are the content types accepted for receipts, as sniffed
+// from the file itself.
+var attachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+type Attachment struct {
+	ID          int    `json:"id"`
+	ExpenseID   int    `json:"expense_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// Key locates the file in the storage.
+	Key       string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// expenseDetail is an expense with the metadata of its attachments.
+type expenseDetail struct {
+	Expenses
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// errAttachmentTooLarge is returned by a limitedBody read past its limit.
+var errAttachmentTooLarge = errors.New("attachment body too large")
+
+// limitedBody reads at most n bytes of a request body and fails with
+// errAttachmentTooLarge once the body goes past them.
+type limitedBody struct {
+	io.ReadCloser
+	n   int64
+	err error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	// Read one byte past the limit to tell a body that ends on it from
+	// one that goes on.
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) <= b.n {
+		b.n -= int64(n)
+		b.err = err
+		return n, err
+	}
+	b.err = errAttachmentTooLarge
+	return int(b.n), b.err
+}
+
+func (h *handler) maxAttachmentSize() int64 {
+	if h.attachmentLimit <= 0 {
+		return DefaultMaxAttachmentSize
+	}
+	return h.attachmentLimit
+}
+
+// attachmentExpense loads the live expense of the current user that the
+// request addresses, answering the request itself when it cannot.
+func (h *handler) attachmentExpense(c echo.Context) (Expenses, bool, error) {
+	if h.attachments == nil || h.files == nil {
+		return Expenses{}, false, c.JSON(
+			http.StatusNotImplemented,
+			apperr.ErrorResponse{Code: http.StatusNotImplemented, Message: "Attachments are not configured"},
+		)
+	}
+
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return Expenses{}, false, c.JSON(errRes.Code, errRes)
+	}
+
+	exp, err := h.repo.Get(c.Request().Context(), ownerID, c.Param("id"))
+	if err != nil {
+		return Expenses{}, false, apperr.Respond(c, err)
+	}
+	return exp, true, nil
+}
+
+func (h *handler) localizeAttachment(a *Attachment) {
+	a.CreatedAt = a.CreatedAt.In(h.zone())
+}
+
+// attachmentFilename keeps the base name of an uploaded file without
+// control characters, so it is safe to send back in a header.
+func attachmentFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+		for !utf8.ValidString(name) {
+			name = name[:len(name)-1]
+		}
+	}
+	return name
+}
+
+func attachmentKey(expenseID int) (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("expenses/%d/%s", expenseID, hex.EncodeToString(token)), nil
+}
+
+// CreateAttachment stores the file field of a multipart form as an
+// attachment of the expense. The content type is sniffed from the file,
+// whatever the client claims.
+func (h *handler) CreateAttachment(c echo.Context) error {
+	exp, ok, err := h.attachmentExpense(c)
+	if !ok {
+		return err
+	}
+
+	maxSize := h.maxAttachmentSize()
+	tooLarge := func() error {
+		return c.JSON(
+			http.StatusRequestEntityTooLarge,
+			apperr.ErrorResponse{Code: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("Attachment must be at most %d bytes", maxSize)},
+		)
+	}
+	// Leave room for the multipart framing around the file.
+	c.Request().Body = &limitedBody{ReadCloser: c.Request().Body, n: maxSize + 64<<10}
+	header, err := c.FormFile("file")
+	if err != nil {
+		if errors.Is(err, errAttachmentTooLarge) {
+			return tooLarge()
+		}
+		return c.JSON(
+			http.StatusBadRequest,
+			apperr.ErrorResponse{Code: http.StatusBadRequest, Message: "Form field file is required"},
+		)
+	}
+	if header.Size > maxSize {
+		return tooLarge()
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		c.Logger().Warnf("request %s: reading attachment: %v", apperr.RequestID(c), err)
+		return c.JSON(
+			http.StatusBadRequest,
+			apperr.ErrorResponse{Code: http.StatusBadRequest, Message: "Invalid request body"},
+		)
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !attachmentTypes[contentType] {
+		return c.JSON(
+			http.StatusUnsupportedMediaType,
+			apperr.ErrorResponse{Code: http.StatusUnsupportedMediaType, Message: "Attachment must be a JPEG, PNG, GIF or WebP image or a PDF"},
+		)
+	}
+
+	key, err := attachmentKey(exp.ID)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	ctx := c.Request().Context()
+	body := io.MultiReader(bytes.NewReader(head), file)
+	if err := h.files.Put(ctx, key, body, header.Size, contentType); err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	a := Attachment{
+		ExpenseID:   exp.ID,
+		Filename:    attachmentFilename(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		Key:         key,
+	}
+	if err := h.attachments.Create(ctx, &a); err != nil {
+		if err := h.files.Delete(ctx, key); err != nil {
+			c.Logger().Error(err)
+		}
+		return apperr.Respond(c, err)
+	}
+
+	if err := h.touch(c, exp); err != nil {
+		// Take the attachment back, so the ETag the client holds still
+		// describes the expense.
+		if _, err := h.attachments.Delete(ctx, exp.ID, strconv.Itoa(a.ID)); err != nil {
+			c.Logger().Error(err)
+		} else if err := h.files.Delete(ctx, key); err != nil {
+			c.Logger().Error(err)
+		}
+		return apperr.Respond(c, err)
+	}
+	h.localizeAttachment(&a)
+	return c.JSON(http.StatusCreated, a)
+}
+
+func (h *handler) GetAttachments(c echo.Context) error {
+	exp, ok, err := h.attachmentExpense(c)
+	if !ok {
+		return err
+	}
+
+	list, err := h.attachments.List(c.Request().Context(), exp.ID)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	for i := range list {
+		h.localizeAttachment(&list[i])
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+// GetAttachmentByID streams the attached file back as a download.
+func (h *handler) GetAttachmentByID(c echo.Context) error {
+	exp, ok, err := h.attachmentExpense(c)
+	if !ok {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	a, err := h.attachments.Get(ctx, exp.ID, c.Param("attachment_id"))
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	file, err := h.files.Get(ctx, a.Key)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	defer file.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+	res.Header().Set(echo.HeaderContentLength, strconv.FormatInt(a.Size, 10))
+	res.Header().Set(echo.HeaderXContentTypeOptions, "nosniff")
+	return c.Stream(http.StatusOK, a.ContentType, file)
+}
+
+func (h *handler) DeleteAttachmentByID(c echo.Context) error {
+	exp, ok, err := h.attachmentExpense(c)
+	if !ok {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	a, err := h.attachments.Delete(ctx, exp.ID, c.Param("attachment_id"))
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	// The metadata is gone, so a file left behind is only logged.
+	if err := h.files.Delete(ctx, a.Key); err != nil {
+		c.Logger().Error(err)
+	}
+	if err := h.touch(c, exp); err != nil {
+		return apperr.Respond(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// touch bumps the version of exp after its attachments changed, so the
+// ETag of the expense, which lists them, changes too. A failure is returned
+// so the client is not left holding an ETag that no longer matches.
+func (h *handler) touch(c echo.Context, exp Expenses) error {
+	return h.repo.Touch(c.Request().Context(), exp.OwnerID, strconv.Itoa(exp.ID))
+}
+
+// purgeAttachments removes the attachments of a purged expense and their
+// files. The expense is already gone, so failures are only logged.
+func (h *handler) purgeAttachments(c echo.Context, list []Attachment) {
+	ctx := c.Request().Context()
+	for _, a := range list {
+		if _, err := h.attachments.Delete(ctx, a.ExpenseID, strconv.Itoa(a.ID)); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			c.Logger().Error(err)
+		}
+		if err := h.files.Delete(ctx, a.Key); err != nil {
+			c.Logger().Error(err)
+		}
+	}
+}
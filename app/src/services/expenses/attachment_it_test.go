@@ -0,0 +1,85 @@
+//go:build it
+
+package expenses
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachments(t *testing.T) {
+	// setup echo server
+	e, settings, close := SetupServer(t)
+	PingServer()
+
+	expense := SeedExpense(t, settings)
+	uri := Uri(fmt.Sprint(settings.Port), "expenses", fmt.Sprint(expense.ID), "attachments")
+	var created Attachment
+
+	t.Run("Should upload a receipt", func(t *testing.T) {
+		// Arrange
+		body := &bytes.Buffer{}
+		form := multipart.NewWriter(body)
+		part, _ := form.CreateFormFile("file", "receipt.pdf")
+		part.Write([]byte("%PDF-1.4\n%receipt\n"))
+		form.Close()
+		req, _ := http.NewRequest(http.MethodPost, uri, body)
+		req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+accessToken)
+
+		// Act
+		resp, err := http.DefaultClient.Do(req)
+		res := &Response{resp, err}
+		decodeErr := res.Decode(&created)
+
+		// Assert
+		if assert.NoError(t, decodeErr) {
+			assert.Equal(t, http.StatusCreated, res.StatusCode)
+			assert.NotEqual(t, 0, created.ID)
+			assert.Equal(t, "receipt.pdf", created.Filename)
+			assert.Equal(t, "application/pdf", created.ContentType)
+			assert.Equal(t, int64(18), created.Size)
+		}
+	})
+
+	t.Run("Should list and download the receipt", func(t *testing.T) {
+		// Act
+		var list []Attachment
+		err := Request(t, http.MethodGet, uri, nil).Decode(&list)
+		res := Request(t, http.MethodGet, uri+"/"+fmt.Sprint(created.ID), nil)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Len(t, list, 1)
+		}
+		if assert.NoError(t, res.err) {
+			defer res.Body.Close()
+			raw, _ := io.ReadAll(res.Body)
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, "application/pdf", res.Header.Get(echo.HeaderContentType))
+			assert.Equal(t, "%PDF-1.4\n%receipt\n", string(raw))
+		}
+	})
+
+	t.Run("Should delete the receipt", func(t *testing.T) {
+		// Act
+		res := Request(t, http.MethodDelete, uri+"/"+fmt.Sprint(created.ID), nil)
+		after := Request(t, http.MethodGet, uri+"/"+fmt.Sprint(created.ID), nil)
+
+		// Assert
+		if assert.NoError(t, res.err) && assert.NoError(t, after.err) {
+			assert.Equal(t, http.StatusNoContent, res.StatusCode)
+			assert.Equal(t, http.StatusNotFound, after.StatusCode)
+		}
+	})
+
+	// teardown echo server
+	TeardownServer(t, e, close)
+}
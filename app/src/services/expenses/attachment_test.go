@@ -0,0 +1,303 @@
+//go:build unit
+
+package expenses
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/RTae/assessment/app/src/storage"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pngFile = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pdfFile = []byte("%PDF-1.4\n%receipt\n")
+)
+
+type attachmentFixture struct {
+	h           handler
+	attachments AttachmentRepository
+	files       storage.Storage
+}
+
+func newAttachmentFixture(t *testing.T) attachmentFixture {
+	repo := NewMemoryRepository()
+	exp := Expenses{Title: "rice", Amount: 500000, Currency: "THB", OwnerID: testUser.ID}
+	if err := repo.Create(context.Background(), &exp); err != nil {
+		t.Fatal(err)
+	}
+	attachments := NewMemoryAttachmentRepository()
+	attachments.(*memoryAttachmentRepository).now = func() time.Time { return testTime }
+	files := storage.NewMemory()
+	return attachmentFixture{
+		h:           handler{repo: repo, attachments: attachments, files: files, attachmentLimit: 64},
+		attachments: attachments,
+		files:       files,
+	}
+}
+
+func attachmentContext(method, target string, body *bytes.Buffer, contentType string, params ...string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	if body == nil {
+		body = &bytes.Buffer{}
+	}
+	req := httptest.NewRequest(method, target, body)
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	res := httptest.NewRecorder()
+	c := e.NewContext(req, res)
+	users.SetCurrentUser(c, testUser)
+	names := []string{"id", "attachment_id"}
+	c.SetParamNames(names[:len(params)]...)
+	c.SetParamValues(params...)
+	return c, res
+}
+
+func upload(t *testing.T, h handler, expenseID, filename string, data []byte) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", filename)
+	part.Write(data)
+	form.Close()
+
+	c, res := attachmentContext(http.MethodPost, "/expenses/"+expenseID+"/attachments", body, form.FormDataContentType(), expenseID)
+	assert.NoError(t, h.CreateAttachment(c))
+	return res
+}
+
+func TestCreateAttachment(t *testing.T) {
+	t.Run("Should store the file with its sniffed content type", func(t *testing.T) {
+		// Arrange
+		f := newAttachmentFixture(t)
+
+		// Act
+		res := upload(t, f.h, "1", `C:\photos\"receipt".png`, pngFile)
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, res.Code)
+		assert.Equal(t,
+			`{"id":1,"expense_id":1,"filename":"receipt.png","content_type":"image/png","size":16,"created_at":"2024-01-02T03:04:05Z"}`,
+			strings.TrimSpace(res.Body.String()),
+		)
+		stored, _ := f.attachments.Get(context.Background(), 1, "1")
+		file, err := f.files.Get(context.Background(), stored.Key)
+		if assert.NoError(t, err) {
+			data := new(bytes.Buffer)
+			data.ReadFrom(file)
+			assert.Equal(t, pngFile, data.Bytes())
+		}
+		assert.Regexp(t, `^expenses/1/[0-9a-f]{32}$`, stored.Key)
+	})
+
+	tests := []struct {
+		name     string
+		id       string
+		filename string
+		data     []byte
+		code     int
+		expected string
+	}{
+		{"Should reject a file that is not a receipt", "1", "receipt.pdf", []byte("<html>hi</html>"), http.StatusUnsupportedMediaType, `{"statusCode":415,"message":"Attachment must be a JPEG, PNG, GIF or WebP image or a PDF"}`},
+		{"Should reject a file over the size limit", "1", "receipt.pdf", append(pdfFile, bytes.Repeat([]byte("x"), 64)...), http.StatusRequestEntityTooLarge, `{"statusCode":413,"message":"Attachment must be at most 64 bytes"}`},
+		{"Should reject a body over the size limit", "1", "receipt.pdf", append(pdfFile, bytes.Repeat([]byte("x"), 128<<10)...), http.StatusRequestEntityTooLarge, `{"statusCode":413,"message":"Attachment must be at most 64 bytes"}`},
+		{"Should return not found for someone else's expense", "2", "receipt.pdf", pdfFile, http.StatusNotFound, `{"statusCode":404,"message":"Record not found"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			f := newAttachmentFixture(t)
+
+			// Act
+			res := upload(t, f.h, tt.id, tt.filename, tt.data)
+
+			// Assert
+			assert.Equal(t, tt.code, res.Code)
+			assert.Equal(t, tt.expected, strings.TrimSpace(res.Body.String()))
+		})
+	}
+
+	t.Run("Should require the file field", func(t *testing.T) {
+		// Arrange
+		f := newAttachmentFixture(t)
+		c, res := attachmentContext(http.MethodPost, "/expenses/1/attachments", bytes.NewBufferString("{}"), echo.MIMEApplicationJSON, "1")
+
+		// Act
+		err := f.h.CreateAttachment(c)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		assert.Equal(t, `{"statusCode":400,"message":"Form field file is required"}`, strings.TrimSpace(res.Body.String()))
+	})
+
+	t.Run("Should answer 501 when attachments are not configured", func(t *testing.T) {
+		// Arrange
+		h := handler{repo: NewMemoryRepository()}
+
+		// Act
+		res := upload(t, h, "1", "receipt.pdf", pdfFile)
+
+		// Assert
+		assert.Equal(t, http.StatusNotImplemented, res.Code)
+	})
+
+	t.Run("Should take the attachment back when the expense version cannot be bumped", func(t *testing.T) {
+		// Arrange
+		f := newAttachmentFixture(t)
+		f.h.repo = untouchable{f.h.repo}
+
+		// Act
+		res := upload(t, f.h, "1", "receipt.pdf", pdfFile)
+		list, err := f.attachments.List(context.Background(), 1)
+
+		// Assert
+		assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+		assert.NoError(t, err)
+		assert.Empty(t, list)
+	})
+}
+
+// untouchable is a repository whose expense versions cannot be bumped.
+type untouchable struct{ ExpenseRepository }
+
+func (untouchable) Touch(ctx context.Context, owner int, id string) error { return sql.ErrConnDone }
+
+func TestGetAndDeleteAttachments(t *testing.T) {
+	// Arrange
+	f := newAttachmentFixture(t)
+	upload(t, f.h, "1", "receipt.pdf", pdfFile)
+	upload(t, f.h, "1", "photo.png", pngFile)
+
+	t.Run("Should list attachments", func(t *testing.T) {
+		c, res := attachmentContext(http.MethodGet, "/expenses/1/attachments", nil, "", "1")
+
+		assert.NoError(t, f.h.GetAttachments(c))
+
+		var list []Attachment
+		json.Unmarshal(res.Body.Bytes(), &list)
+		assert.Equal(t, http.StatusOK, res.Code)
+		if assert.Len(t, list, 2) {
+			assert.Equal(t, "receipt.pdf", list[0].Filename)
+			assert.Equal(t, "image/png", list[1].ContentType)
+		}
+	})
+
+	t.Run("Should include attachments when getting the expense", func(t *testing.T) {
+		c, res := attachmentContext(http.MethodGet, "/expenses/1", nil, "", "1")
+
+		assert.NoError(t, f.h.GetExpenseByID(c))
+
+		var detail expenseDetail
+		json.Unmarshal(res.Body.Bytes(), &detail)
+		assert.Equal(t, "rice", detail.Title)
+		assert.Len(t, detail.Attachments, 2)
+	})
+
+	t.Run("Should download an attachment", func(t *testing.T) {
+		c, res := attachmentContext(http.MethodGet, "/expenses/1/attachments/1", nil, "", "1", "1")
+
+		assert.NoError(t, f.h.GetAttachmentByID(c))
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "application/pdf", res.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, `attachment; filename=receipt.pdf`, res.Header().Get(echo.HeaderContentDisposition))
+		assert.Equal(t, "nosniff", res.Header().Get(echo.HeaderXContentTypeOptions))
+		assert.Equal(t, pdfFile, res.Body.Bytes())
+	})
+
+	t.Run("Should delete an attachment and its file", func(t *testing.T) {
+		stored, _ := f.attachments.Get(context.Background(), 1, "1")
+		c, res := attachmentContext(http.MethodDelete, "/expenses/1/attachments/1", nil, "", "1", "1")
+
+		assert.NoError(t, f.h.DeleteAttachmentByID(c))
+
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		_, err := f.files.Get(context.Background(), stored.Key)
+		assert.Equal(t, storage.ErrNotFound, err)
+	})
+
+	t.Run("Should return not found for a deleted attachment", func(t *testing.T) {
+		c, res := attachmentContext(http.MethodGet, "/expenses/1/attachments/1", nil, "", "1", "1")
+
+		assert.NoError(t, f.h.GetAttachmentByID(c))
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("Should remove the files when the expense is purged", func(t *testing.T) {
+		stored, _ := f.attachments.Get(context.Background(), 1, "2")
+		c, res := attachmentContext(http.MethodDelete, "/admin/expenses/1", nil, "", "1")
+
+		assert.NoError(t, f.h.PurgeExpenseByID(c))
+
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		_, err := f.files.Get(context.Background(), stored.Key)
+		assert.Equal(t, storage.ErrNotFound, err)
+		list, _ := f.attachments.List(context.Background(), 1)
+		assert.Empty(t, list)
+	})
+}
+
+func TestAttachmentFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"receipt.pdf", "receipt.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\me\slip.jpg`, "slip.jpg"},
+		{"bad\r\nname.png", "badname.png"},
+		{"", "attachment"},
+		{strings.Repeat("ก", 100), strings.Repeat("ก", 85)},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, attachmentFilename(tt.name))
+	}
+}
+
+func TestPostgresAttachmentRepository(t *testing.T) {
+	t.Run("Should insert the metadata", func(t *testing.T) {
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("INSERT INTO attachments").WithArgs(1, "receipt.pdf", "application/pdf", int64(18), "expenses/1/abc").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, testTime))
+
+		a := Attachment{ExpenseID: 1, Filename: "receipt.pdf", ContentType: "application/pdf", Size: 18, Key: "expenses/1/abc"}
+		err := NewPostgresAttachmentRepository(db).Create(context.Background(), &a)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, 3, a.ID)
+			assert.Equal(t, testTime, a.CreatedAt)
+		}
+	})
+
+	t.Run("Should return the deleted attachment", func(t *testing.T) {
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("DELETE FROM attachments").WithArgs("3", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "expense_id", "filename", "content_type", "size", "storage_key", "created_at"}).
+				AddRow(3, 1, "receipt.pdf", "application/pdf", 18, "expenses/1/abc", testTime))
+
+		a, err := NewPostgresAttachmentRepository(db).Delete(context.Background(), 1, "3")
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, "expenses/1/abc", a.Key)
+		}
+	})
+}
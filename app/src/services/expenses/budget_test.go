@@ -0,0 +1,126 @@
+//go:build unit
+
+package expenses
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/services/budgets"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemorySpent(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	seedMemory(t, repo, testUser.ID,
+		Expenses{Title: "rice", Amount: 100000, Currency: "THB", Tags: []string{"food"}, SpentAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Expenses{Title: "tea", Amount: 150000, Currency: "THB", Tags: []string{"food", "beverage"}, SpentAt: time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC)},
+		Expenses{Title: "noodle", Amount: 200000, Currency: "THB", Tags: []string{"food"}, SpentAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		Expenses{Title: "bagel", Amount: 50000, Currency: "USD", Tags: []string{"food"}, SpentAt: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+	)
+	seedMemory(t, repo, 2,
+		Expenses{Title: "rice", Amount: 100000, Currency: "THB", Tags: []string{"food"}, SpentAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	)
+
+	// Act
+	spent, err := repo.Spent(ctx, testUser.ID, "food", "THB", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	// Assert
+	if assert.NoError(t, err) {
+		assert.Equal(t, money.Amount(250000), spent)
+	}
+}
+
+func TestCreateExpenseOverBudget(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	budgetRepo := budgets.NewMemoryRepository()
+	food := budgets.Budget{OwnerID: testUser.ID, Tag: "food", Period: budgets.PeriodMonth, Currency: "THB", Limit: 1000000}
+	assert.NoError(t, budgetRepo.Create(ctx, &food))
+	h := handler{repo: repo, budgets: budgets.NewTracker(budgetRepo, repo, nil)}
+
+	tests := []struct {
+		name       string
+		body       string
+		overBudget int
+	}{
+		{"Should not flag a write within the budget", `{"title": "rice", "amount": 60, "tags": ["food"], "spent_at": "2024-01-10T12:00:00Z"}`, 0},
+		{"Should flag a write that pushes the budget over its limit", `{"title": "noodle", "amount": 60, "tags": ["food"], "spent_at": "2024-01-11T12:00:00Z"}`, 1},
+		{"Should not flag an expense in another period", `{"title": "noodle", "amount": 60, "tags": ["food"], "spent_at": "2024-02-11T12:00:00Z"}`, 0},
+		{"Should not flag an expense with other tags", `{"title": "taxi", "amount": 60, "tags": ["travel"], "spent_at": "2024-01-11T12:00:00Z"}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			res := httptest.NewRecorder()
+			c := e.NewContext(req, res)
+			users.SetCurrentUser(c, testUser)
+			var body struct {
+				OverBudget []budgets.Status `json:"over_budget"`
+			}
+
+			// Act
+			err := h.CreateExpense(c)
+			json.Unmarshal(res.Body.Bytes(), &body)
+
+			// Assert
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusCreated, res.Code)
+				assert.Len(t, body.OverBudget, tt.overBudget)
+				if tt.overBudget > 0 {
+					assert.Equal(t, food.ID, body.OverBudget[0].ID)
+					assert.Equal(t, money.Amount(1200000), body.OverBudget[0].Spent)
+					assert.True(t, body.OverBudget[0].Overspent)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateExpenseOverBudget(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	seedMemory(t, repo, testUser.ID,
+		Expenses{Title: "rice", Amount: 100000, Currency: "THB", Tags: []string{"food"}, SpentAt: testTime},
+	)
+	budgetRepo := budgets.NewMemoryRepository()
+	food := budgets.Budget{OwnerID: testUser.ID, Tag: "food", Period: budgets.PeriodDay, Currency: "THB", Limit: 1000000}
+	assert.NoError(t, budgetRepo.Create(ctx, &food))
+	h := handler{repo: repo, budgets: budgets.NewTracker(budgetRepo, repo, nil)}
+
+	e := echo.New()
+	body := `{"title": "rice", "amount": 150, "tags": ["food"]}`
+	req := httptest.NewRequest(http.MethodPut, "/expenses", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	res := httptest.NewRecorder()
+	c := e.NewContext(req, res)
+	users.SetCurrentUser(c, testUser)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	// Act
+	err := h.UpdateExpenseByID(c)
+
+	// Assert
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Contains(t, res.Body.String(), `"over_budget":[{"id":1,"tag":"food","period":"day","currency":"THB","limit":100,`)
+		assert.Contains(t, res.Body.String(), `"spent":150,"remaining":-50,"overspent":true}]`)
+	}
+}
@@ -3,35 +3,39 @@ package expenses
 import (
 	"net/http"
 
+	"github.com/RTae/assessment/app/src/apperr"
 	"github.com/labstack/echo/v4"
-	"github.com/lib/pq"
 )
 
+// CreateExpense honors the Idempotency-Key header so a retried request does
+// not add the expense twice.
 func (h *handler) CreateExpense(c echo.Context) error {
+	return h.idempotent(c, h.createExpense)
+}
+
+func (h *handler) createExpense(c echo.Context) error {
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
 
 	var exp Expenses
 	err := c.Bind(&exp)
 	if err != nil {
-		return c.JSON(
-			http.StatusUnprocessableEntity,
-			ErrorResponse{Code: http.StatusUnprocessableEntity, Message: err.Error()},
-		)
+		return apperr.InvalidBody(c, err)
+	}
+
+	exp.OwnerID = ownerID
+	exp.defaultCurrency()
+	if err := validate(c, &exp); err != nil {
+		return validationErrorResponse(c, err)
 	}
 
-	sql := `
-	INSERT INTO
-		expenses (title, amount, note, tags)
-	VALUES
-		($1, $2, $3, $4) 
-	RETURNING id;
-	`
-	row := h.db.QueryRow(sql, exp.Title, exp.Amount, exp.Note, pq.Array(&exp.Tags))
-	if err := row.Scan(&exp.ID); err != nil {
-		return c.JSON(
-			http.StatusInternalServerError,
-			ErrorResponse{Code: http.StatusInternalServerError, Message: err.Error()},
-		)
+	if err := h.repo.Create(h.context(c), &exp); err != nil {
+		return apperr.Respond(c, err)
 	}
+	recordCreated(sourceAPI, exp)
 
-	return c.JSON(http.StatusCreated, exp)
+	setETag(c, exp)
+	return c.JSON(http.StatusCreated, h.written(c, exp))
 }
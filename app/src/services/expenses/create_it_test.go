@@ -3,11 +3,16 @@
 package expenses
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/services/budgets"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,13 +40,63 @@ func TestCreateExpense(t *testing.T) {
 			assert.Equal(t, http.StatusCreated, res.StatusCode)
 			assert.NotEqual(t, 0, exp.ID)
 			assert.Equal(t, "strawberry smoothie", exp.Title)
-			assert.Equal(t, float32(79.00), exp.Amount)
+			assert.Equal(t, money.Amount(790000), exp.Amount)
+			assert.Equal(t, "THB", exp.Currency)
 			assert.Equal(t, "night market promotion discount 10 bath", exp.Note)
 			assert.Equal(t, []string{"food", "beverage"}, exp.Tags)
+			assert.False(t, exp.SpentAt.IsZero())
+			assert.False(t, exp.CreatedAt.IsZero())
+			assert.Equal(t, exp.CreatedAt, exp.UpdatedAt)
 		}
 
 	})
 
+	t.Run("Should keep the supplied spent_at", func(t *testing.T) {
+		// Arrange
+		body := `{
+			"title": "strawberry smoothie",
+			"amount": 79,
+			"spent_at": "2024-01-02T10:04:05+07:00"
+		}`
+		var exp Expenses
+
+		// Act
+		res := Request(t, http.MethodPost, Uri(fmt.Sprint(settings.Port), "expenses"), strings.NewReader(body))
+		err := res.Decode(&exp)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusCreated, res.StatusCode)
+			assert.Equal(t, "2024-01-02T03:04:05Z", exp.SpentAt.Format(time.RFC3339))
+		}
+	})
+
+	t.Run("Should flag the budgets the expense pushes over their limit", func(t *testing.T) {
+		// Arrange
+		budget := budgets.Budget{OwnerID: seededUser.ID, Tag: "groceries", Period: budgets.PeriodMonth, Currency: "THB", Limit: 1000000}
+		assert.NoError(t, budgetRepo.Create(context.Background(), &budget))
+		body := `{
+			"title": "weekly groceries",
+			"amount": 120,
+			"tags": ["groceries"]
+		}`
+		var exp struct {
+			OverBudget []budgets.Status `json:"over_budget"`
+		}
+
+		// Act
+		res := Request(t, http.MethodPost, Uri(fmt.Sprint(settings.Port), "expenses"), strings.NewReader(body))
+		err := res.Decode(&exp)
+
+		// Assert
+		if assert.NoError(t, err) && assert.Len(t, exp.OverBudget, 1) {
+			assert.Equal(t, http.StatusCreated, res.StatusCode)
+			assert.Equal(t, budget.ID, exp.OverBudget[0].ID)
+			assert.Equal(t, money.Amount(1200000), exp.OverBudget[0].Spent)
+			assert.True(t, exp.OverBudget[0].Overspent)
+		}
+	})
+
 	tests := []struct {
 		name     string
 		body     string
@@ -55,7 +110,7 @@ func TestCreateExpense(t *testing.T) {
 				"note": "night market promotion discount 10 bath", 
 				"tags": ["food", "beverage"]
 			}`,
-			"cannot unmarshal number into Go struct field Expenses.title of type string",
+			"Invalid request body",
 		},
 		{
 			"Should return unprocess entity error if amount is not correct",
@@ -65,7 +120,7 @@ func TestCreateExpense(t *testing.T) {
 				"note": "night market promotion discount 10 bath", 
 				"tags": ["food", "beverage"]
 			}`,
-			"cannot unmarshal string into Go struct field Expenses.amount of type float32",
+			"Invalid request body",
 		},
 		{
 			"Should return unprocess entity error if note is not correct",
@@ -75,7 +130,7 @@ func TestCreateExpense(t *testing.T) {
 				"note": 22321, 
 				"tags": ["food", "beverage"]
 			}`,
-			"cannot unmarshal number into Go struct field Expenses.note of type string",
+			"Invalid request body",
 		},
 		{
 			"Should return unprocess entity error if tags is not correct",
@@ -85,7 +140,7 @@ func TestCreateExpense(t *testing.T) {
 				"note": "night market promotion discount 10 bath", 
 				"tags": "["food", "beverage"]"
 			}`,
-			"invalid character 'f' after object key:value pair",
+			"Invalid request body",
 		},
 		{
 			"Should return unprocess entity error if data in tags is not correct",
@@ -95,7 +150,7 @@ func TestCreateExpense(t *testing.T) {
 				"note": "night market promotion discount 10 bath", 
 				"tags": ["food", "beverage", 2312]
 			}`,
-			"cannot unmarshal number into Go struct field Expenses.tags of type string",
+			"Invalid request body",
 		},
 	}
 	for _, tt := range tests {
@@ -103,7 +158,7 @@ func TestCreateExpense(t *testing.T) {
 			// Arrange
 			body := tt.body
 			expected := tt.expected
-			var errRes ErrorResponse
+			var errRes apperr.ErrorResponse
 
 			// Act
 			res := Request(t, http.MethodPost, Uri(fmt.Sprint(settings.Port), "expenses"), strings.NewReader(body))
@@ -118,6 +173,27 @@ func TestCreateExpense(t *testing.T) {
 		})
 	}
 
+	t.Run("Should return bad request error if expense is not valid", func(t *testing.T) {
+		// Arrange
+		body := `{
+			"title": "",
+			"amount": 79,
+			"note": "night market promotion discount 10 bath",
+			"tags": ["food"]
+		}`
+		var errRes apperr.ErrorResponse
+
+		// Act
+		res := Request(t, http.MethodPost, Uri(fmt.Sprint(settings.Port), "expenses"), strings.NewReader(body))
+		err := res.Decode(&errRes)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, errRes.Code)
+			assert.Equal(t, []apperr.FieldError{{Field: "title", Message: "is required"}}, errRes.Details)
+		}
+	})
+
 	// teardown echo server
 	TeardownServer(t, e, close)
 }
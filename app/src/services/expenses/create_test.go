@@ -11,6 +11,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/services/users"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 )
@@ -32,12 +33,13 @@ func TestCreateExpenseHandler(t *testing.T) {
 		db, mock, close := handlers.MockDatabase(t)
 		defer close()
 
-		insertMockRow := mock.NewRows([]string{"id"}).AddRow("1")
+		insertMockRow := mock.NewRows([]string{"id", "spent_at", "created_at", "updated_at", "version"}).AddRow("1", testTime, testTime, testTime, 1)
 		mock.ExpectQuery("INSERT INTO expenses").WillReturnRows(insertMockRow)
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
-		expected := "{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"note\":\"night market promotion discount 10 bath\",\"tags\":[\"food\",\"beverage\"]}"
+		users.SetCurrentUser(c, testUser)
+		expected := "{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"currency\":\"THB\",\"note\":\"night market promotion discount 10 bath\",\"tags\":[\"food\",\"beverage\"]" + testTimestamps + "}"
 
 		// Act
 		err := h.CreateExpense(c)
@@ -63,7 +65,7 @@ func TestCreateExpenseHandler(t *testing.T) {
 				"note": "night market promotion discount 10 bath", 
 				"tags": ["food", "beverage"]
 			}`,
-			"cannot unmarshal number into Go struct field Expenses.title of type string",
+			"Invalid request body",
 		},
 		{
 			"Should return unprocess entity error if amount is not correct",
@@ -73,7 +75,7 @@ func TestCreateExpenseHandler(t *testing.T) {
 				"note": "night market promotion discount 10 bath", 
 				"tags": ["food", "beverage"]
 			}`,
-			"cannot unmarshal string into Go struct field Expenses.amount of type float32",
+			"Invalid request body",
 		},
 		{
 			"Should return unprocess entity error if note is not correct",
@@ -83,7 +85,7 @@ func TestCreateExpenseHandler(t *testing.T) {
 				"note": 22321, 
 				"tags": ["food", "beverage"]
 			}`,
-			"cannot unmarshal number into Go struct field Expenses.note of type string",
+			"Invalid request body",
 		},
 		{
 			"Should return unprocess entity error if tags is not correct",
@@ -93,7 +95,7 @@ func TestCreateExpenseHandler(t *testing.T) {
 				"note": "night market promotion discount 10 bath", 
 				"tags": "["food", "beverage"]"
 			}`,
-			"invalid character 'f' after object key:value pair",
+			"Invalid request body",
 		},
 		{
 			"Should return unprocess entity error if data in tags is not correct",
@@ -103,7 +105,7 @@ func TestCreateExpenseHandler(t *testing.T) {
 				"note": "night market promotion discount 10 bath", 
 				"tags": ["food", "beverage", 2312]
 			}`,
-			"cannot unmarshal number into Go struct field Expenses.tags of type string",
+			"Invalid request body",
 		},
 	}
 
@@ -119,11 +121,12 @@ func TestCreateExpenseHandler(t *testing.T) {
 			db, mock, close := handlers.MockDatabase(t)
 			defer close()
 
-			insertMockRow := mock.NewRows([]string{"id"}).AddRow("1")
+			insertMockRow := mock.NewRows([]string{"id", "spent_at", "created_at", "updated_at", "version"}).AddRow("1", testTime, testTime, testTime, 1)
 			mock.ExpectQuery("INSERT INTO expenses").WillReturnRows(insertMockRow)
 
-			h := handler{db}
+			h := handler{repo: NewPostgresRepository(db)}
 			c := e.NewContext(req, res)
+			users.SetCurrentUser(c, testUser)
 			expected := tt.expected
 
 			// Act
@@ -138,6 +141,38 @@ func TestCreateExpenseHandler(t *testing.T) {
 		})
 	}
 
+	t.Run("Should return bad request error with field details if expense is not valid", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		body := `{
+			"title": "",
+			"amount": -79,
+			"note": "night market promotion discount 10 bath", 
+			"tags": ["food", "food"]
+		}`
+		req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		db, _, close := handlers.MockDatabase(t)
+		defer close()
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		expected := "{\"statusCode\":400,\"message\":\"Validation failed\",\"details\":[{\"field\":\"title\",\"message\":\"is required\"},{\"field\":\"amount\",\"message\":\"must be greater than 0\"},{\"field\":\"tags[1]\",\"message\":\"is duplicated\"}]}"
+
+		// Act
+		err := h.CreateExpense(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+
+	})
+
 	t.Run("Should return internal error if can not create new expense", func(t *testing.T) {
 		// Arrange
 		e := echo.New()
@@ -156,9 +191,10 @@ func TestCreateExpenseHandler(t *testing.T) {
 
 		mock.ExpectQuery("INSERT INTO expenses").WillReturnError(sqlmock.ErrCancelled)
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
-		expected := "{\"statusCode\":500,\"message\":\"canceling query due to user request\"}"
+		users.SetCurrentUser(c, testUser)
+		expected := "{\"statusCode\":500,\"message\":\"Internal Server Error\"}"
 
 		// Act
 		err := h.CreateExpense(c)
@@ -0,0 +1,80 @@
+package expenses
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *handler) DeleteExpenseByID(c echo.Context) error {
+	expenseId := c.Param("id")
+	if expenseId == "" {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Param id is empty"},
+		)
+	}
+
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	if err := h.repo.Delete(h.context(c), ownerID, expenseId); err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *handler) RestoreExpenseByID(c echo.Context) error {
+	expenseId := c.Param("id")
+	if expenseId == "" {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Param id is empty"},
+		)
+	}
+
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	e, err := h.repo.Restore(h.context(c), ownerID, expenseId)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	h.localize(&e)
+	return c.JSON(http.StatusOK, e)
+}
+
+func (h *handler) PurgeExpenseByID(c echo.Context) error {
+	expenseId := c.Param("id")
+	if expenseId == "" {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Param id is empty"},
+		)
+	}
+
+	// The attachment rows go with the expense, so their files are looked up
+	// first.
+	var attached []Attachment
+	if n, err := strconv.Atoi(expenseId); err == nil && h.attachments != nil && h.files != nil {
+		attached, err = h.attachments.List(c.Request().Context(), n)
+		if err != nil {
+			return apperr.Respond(c, err)
+		}
+	}
+
+	if err := h.repo.Purge(h.context(c), expenseId); err != nil {
+		return apperr.Respond(c, err)
+	}
+	h.purgeAttachments(c, attached)
+
+	return c.NoContent(http.StatusNoContent)
+}
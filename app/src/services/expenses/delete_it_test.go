@@ -0,0 +1,62 @@
+//go:build it
+
+package expenses
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteExpenseByID(t *testing.T) {
+	// setup echo server
+	e, settings, close := SetupServer(t)
+	PingServer()
+	t.Run("Should soft delete and restore expense successfully", func(t *testing.T) {
+
+		// Arrange
+		createExpense := SeedExpense(t, settings)
+		uri := Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%d", createExpense.ID))
+
+		// Act
+		deleteRes := Request(t, http.MethodDelete, uri, nil)
+		getRes := Request(t, http.MethodGet, uri, nil)
+
+		var exp Expenses
+		restoreRes := Request(t, http.MethodPost, uri+"/restore", nil)
+		err := restoreRes.Decode(&exp)
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, deleteRes.StatusCode)
+		assert.Equal(t, http.StatusNotFound, getRes.StatusCode)
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, restoreRes.StatusCode)
+			assert.Equal(t, createExpense.ID, exp.ID)
+			assert.Equal(t, createExpense.Title, exp.Title)
+		}
+	})
+
+	t.Run("Should return not found error if the request expense id is not exist", func(t *testing.T) {
+
+		// Arrange
+		expenseId := "1000000"
+		expectedMessage := "Record not found"
+
+		// Act
+		var errRes apperr.ErrorResponse
+		res := Request(t, http.MethodDelete, Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%s", expenseId)), nil)
+		err := res.Decode(&errRes)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNotFound, errRes.Code)
+			assert.Equal(t, expectedMessage, errRes.Message)
+		}
+	})
+
+	// teardown echo server
+	TeardownServer(t, e, close)
+}
@@ -0,0 +1,250 @@
+//go:build unit
+
+package expenses
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteExpenseHandler(t *testing.T) {
+	t.Run("Should soft delete expense successfully", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		expenseID := "1"
+		req := httptest.NewRequest(http.MethodDelete, "/expenses", nil)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("UPDATE expenses SET deleted_at = NOW()").
+			WithArgs(expenseID, testUser.ID).
+			WillReturnRows(mock.NewRows([]string{"id"}).AddRow(expenseID))
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(expenseID)
+
+		// Act
+		err := h.DeleteExpenseByID(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNoContent, res.Code)
+			assert.Empty(t, res.Body.String())
+		}
+	})
+
+	t.Run("Should return not found error if expense is already deleted", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		expenseID := "1"
+		req := httptest.NewRequest(http.MethodDelete, "/expenses", nil)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("UPDATE expenses SET deleted_at = NOW()").
+			WithArgs(expenseID, testUser.ID).
+			WillReturnError(sql.ErrNoRows)
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(expenseID)
+		expected := "{\"statusCode\":404,\"message\":\"Record not found\"}"
+
+		// Act
+		err := h.DeleteExpenseByID(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNotFound, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+
+	t.Run("Should return unprocessable entity error if expense id is empty", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/expenses", nil)
+		res := httptest.NewRecorder()
+
+		db, _, close := handlers.MockDatabase(t)
+		defer close()
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("")
+		expected := "{\"statusCode\":422,\"message\":\"Param id is empty\"}"
+
+		// Act
+		err := h.DeleteExpenseByID(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+}
+
+func TestRestoreExpenseHandler(t *testing.T) {
+	t.Run("Should restore expense successfully", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		expenseID := "1"
+		req := httptest.NewRequest(http.MethodPost, "/expenses", nil)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		restoreMockRows := mock.NewRows([]string{"ID", "Title", "Amount", "Currency", "Note", "Tags", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).
+			AddRow(
+				"1",
+				"strawberry smoothie",
+				79.00,
+				"THB",
+				"night market promotion discount 10 bath",
+				pq.Array([]string{"food", "beverage"}),
+				testTime, testTime, testTime, 1,
+			)
+		mock.ExpectQuery("UPDATE expenses SET deleted_at = NULL").
+			WithArgs(expenseID, testUser.ID).
+			WillReturnRows(restoreMockRows)
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/expenses/:id/restore")
+		c.SetParamNames("id")
+		c.SetParamValues(expenseID)
+		expected := "{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"currency\":\"THB\",\"note\":\"night market promotion discount 10 bath\",\"tags\":[\"food\",\"beverage\"]" + testTimestamps + "}"
+
+		// Act
+		err := h.RestoreExpenseByID(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+
+	t.Run("Should return unprocessable entity error if expense id is not integer", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		expenseID := "dw2"
+		req := httptest.NewRequest(http.MethodPost, "/expenses", nil)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("UPDATE expenses SET deleted_at = NULL").
+			WithArgs(expenseID, testUser.ID).
+			WillReturnError(&pq.Error{Code: "22P02", Message: "invalid input syntax for type integer"})
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/expenses/:id/restore")
+		c.SetParamNames("id")
+		c.SetParamValues(expenseID)
+		expected := "{\"statusCode\":422,\"message\":\"Param id must be integer\"}"
+
+		// Act
+		err := h.RestoreExpenseByID(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+}
+
+func TestPurgeExpenseHandler(t *testing.T) {
+	t.Run("Should purge expense successfully", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		expenseID := "1"
+		req := httptest.NewRequest(http.MethodDelete, "/admin/expenses", nil)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("DELETE FROM expenses").
+			WithArgs(expenseID).
+			WillReturnRows(mock.NewRows([]string{"id"}).AddRow(expenseID))
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/admin/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(expenseID)
+
+		// Act
+		err := h.PurgeExpenseByID(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNoContent, res.Code)
+		}
+	})
+
+	t.Run("Should return internal error if can not purge expense", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		expenseID := "1"
+		req := httptest.NewRequest(http.MethodDelete, "/admin/expenses", nil)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("DELETE FROM expenses").
+			WithArgs(expenseID).
+			WillReturnError(sqlmock.ErrCancelled)
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/admin/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(expenseID)
+		expected := "{\"statusCode\":500,\"message\":\"Internal Server Error\"}"
+
+		// Act
+		err := h.PurgeExpenseByID(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusInternalServerError, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+}
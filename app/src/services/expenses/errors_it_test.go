@@ -0,0 +1,65 @@
+//go:build it
+
+package expenses
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorResponses(t *testing.T) {
+	// setup echo server
+	e, settings, close := SetupServer(t)
+	PingServer()
+
+	tests := []struct {
+		name     string
+		path     string
+		expected apperr.ErrorResponse
+	}{
+		{"Should answer a malformed id from the database", "expenses/abc", apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Param id must be integer"}},
+		{"Should answer a missing expense", "expenses/2147483647", apperr.ErrorResponse{Code: http.StatusNotFound, Message: "Record not found"}},
+		{"Should answer an unknown route", "expenses/1/unknown", apperr.ErrorResponse{Code: http.StatusNotFound, Message: "Not Found"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			var body apperr.ErrorResponse
+			res := Request(t, http.MethodGet, Uri(fmt.Sprint(settings.Port), tt.path), nil)
+			err := res.Decode(&body)
+
+			// Assert
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected.Code, res.StatusCode)
+				assert.Equal(t, tt.expected, body)
+			}
+		})
+	}
+
+	t.Run("Should answer a problem to clients that accept one", func(t *testing.T) {
+		// Act
+		var problem apperr.Problem
+		res := RequestWith(t, accessToken, http.MethodGet, Uri(fmt.Sprint(settings.Port), "expenses/abc"), nil,
+			map[string]string{echo.HeaderAccept: apperr.MIMEProblemJSON})
+		err := res.Decode(&problem)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, apperr.MIMEProblemJSON, res.Header.Get(echo.HeaderContentType))
+			assert.Equal(t, http.StatusUnprocessableEntity, problem.Status)
+			assert.Equal(t, "Param id must be integer", problem.Detail)
+			assert.Equal(t, "/expenses/abc", problem.Instance)
+			assert.Equal(t, res.Header.Get(echo.HeaderXRequestID), problem.RequestID)
+			assert.NotEmpty(t, problem.RequestID)
+		}
+	})
+
+	// teardown echo server
+	TeardownServer(t, e, close)
+}
@@ -0,0 +1,61 @@
+package expenses
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderETag        = "ETag"
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+// etag is the entity tag of an expense, which changes with every version.
+func etag(exp Expenses) string {
+	return fmt.Sprintf(`"%d"`, exp.Version)
+}
+
+func setETag(c echo.Context, exp Expenses) {
+	c.Response().Header().Set(HeaderETag, etag(exp))
+}
+
+// etagMatches tells whether tag is listed in an If-Match or If-None-Match
+// header. If-Match compares strongly, so weak tags never match, while
+// If-None-Match compares weakly.
+func etagMatches(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch returns the If-Match header of a write, answering the request
+// itself when the header is required but missing.
+func (h *handler) ifMatch(c echo.Context) (string, bool, error) {
+	header := c.Request().Header.Get(HeaderIfMatch)
+	if header == "" && h.requireIfMatch {
+		return "", false, c.JSON(
+			http.StatusPreconditionRequired,
+			apperr.ErrorResponse{Code: http.StatusPreconditionRequired, Message: "Header If-Match is required"},
+		)
+	}
+	return header, true, nil
+}
+
+func preconditionFailedResponse(c echo.Context) error {
+	return c.JSON(
+		http.StatusPreconditionFailed,
+		apperr.ErrorResponse{Code: http.StatusPreconditionFailed, Message: "Expense has been changed since it was read"},
+	)
+}
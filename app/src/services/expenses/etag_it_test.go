@@ -0,0 +1,51 @@
+//go:build it
+
+package expenses
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpenseETag(t *testing.T) {
+	// setup echo server
+	e, settings, close := SetupServer(t)
+	PingServer()
+
+	created := SeedExpense(t, settings)
+	uri := Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%d", created.ID))
+	body := `{"title":"etag tea","amount":30,"tags":["food"]}`
+
+	t.Run("Should answer not modified until the expense changes", func(t *testing.T) {
+		// Act
+		res := Request(t, http.MethodGet, uri, nil)
+		tag := res.Header.Get(HeaderETag)
+		res.Body.Close()
+		cached := RequestWith(t, accessToken, http.MethodGet, uri, nil, map[string]string{HeaderIfNoneMatch: tag})
+		cached.Body.Close()
+
+		// Assert
+		assert.Equal(t, `"1"`, tag)
+		assert.Equal(t, http.StatusNotModified, cached.StatusCode)
+	})
+
+	t.Run("Should reject an update of a stale version", func(t *testing.T) {
+		// Act
+		updated := RequestWith(t, accessToken, http.MethodPut, uri, strings.NewReader(body), map[string]string{HeaderIfMatch: `"1"`})
+		updated.Body.Close()
+		stale := RequestWith(t, accessToken, http.MethodPut, uri, strings.NewReader(body), map[string]string{HeaderIfMatch: `"1"`})
+		stale.Body.Close()
+
+		// Assert
+		assert.Equal(t, http.StatusOK, updated.StatusCode)
+		assert.Equal(t, `"2"`, updated.Header.Get(HeaderETag))
+		assert.Equal(t, http.StatusPreconditionFailed, stale.StatusCode)
+	})
+
+	// teardown echo server
+	TeardownServer(t, e, close)
+}
@@ -0,0 +1,169 @@
+//go:build unit
+
+package expenses
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func conditionalRequest(t *testing.T, h handler, method, contentType, body string, header ...string) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.Validator = NewValidator()
+	req := httptest.NewRequest(method, "/expenses/1", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, contentType)
+	for i := 0; i < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	res := httptest.NewRecorder()
+	c := e.NewContext(req, res)
+	users.SetCurrentUser(c, testUser)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	var err error
+	switch method {
+	case http.MethodGet:
+		err = h.GetExpenseByID(c)
+	case http.MethodPut:
+		err = h.UpdateExpenseByID(c)
+	case http.MethodPatch:
+		err = h.PatchExpenseByID(c)
+	}
+	assert.NoError(t, err)
+	return res
+}
+
+func newETagHandler(t *testing.T) handler {
+	repo := NewMemoryRepository()
+	exp := Expenses{Title: "rice", Amount: 500000, Currency: "THB", OwnerID: testUser.ID, Tags: []string{"food"}}
+	if err := repo.Create(context.Background(), &exp); err != nil {
+		t.Fatal(err)
+	}
+	return handler{repo: repo}
+}
+
+func TestExpenseETag(t *testing.T) {
+	tea := `{"title":"tea","amount":30,"tags":["food"]}`
+
+	t.Run("Should answer not modified for a matching If-None-Match", func(t *testing.T) {
+		// Arrange
+		h := newETagHandler(t)
+
+		// Act
+		first := conditionalRequest(t, h, http.MethodGet, "", "")
+		cached := conditionalRequest(t, h, http.MethodGet, "", "", HeaderIfNoneMatch, `W/"1"`)
+		stale := conditionalRequest(t, h, http.MethodGet, "", "", HeaderIfNoneMatch, `"0"`)
+
+		// Assert
+		assert.Equal(t, `"1"`, first.Header().Get(HeaderETag))
+		assert.Equal(t, http.StatusNotModified, cached.Code)
+		assert.Empty(t, cached.Body.String())
+		assert.Equal(t, `"1"`, cached.Header().Get(HeaderETag))
+		assert.Equal(t, http.StatusOK, stale.Code)
+	})
+
+	t.Run("Should update the version named by If-Match only once", func(t *testing.T) {
+		// Arrange
+		h := newETagHandler(t)
+
+		// Act
+		updated := conditionalRequest(t, h, http.MethodPut, echo.MIMEApplicationJSON, tea, HeaderIfMatch, `"1"`)
+		conflict := conditionalRequest(t, h, http.MethodPut, echo.MIMEApplicationJSON, tea, HeaderIfMatch, `"1"`)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, updated.Code)
+		assert.Equal(t, `"2"`, updated.Header().Get(HeaderETag))
+		assert.Equal(t, http.StatusPreconditionFailed, conflict.Code)
+		assert.Equal(t, `{"statusCode":412,"message":"Expense has been changed since it was read"}`, strings.TrimSpace(conflict.Body.String()))
+	})
+
+	t.Run("Should reject a patch of a changed expense", func(t *testing.T) {
+		// Arrange
+		h := newETagHandler(t)
+		conditionalRequest(t, h, http.MethodPatch, MIMEMergePatch, `{"note":"x"}`)
+
+		// Act
+		res := conditionalRequest(t, h, http.MethodPatch, MIMEMergePatch, `{"note":"y"}`, HeaderIfMatch, `"1"`)
+		current := conditionalRequest(t, h, http.MethodGet, "", "")
+
+		// Assert
+		assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+		assert.Contains(t, current.Body.String(), `"note":"x"`)
+	})
+
+	t.Run("Should require If-Match when configured", func(t *testing.T) {
+		// Arrange
+		h := newETagHandler(t)
+		h.requireIfMatch = true
+
+		// Act
+		missing := conditionalRequest(t, h, http.MethodPut, echo.MIMEApplicationJSON, tea)
+		wildcard := conditionalRequest(t, h, http.MethodPatch, MIMEMergePatch, `{"note":"x"}`, HeaderIfMatch, "*")
+
+		// Assert
+		assert.Equal(t, http.StatusPreconditionRequired, missing.Code)
+		assert.Equal(t, http.StatusOK, wildcard.Code)
+	})
+
+	t.Run("Should change the ETag when an attachment is added", func(t *testing.T) {
+		// Arrange
+		f := newAttachmentFixture(t)
+
+		// Act
+		upload(t, f.h, "1", "receipt.pdf", pdfFile)
+		res := conditionalRequest(t, f.h, http.MethodGet, "", "", HeaderIfNoneMatch, `"1"`)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, `"2"`, res.Header().Get(HeaderETag))
+	})
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header   string
+		weak     bool
+		expected bool
+	}{
+		{`"3"`, false, true},
+		{`"1", "3"`, false, true},
+		{`*`, false, true},
+		{`W/"3"`, false, false},
+		{`W/"3"`, true, true},
+		{`"4"`, true, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, etagMatches(tt.header, `"3"`, tt.weak), tt.header)
+	}
+}
+
+func TestPostgresConditionalUpdate(t *testing.T) {
+	t.Run("Should report a version mismatch for an expense that has moved on", func(t *testing.T) {
+		// Arrange
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+		mock.ExpectQuery("UPDATE expenses").
+			WithArgs("tea", sqlmock.AnyArg(), "THB", "", sqlmock.AnyArg(), nil, "1", testUser.ID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "spent_at", "created_at", "updated_at", "version"}))
+		mock.ExpectQuery("SELECT (.+) FROM expenses").
+			WillReturnRows(sqlmock.NewRows([]string{"ID", "Title", "Amount", "Currency", "Note", "Tags", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).
+				AddRow("1", "rice", 50.00, "THB", "", "{food}", testTime, testTime, testTime, 2))
+
+		// Act
+		exp := Expenses{Title: "tea", Amount: 300000, Currency: "THB", Version: 1}
+		err := NewPostgresRepository(db).Update(context.Background(), testUser.ID, "1", &exp)
+
+		// Assert
+		assert.Equal(t, errVersionMismatch, err)
+	})
+}
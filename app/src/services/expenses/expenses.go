@@ -1,24 +1,149 @@
 package expenses
 
-import "database/sql"
+import (
+	"context"
+	"time"
+
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/services/budgets"
+	"github.com/RTae/assessment/app/src/storage"
+	"github.com/labstack/echo/v4"
+)
 
 type handler struct {
-	db *sql.DB
+	repo ExpenseRepository
+	// location is the zone times are reported in, UTC when nil.
+	location *time.Location
+	// budgets flags the budgets a write pushes over their limit, skipped
+	// when nil.
+	budgets BudgetChecker
+	// attachments and files keep the receipts of expenses, which are not
+	// available when either is nil.
+	attachments     AttachmentRepository
+	files           storage.Storage
+	attachmentLimit int64
+	// idempotency remembers the responses to requests sent with an
+	// Idempotency-Key, which is ignored when nil.
+	idempotency    IdempotencyRepository
+	idempotencyTTL time.Duration
+	// requireIfMatch rejects updates sent without an If-Match header.
+	requireIfMatch bool
+	// audit records a revision of every change made through the handler.
+	audit bool
+}
+
+// BudgetChecker reports the budgets of owner that an expense in currency
+// with tags spent at at counts toward and that are over their limit.
+type BudgetChecker interface {
+	Exceeded(ctx context.Context, owner int, tags []string, currency string, at time.Time) ([]budgets.Status, error)
 }
 
 type Expenses struct {
-	ID     int      `json:"id"`
-	Title  string   `json:"title"`
-	Amount float32  `json:"amount"`
-	Note   string   `json:"note"`
-	Tags   []string `json:"tags"`
+	ID       int          `json:"id"`
+	Title    string       `json:"title"`
+	Amount   money.Amount `json:"amount"`
+	Currency string       `json:"currency"`
+	Note     string       `json:"note"`
+	Tags     []string     `json:"tags"`
+	OwnerID  int          `json:"-"`
+	// SpentAt is when the money was spent and defaults to the time the
+	// expense is created. CreatedAt and UpdatedAt are managed by the server.
+	SpentAt   time.Time `json:"spent_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Version counts the changes to the expense and is sent as its ETag.
+	Version int `json:"-"`
+}
+
+// expenseResponse is a written expense with the budgets it counts toward
+// that are now over their limit.
+type expenseResponse struct {
+	Expenses
+	OverBudget []budgets.Status `json:"over_budget,omitempty"`
+}
+
+// Options configures the expense handlers. Every field may be left zero.
+type Options struct {
+	// Location is the zone times are reported in, UTC when nil.
+	Location *time.Location
+	// Budgets flags the budgets a write pushes over their limit.
+	Budgets BudgetChecker
+	// Attachments and Files keep the receipts of expenses. AttachmentLimit
+	// caps the size of an attachment in bytes, DefaultMaxAttachmentSize when
+	// not positive.
+	Attachments     AttachmentRepository
+	Files           storage.Storage
+	AttachmentLimit int64
+	// Idempotency remembers responses for IdempotencyTTL,
+	// DefaultIdempotencyTTL when not positive.
+	Idempotency    IdempotencyRepository
+	IdempotencyTTL time.Duration
+	// RequireIfMatch makes PUT and PATCH fail with 428 unless they name the
+	// version they replace.
+	RequireIfMatch bool
+	// Audit keeps the history of every change.
+	Audit bool
+}
+
+// CreateHandler builds the expense handlers on repo.
+func CreateHandler(repo ExpenseRepository, opts Options) *handler {
+	return &handler{
+		repo:            repo,
+		location:        opts.Location,
+		budgets:         opts.Budgets,
+		attachments:     opts.Attachments,
+		files:           opts.Files,
+		attachmentLimit: opts.AttachmentLimit,
+		idempotency:     opts.Idempotency,
+		idempotencyTTL:  opts.IdempotencyTTL,
+		requireIfMatch:  opts.RequireIfMatch,
+		audit:           opts.Audit,
+	}
+}
+
+func (h *handler) zone() *time.Location {
+	if h.location == nil {
+		return time.UTC
+	}
+	return h.location
+}
+
+// localize reports the times of exp in the configured zone.
+func (h *handler) localize(exp *Expenses) {
+	exp.SpentAt = exp.SpentAt.In(h.zone())
+	exp.CreatedAt = exp.CreatedAt.In(h.zone())
+	exp.UpdatedAt = exp.UpdatedAt.In(h.zone())
+}
+
+// written localizes exp and adds the budgets it is over. The expense is
+// already stored, so a failing check is only logged.
+func (h *handler) written(c echo.Context, exp Expenses) expenseResponse {
+	h.localize(&exp)
+	res := expenseResponse{Expenses: exp}
+	if h.budgets == nil {
+		return res
+	}
+	exceeded, err := h.budgets.Exceeded(c.Request().Context(), exp.OwnerID, exp.Tags, exp.Currency, exp.SpentAt)
+	if err != nil {
+		c.Logger().Error(err)
+		return res
+	}
+	res.OverBudget = exceeded
+	return res
 }
 
-type ErrorResponse struct {
-	Code    int    `json:"statusCode"`
-	Message string `json:"message"`
+// keepServerFields copies the fields clients may not change from the stored
+// expense.
+func (e *Expenses) keepServerFields(stored Expenses) {
+	e.ID = stored.ID
+	e.OwnerID = stored.OwnerID
+	e.CreatedAt = stored.CreatedAt
+	e.UpdatedAt = stored.UpdatedAt
+	e.Version = stored.Version
 }
 
-func CreateHandler(db *sql.DB) *handler {
-	return &handler{db}
+func (e *Expenses) defaultCurrency() {
+	if e.Currency == "" {
+		e.Currency = money.DefaultCurrency
+	}
 }
@@ -0,0 +1,238 @@
+package expenses
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/xlsx"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	ExportCSV   = "csv"
+	ExportJSONL = "jsonl"
+	ExportXLSX  = "xlsx"
+)
+
+// exportFlushRows is how many rows are written between flushes so clients
+// see the download progress.
+const exportFlushRows = 500
+
+// exportColumns are the CSV and XLSX columns. The header names match the
+// fields the CSV import reads, so an export can be imported again.
+var exportColumns = []string{"id", "title", "amount", "currency", "note", "tags", "spent_at", "created_at", "updated_at"}
+
+var exportContentTypes = map[string]string{
+	ExportCSV:   "text/csv; charset=utf-8",
+	ExportJSONL: "application/x-ndjson",
+	ExportXLSX:  xlsx.ContentType,
+}
+
+// exportWriter encodes expenses in one format. Flush hands the rows written
+// so far to the response; Close also ends the document.
+type exportWriter interface {
+	Write(exp Expenses) error
+	Flush() error
+	Close() error
+}
+
+func newExportWriter(format string, w io.Writer) (exportWriter, error) {
+	switch format {
+	case ExportJSONL:
+		return jsonlExport{json.NewEncoder(w)}, nil
+	case ExportXLSX:
+		sheet, err := xlsx.NewWriter(w, "Expenses")
+		if err != nil {
+			return nil, err
+		}
+		header := make([]interface{}, len(exportColumns))
+		for i, column := range exportColumns {
+			header[i] = column
+		}
+		if err := sheet.WriteRow(header...); err != nil {
+			return nil, err
+		}
+		return xlsxExport{sheet}, nil
+	default:
+		out := csv.NewWriter(w)
+		if err := out.Write(exportColumns); err != nil {
+			return nil, err
+		}
+		return csvExport{out}, nil
+	}
+}
+
+type csvExport struct{ w *csv.Writer }
+
+// csvFormulaPrefixes are the first characters that make a spreadsheet read a
+// cell as a formula.
+const csvFormulaPrefixes = "=+-@"
+
+// csvText escapes a text cell that a spreadsheet would run as a formula by
+// prefixing it with a quote, which spreadsheets show as plain text.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvUnquote takes back the quote csvText adds, so an export imports as it
+// was written.
+func csvUnquote(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+func (e csvExport) Write(exp Expenses) error {
+	return e.w.Write([]string{
+		strconv.Itoa(exp.ID),
+		csvText(exp.Title),
+		exp.Amount.String(),
+		csvText(exp.Currency),
+		csvText(exp.Note),
+		csvText(strings.Join(exp.Tags, ";")),
+		exp.SpentAt.Format(time.RFC3339),
+		exp.CreatedAt.Format(time.RFC3339),
+		exp.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+func (e csvExport) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e csvExport) Close() error { return e.Flush() }
+
+type jsonlExport struct{ enc *json.Encoder }
+
+func (e jsonlExport) Write(exp Expenses) error {
+	if exp.Tags == nil {
+		exp.Tags = []string{}
+	}
+	return e.enc.Encode(exp)
+}
+
+func (e jsonlExport) Flush() error { return nil }
+
+func (e jsonlExport) Close() error { return nil }
+
+type xlsxExport struct{ sheet *xlsx.Writer }
+
+func (e xlsxExport) Write(exp Expenses) error {
+	return e.sheet.WriteRow(
+		exp.ID,
+		exp.Title,
+		xlsx.Number(exp.Amount.String()),
+		exp.Currency,
+		exp.Note,
+		strings.Join(exp.Tags, ";"),
+		exp.SpentAt,
+		exp.CreatedAt,
+		exp.UpdatedAt,
+	)
+}
+
+func (e xlsxExport) Flush() error { return e.sheet.Flush() }
+
+func (e xlsxExport) Close() error { return e.sheet.Close() }
+
+func parseExportFormat(c echo.Context) (string, error) {
+	switch format := c.QueryParam("format"); format {
+	case "":
+		return ExportCSV, nil
+	case ExportCSV, ExportJSONL, ExportXLSX:
+		return format, nil
+	default:
+		return "", errors.New("Query param format must be csv, jsonl or xlsx")
+	}
+}
+
+// ExportExpenses streams every expense matching the list filters and sort in
+// the requested format. Rows go from the database cursor to the response
+// without being collected, so the response is only started once the first
+// row arrives; a failure before that is still answered with a JSON error,
+// while one after it cuts the download short and is only logged.
+func (h *handler) ExportExpenses(c echo.Context) error {
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	format, err := parseExportFormat(c)
+	if err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: err.Error()},
+		)
+	}
+
+	q, err := parseListQuery(c, h.zone())
+	if err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: err.Error()},
+		)
+	}
+	// An export always covers the whole result.
+	q.OwnerID = ownerID
+	q.Paginate = false
+	q.Cursor = nil
+
+	res := c.Response()
+	var out exportWriter
+	begin := func() error {
+		res.Header().Set(echo.HeaderContentType, exportContentTypes[format])
+		res.Header().Set(echo.HeaderContentDisposition,
+			fmt.Sprintf(`attachment; filename="expenses-%s.%s"`, time.Now().In(h.zone()).Format("20060102"), format))
+		res.WriteHeader(http.StatusOK)
+		w, err := newExportWriter(format, res)
+		out = w
+		return err
+	}
+
+	rows := 0
+	err = h.repo.Stream(c.Request().Context(), q, func(exp Expenses) error {
+		if out == nil {
+			if err := begin(); err != nil {
+				return err
+			}
+		}
+		h.localize(&exp)
+		if err := out.Write(exp); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushRows == 0 {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+			res.Flush()
+		}
+		return nil
+	})
+	if err == nil && out == nil {
+		err = begin()
+	}
+	if err != nil {
+		if res.Committed {
+			c.Logger().Errorf("request %s: export: %v", apperr.RequestID(c), err)
+			return nil
+		}
+		return apperr.Respond(c, err)
+	}
+	if err := out.Close(); err != nil {
+		c.Logger().Errorf("request %s: export: %v", apperr.RequestID(c), err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+//go:build it
+
+package expenses
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportExpenses(t *testing.T) {
+	// setup echo server
+	e, settings, close := SetupServer(t)
+	PingServer()
+
+	t.Run("Should stream the filtered expenses as CSV", func(t *testing.T) {
+		// Arrange
+		body := "title,amount,tags\nexport rice,50,export-it\nexport tea,30,export-it\nexport cake,90,other\n"
+		var report ImportReport
+		err := Request(t, http.MethodPost, Uri(fmt.Sprint(settings.Port), "expenses", "import"), strings.NewReader(body)).Decode(&report)
+		assert.NoError(t, err)
+
+		// Act
+		res := Request(t, http.MethodGet, Uri(fmt.Sprint(settings.Port), "expenses", "export?tag=export-it&sort=title"), nil)
+		if !assert.NoError(t, res.err) {
+			return
+		}
+		defer res.Body.Close()
+		raw, err := io.ReadAll(res.Body)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, "text/csv; charset=utf-8", res.Header.Get(echo.HeaderContentType))
+			assert.Contains(t, res.Header.Get(echo.HeaderContentDisposition), "attachment; filename=")
+			lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+			if assert.Len(t, lines, 3) {
+				assert.Contains(t, lines[1], ",export rice,50,THB,,export-it,")
+				assert.Contains(t, lines[2], ",export tea,30,THB,,export-it,")
+			}
+		}
+	})
+
+	// teardown echo server
+	TeardownServer(t, e, close)
+}
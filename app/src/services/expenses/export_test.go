@@ -0,0 +1,287 @@
+//go:build unit
+
+package expenses
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func exportRequest(t *testing.T, h handler, query string) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses/export"+query, nil)
+	res := httptest.NewRecorder()
+	c := e.NewContext(req, res)
+	users.SetCurrentUser(c, testUser)
+
+	err := h.ExportExpenses(c)
+	assert.NoError(t, err)
+	return res
+}
+
+func exportRepository(t *testing.T) ExpenseRepository {
+	repo := NewMemoryRepository().(*memoryRepository)
+	repo.now = func() time.Time { return testTime }
+	for _, exp := range []Expenses{
+		{Title: "rice", Amount: 500000, Currency: "THB", Tags: []string{"food", "lunch"}, SpentAt: testTime},
+		{Title: "taxi, airport", Amount: 3502500, Currency: "THB", Note: `said "thanks"`, SpentAt: testTime.AddDate(0, 0, 1)},
+		{Title: "coffee", Amount: 35000, Currency: "USD", Tags: []string{"food"}, SpentAt: testTime.AddDate(0, 0, 2)},
+	} {
+		exp.OwnerID = testUser.ID
+		if err := repo.Create(context.Background(), &exp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+// flushRecorder records how many lines the client had received at each flush.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []int
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushed = append(r.flushed, strings.Count(r.Body.String(), "\n"))
+	r.ResponseRecorder.Flush()
+}
+
+func TestExportExpensesHandler(t *testing.T) {
+	t.Run("Should stream CSV that the import can read back", func(t *testing.T) {
+		// Arrange
+		repo := exportRepository(t)
+		bangkok := time.FixedZone("ICT", 7*60*60)
+		h := handler{repo: repo, location: bangkok}
+
+		// Act
+		res := exportRequest(t, h, "?sort=-spent_at")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", res.Header().Get(echo.HeaderContentType))
+		assert.Regexp(t, `^attachment; filename="expenses-\d{8}\.csv"$`, res.Header().Get(echo.HeaderContentDisposition))
+		lines := strings.Split(strings.TrimSpace(res.Body.String()), "\n")
+		assert.Equal(t, "id,title,amount,currency,note,tags,spent_at,created_at,updated_at", lines[0])
+		assert.Len(t, lines, 4)
+		assert.Equal(t, "3,coffee,3.5,USD,,food,2024-01-04T10:04:05+07:00,", lines[1][:len("3,coffee,3.5,USD,,food,2024-01-04T10:04:05+07:00,")])
+		assert.Contains(t, lines[2], `2,"taxi, airport",350.25,THB,"said ""thanks""",,2024-01-03T10:04:05+07:00,`)
+
+		imported := NewMemoryRepository()
+		_, report := importRequest(t, handler{repo: imported}, "", res.Body.String())
+		assert.Equal(t, 3, report.Imported)
+		rice, _ := imported.Get(context.Background(), testUser.ID, "3")
+		assert.Equal(t, []string{"food", "lunch"}, rice.Tags)
+		assert.True(t, testTime.Equal(rice.SpentAt))
+	})
+
+	t.Run("Should quote text cells a spreadsheet would run as formulas", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		exp := Expenses{Title: "=HYPERLINK(\"x\")", Amount: 500000, Currency: "THB", Note: "@SUM(A1)", Tags: []string{"-1", "food"}, OwnerID: testUser.ID}
+		if err := repo.Create(context.Background(), &exp); err != nil {
+			t.Fatal(err)
+		}
+		h := handler{repo: repo}
+
+		// Act
+		res := exportRequest(t, h, "")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, res.Code)
+		lines := strings.Split(strings.TrimSpace(res.Body.String()), "\n")
+		assert.True(t, strings.HasPrefix(lines[1], `1,"'=HYPERLINK(""x"")",50,THB,'@SUM(A1),'-1;food,`), lines[1])
+
+		imported := NewMemoryRepository()
+		_, report := importRequest(t, handler{repo: imported}, "", res.Body.String())
+		assert.Equal(t, 1, report.Imported)
+		got, _ := imported.Get(context.Background(), testUser.ID, "1")
+		assert.Equal(t, exp.Title, got.Title)
+		assert.Equal(t, exp.Note, got.Note)
+		assert.Equal(t, exp.Tags, got.Tags)
+	})
+
+	t.Run("Should log a failure after the download has started", func(t *testing.T) {
+		// Arrange
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+		mock.ExpectQuery("SELECT (.+) FROM expenses").WillReturnRows(
+			sqlmock.NewRows([]string{"ID", "Title", "Amount", "Currency", "Note", "Tags", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).
+				AddRow("1", "rice", 50.00, "THB", "", pq.Array([]string{}), testTime, testTime, testTime, 1).
+				AddRow("2", "tea", 30.00, "THB", "", pq.Array([]string{}), testTime, testTime, testTime, 1).
+				RowError(1, errors.New("connection reset")),
+		)
+		h := handler{repo: NewPostgresRepository(db)}
+		e := echo.New()
+		var logged bytes.Buffer
+		e.Logger.SetOutput(&logged)
+		req := httptest.NewRequest(http.MethodGet, "/expenses/export", nil)
+		res := httptest.NewRecorder()
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+
+		// Act
+		err := h.ExportExpenses(c)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Contains(t, logged.String(), "export: connection reset")
+	})
+
+	t.Run("Should honor the list filters", func(t *testing.T) {
+		// Arrange
+		h := handler{repo: exportRepository(t)}
+
+		// Act
+		res := exportRequest(t, h, "?format=jsonl&tag=food&min_amount=40&limit=1")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "application/x-ndjson", res.Header().Get(echo.HeaderContentType))
+		assert.Regexp(t, `^attachment; filename="expenses-\d{8}\.jsonl"$`, res.Header().Get(echo.HeaderContentDisposition))
+		assert.Equal(t,
+			`{"id":1,"title":"rice","amount":50,"currency":"THB","note":"","tags":["food","lunch"]`+testTimestamps+"}\n",
+			res.Body.String(),
+		)
+	})
+
+	t.Run("Should write a workbook for xlsx", func(t *testing.T) {
+		// Arrange
+		h := handler{repo: exportRepository(t)}
+
+		// Act
+		res := exportRequest(t, h, "?format=xlsx")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", res.Header().Get(echo.HeaderContentType))
+		book, err := zip.NewReader(bytes.NewReader(res.Body.Bytes()), int64(res.Body.Len()))
+		if !assert.NoError(t, err) {
+			return
+		}
+		var sheet string
+		for _, f := range book.File {
+			if f.Name == "xl/worksheets/sheet1.xml" {
+				r, _ := f.Open()
+				raw, _ := io.ReadAll(r)
+				sheet = string(raw)
+			}
+		}
+		assert.Equal(t, 4, strings.Count(sheet, "<row "))
+		assert.Contains(t, sheet, `<c r="C3"><v>350.25</v></c>`)
+		assert.Contains(t, sheet, `<t xml:space="preserve">said &#34;thanks&#34;</t>`)
+	})
+
+	t.Run("Should send only the header when nothing matches", func(t *testing.T) {
+		// Arrange
+		h := handler{repo: exportRepository(t)}
+
+		// Act
+		res := exportRequest(t, h, "?tag=travel")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "id,title,amount,currency,note,tags,spent_at,created_at,updated_at\n", res.Body.String())
+	})
+
+	t.Run("Should hand every written row to the client when flushing", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		for i := 0; i < exportFlushRows; i++ {
+			exp := Expenses{Title: "rice", Amount: 500000, Currency: "THB", OwnerID: testUser.ID}
+			if err := repo.Create(context.Background(), &exp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		h := handler{repo: repo}
+		res := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/expenses/export", nil), res)
+		users.SetCurrentUser(c, testUser)
+
+		// Act
+		err := h.ExportExpenses(c)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []int{exportFlushRows + 1}, res.flushed)
+	})
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"Should reject an unknown format", "?format=pdf", `{"statusCode":422,"message":"Query param format must be csv, jsonl or xlsx"}`},
+		{"Should reject an invalid filter", "?min_amount=abc", `{"statusCode":422,"message":"Query param min_amount must be number"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			h := handler{repo: NewMemoryRepository()}
+
+			// Act
+			res := exportRequest(t, h, tt.query)
+
+			// Assert
+			assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+			assert.Equal(t, tt.expected, strings.TrimSpace(res.Body.String()))
+		})
+	}
+
+	t.Run("Should return JSON error when the query fails before any row", func(t *testing.T) {
+		// Arrange
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+		mock.ExpectQuery("SELECT (.+) FROM expenses").WillReturnError(errors.New("connection reset"))
+		h := handler{repo: NewPostgresRepository(db)}
+
+		// Act
+		res := exportRequest(t, h, "")
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+		assert.Equal(t, `{"statusCode":500,"message":"Internal Server Error"}`, strings.TrimSpace(res.Body.String()))
+	})
+}
+
+func TestPostgresStream(t *testing.T) {
+	t.Run("Should hand every row to fn and stop at its error", func(t *testing.T) {
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("SELECT (.+) FROM expenses").
+			WillReturnRows(sqlmock.NewRows([]string{"ID", "Title", "Amount", "Currency", "Note", "Tags", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).
+				AddRow("1", "rice", 50.00, "THB", "", pq.Array([]string{"food"}), testTime, testTime, testTime, 1).
+				AddRow("2", "tea", 30.00, "THB", "", pq.Array([]string{}), testTime, testTime, testTime, 1).
+				AddRow("3", "cake", 90.00, "THB", "", pq.Array([]string{}), testTime, testTime, testTime, 1))
+
+		var titles []string
+		stop := errors.New("stop")
+		err := NewPostgresRepository(db).Stream(context.Background(), listQuery{listFilter: listFilter{OwnerID: testUser.ID}, SortColumn: "id"},
+			func(exp Expenses) error {
+				titles = append(titles, exp.Title)
+				if len(titles) == 2 {
+					return stop
+				}
+				return nil
+			})
+
+		assert.Equal(t, stop, err)
+		assert.Equal(t, []string{"rice", "tea"}, titles)
+	})
+}
@@ -0,0 +1,317 @@
+package expenses
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+var sortColumns = map[string]string{
+	"id":       "id",
+	"amount":   "amount",
+	"title":    "title",
+	"spent_at": "spent_at",
+}
+
+type ExpensesPage struct {
+	Data       []Expenses `json:"data"`
+	NextCursor string     `json:"next_cursor"`
+	Total      int        `json:"total"`
+}
+
+// listFilter holds the filters shared by every endpoint that reads a set of
+// expenses. OwnerID is always applied.
+type listFilter struct {
+	OwnerID   int
+	Tags      []string
+	MinAmount *money.Amount
+	MaxAmount *money.Amount
+	Title     string
+	Note      string
+	// SpentFrom is inclusive and SpentTo exclusive.
+	SpentFrom *time.Time
+	SpentTo   *time.Time
+}
+
+// listCursor points after the last expense of a page. Sort is the sort it
+// was issued for, such as -amount, so it cannot be replayed under another
+// one, and Value is the sort column of that expense, empty when sorting by
+// id.
+type listCursor struct {
+	Sort  string `json:"sort"`
+	ID    int    `json:"id"`
+	Value string `json:"value,omitempty"`
+}
+
+type listQuery struct {
+	listFilter
+	SortColumn string
+	Descending bool
+	Limit      int
+	Cursor     *listCursor
+	Paginate   bool
+}
+
+// parseDate reads an RFC 3339 time or a YYYY-MM-DD date, which is taken as
+// the start of that day in loc. endOfDay moves a plain date to the start of
+// the next day so it can be used as an exclusive upper bound.
+func parseDate(raw string, loc *time.Location, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", raw, loc)
+	if err != nil {
+		return t, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func parseListFilter(c echo.Context, loc *time.Location) (listFilter, error) {
+	var f listFilter
+	for _, tags := range c.QueryParams()["tag"] {
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				f.Tags = append(f.Tags, tag)
+			}
+		}
+	}
+
+	amounts := []struct {
+		name string
+		dst  **money.Amount
+	}{
+		{"min_amount", &f.MinAmount},
+		{"max_amount", &f.MaxAmount},
+	}
+	for _, amount := range amounts {
+		raw := c.QueryParam(amount.name)
+		if raw == "" {
+			continue
+		}
+		v, err := money.Parse(raw)
+		if err != nil {
+			return f, fmt.Errorf("Query param %s must be number", amount.name)
+		}
+		*amount.dst = &v
+	}
+
+	dates := []struct {
+		name     string
+		dst      **time.Time
+		endOfDay bool
+	}{
+		{"from", &f.SpentFrom, false},
+		{"to", &f.SpentTo, true},
+	}
+	for _, date := range dates {
+		raw := c.QueryParam(date.name)
+		if raw == "" {
+			continue
+		}
+		v, err := parseDate(raw, loc, date.endOfDay)
+		if err != nil {
+			return f, fmt.Errorf("Query param %s must be RFC 3339 time or YYYY-MM-DD date", date.name)
+		}
+		*date.dst = &v
+	}
+
+	f.Title = c.QueryParam("title")
+	f.Note = c.QueryParam("note")
+	return f, nil
+}
+
+func parseListQuery(c echo.Context, loc *time.Location) (listQuery, error) {
+	q := listQuery{SortColumn: "id", Limit: defaultPageLimit}
+
+	var err error
+	q.listFilter, err = parseListFilter(c, loc)
+	if err != nil {
+		return q, err
+	}
+
+	if sort := c.QueryParam("sort"); sort != "" {
+		q.Descending = strings.HasPrefix(sort, "-")
+		column, ok := sortColumns[strings.TrimPrefix(sort, "-")]
+		if !ok {
+			return q, errors.New("Query param sort must be one of id, amount, title, spent_at")
+		}
+		q.SortColumn = column
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		q.Paginate = true
+		q.Limit, err = strconv.Atoi(limit)
+		if err != nil || q.Limit < 1 || q.Limit > maxPageLimit {
+			return q, fmt.Errorf("Query param limit must be integer between 1 and %d", maxPageLimit)
+		}
+	}
+
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		q.Paginate = true
+		q.Cursor, err = decodeCursor(cursor)
+		if err != nil || !q.validCursor() {
+			return q, errors.New("Query param cursor is invalid")
+		}
+		if q.Cursor.Sort != q.sortKey() {
+			return q, errors.New("Query param cursor belongs to another sort")
+		}
+	}
+
+	return q, nil
+}
+
+func encodeCursor(cursor listCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (*listCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// sortKey names the sort of q the way the sort query param does.
+func (q listQuery) sortKey() string {
+	if q.Descending {
+		return "-" + q.SortColumn
+	}
+	return q.SortColumn
+}
+
+// validCursor tells whether the value of the cursor can be compared with
+// the sort column of q.
+func (q listQuery) validCursor() bool {
+	if q.Cursor.ID < 1 {
+		return false
+	}
+	var err error
+	switch q.SortColumn {
+	case "amount":
+		_, err = money.Parse(q.Cursor.Value)
+	case "spent_at":
+		_, err = time.Parse(time.RFC3339Nano, q.Cursor.Value)
+	}
+	return err == nil
+}
+
+// where renders the filter as a SQL condition. Placeholders are numbered
+// after the given args so the caller can keep appending to them.
+func (f listFilter) where(args []interface{}) (string, []interface{}) {
+	var conds []string
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conds = append(conds, "owner_id = "+arg(f.OwnerID), "deleted_at IS NULL")
+	if len(f.Tags) > 0 {
+		conds = append(conds, "tags @> "+arg(pq.Array(f.Tags)))
+	}
+	if f.MinAmount != nil {
+		conds = append(conds, "amount >= "+arg(*f.MinAmount))
+	}
+	if f.MaxAmount != nil {
+		conds = append(conds, "amount <= "+arg(*f.MaxAmount))
+	}
+	if f.Title != "" {
+		conds = append(conds, "title ILIKE "+arg("%"+escapeLike(f.Title)+"%"))
+	}
+	if f.Note != "" {
+		conds = append(conds, "note ILIKE "+arg("%"+escapeLike(f.Note)+"%"))
+	}
+	if f.SpentFrom != nil {
+		conds = append(conds, "spent_at >= "+arg(*f.SpentFrom))
+	}
+	if f.SpentTo != nil {
+		conds = append(conds, "spent_at < "+arg(*f.SpentTo))
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+func (q listQuery) sql() (string, []interface{}) {
+	where, args := q.where(nil)
+
+	op, dir := ">", "ASC"
+	if q.Descending {
+		op, dir = "<", "DESC"
+	}
+
+	if q.Cursor != nil && q.SortColumn == "id" {
+		args = append(args, q.Cursor.ID)
+		where += fmt.Sprintf(" AND id %s $%d", op, len(args))
+	} else if q.Cursor != nil {
+		args = append(args, q.Cursor.Value, q.Cursor.ID)
+		where += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", q.SortColumn, op, len(args)-1, len(args))
+	}
+
+	sql := fmt.Sprintf(`
+	SELECT id, title, amount, currency, note, tags, spent_at, created_at, updated_at, version
+	FROM expenses
+	WHERE %s
+	ORDER BY %s %s, id %s
+	`, where, q.SortColumn, dir, dir)
+
+	if q.Paginate {
+		args = append(args, q.Limit+1)
+		sql += fmt.Sprintf("LIMIT $%d\n", len(args))
+	}
+	return sql, args
+}
+
+func (q listQuery) countSQL() (string, []interface{}) {
+	where, args := q.where(nil)
+	return fmt.Sprintf(`
+	SELECT COUNT(*)
+	FROM expenses
+	WHERE %s
+	`, where), args
+}
+
+// nextCursor trims the extra row fetched by sql and returns the cursor
+// pointing after the last returned expense.
+func (q listQuery) nextCursor(expenses []Expenses) ([]Expenses, string) {
+	if len(expenses) <= q.Limit {
+		return expenses, ""
+	}
+	expenses = expenses[:q.Limit]
+	last := expenses[len(expenses)-1]
+
+	cursor := listCursor{Sort: q.sortKey(), ID: last.ID}
+	switch q.SortColumn {
+	case "amount":
+		cursor.Value = last.Amount.String()
+	case "title":
+		cursor.Value = last.Title
+	case "spent_at":
+		cursor.Value = last.SpentAt.UTC().Format(time.RFC3339Nano)
+	}
+	return expenses, encodeCursor(cursor)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
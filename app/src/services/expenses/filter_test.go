@@ -0,0 +1,82 @@
+//go:build unit
+
+package expenses
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListFilterWhere(t *testing.T) {
+	t.Run("Should render every filter as a numbered condition", func(t *testing.T) {
+		// Arrange
+		min, max := money.Amount(100000), money.Amount(1000000)
+		from, to := testTime, testTime.AddDate(0, 1, 0)
+		f := listFilter{
+			OwnerID:   7,
+			Tags:      []string{"food"},
+			MinAmount: &min,
+			MaxAmount: &max,
+			Title:     "50%_off",
+			Note:      "market",
+			SpentFrom: &from,
+			SpentTo:   &to,
+		}
+		expected := "owner_id = $2 AND deleted_at IS NULL AND tags @> $3 AND amount >= $4 AND amount <= $5 AND title ILIKE $6 AND note ILIKE $7 AND spent_at >= $8 AND spent_at < $9"
+
+		// Act
+		where, args := f.where([]interface{}{"first"})
+
+		// Assert
+		assert.Equal(t, expected, where)
+		assert.Equal(t, []interface{}{"first", 7, pq.Array([]string{"food"}), min, max, `%50\%\_off%`, "%market%", from, to}, args)
+	})
+
+	t.Run("Should only scope to owner and exclude deleted expenses without filters", func(t *testing.T) {
+		// Act
+		where, args := listFilter{OwnerID: 7}.where(nil)
+
+		// Assert
+		assert.Equal(t, "owner_id = $1 AND deleted_at IS NULL", where)
+		assert.Equal(t, []interface{}{7}, args)
+	})
+}
+
+func TestParseDate(t *testing.T) {
+	bangkok := time.FixedZone("ICT", 7*60*60)
+
+	tests := []struct {
+		name     string
+		raw      string
+		endOfDay bool
+		expected time.Time
+	}{
+		{"Should read RFC 3339 time as is", "2024-01-02T03:04:05Z", true, testTime},
+		{"Should read date as start of day in location", "2024-01-02", false, time.Date(2024, 1, 2, 0, 0, 0, 0, bangkok)},
+		{"Should read date as start of next day for upper bound", "2024-01-02", true, time.Date(2024, 1, 3, 0, 0, 0, 0, bangkok)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got, err := parseDate(tt.raw, bangkok, tt.endOfDay)
+
+			// Assert
+			if assert.NoError(t, err) {
+				assert.True(t, tt.expected.Equal(got), "expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("Should reject other formats", func(t *testing.T) {
+		// Act
+		_, err := parseDate("02/01/2024", bangkok, false)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
@@ -2,90 +2,89 @@ package expenses
 
 import (
 	"net/http"
-	"regexp"
 
+	"github.com/RTae/assessment/app/src/apperr"
 	"github.com/labstack/echo/v4"
-	"github.com/lib/pq"
 )
 
 func (h *handler) GetExpenseByID(c echo.Context) error {
-	var e Expenses
 	id := c.Param("id")
 	if id == "" {
 		return c.JSON(
 			http.StatusUnprocessableEntity,
-			ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Param id is empty"},
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Param id is empty"},
 		)
 	}
 
-	sql := `
-	SELECT id, title, amount, note, tags
-	FROM expenses
-	WHERE id = $1
-	`
-	err := h.db.QueryRow(sql, id).Scan(&e.ID, &e.Title, &e.Amount, &e.Note, pq.Array(&e.Tags))
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
 
+	e, err := h.repo.Get(c.Request().Context(), ownerID, id)
 	if err != nil {
-		match, errMatch := regexp.MatchString("invalid input syntax", err.Error())
-		if match {
-			return c.JSON(
-				http.StatusUnprocessableEntity,
-				ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Param id must be integer"},
-			)
-		}
-		if errMatch != nil {
-			return c.JSON(
-				http.StatusInternalServerError,
-				ErrorResponse{Code: http.StatusInternalServerError, Message: err.Error()},
-			)
-		}
-		match, errMatch = regexp.MatchString("no rows in result set", err.Error())
-		if match {
-			return c.JSON(
-				http.StatusNotFound,
-				ErrorResponse{Code: http.StatusNotFound, Message: "Record not found"},
-			)
-		}
-		if errMatch != nil {
-			return c.JSON(
-				http.StatusInternalServerError,
-				ErrorResponse{Code: http.StatusInternalServerError, Message: err.Error()},
-			)
-		}
-		return c.JSON(
-			http.StatusInternalServerError,
-			ErrorResponse{Code: http.StatusInternalServerError, Message: err.Error()},
-		)
+		return apperr.Respond(c, err)
 	}
-	return c.JSON(http.StatusOK, e)
+	setETag(c, e)
+	if inm := c.Request().Header.Get(HeaderIfNoneMatch); inm != "" && etagMatches(inm, etag(e), true) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	h.localize(&e)
+	if h.attachments == nil {
+		return c.JSON(http.StatusOK, e)
+	}
+
+	detail := expenseDetail{Expenses: e}
+	detail.Attachments, err = h.attachments.List(c.Request().Context(), e.ID)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	for i := range detail.Attachments {
+		h.localizeAttachment(&detail.Attachments[i])
+	}
+	return c.JSON(http.StatusOK, detail)
 }
 
 func (h *handler) GetExpenses(c echo.Context) error {
-	var expenses []Expenses
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
 
-	sql := `
-	SELECT id, title, amount, note, tags
-	FROM expenses
-	`
-	rows, err := h.db.Query(sql)
+	q, err := parseListQuery(c, h.zone())
 	if err != nil {
 		return c.JSON(
-			http.StatusInternalServerError,
-			ErrorResponse{Code: http.StatusInternalServerError, Message: err.Error()},
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: err.Error()},
 		)
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var e Expenses
-		err := rows.Scan(&e.ID, &e.Title, &e.Amount, &e.Note, pq.Array(&e.Tags))
-		if err != nil {
-			return c.JSON(
-				http.StatusInternalServerError,
-				ErrorResponse{Code: http.StatusInternalServerError, Message: err.Error()},
-			)
-		}
-		expenses = append(expenses, e)
+	q.OwnerID = ownerID
+
+	expenses, err := h.repo.List(c.Request().Context(), q)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	for i := range expenses {
+		h.localize(&expenses[i])
 	}
-	return c.JSON(http.StatusOK, expenses)
+
+	// Without limit or cursor the endpoint keeps answering with the bare
+	// array older clients expect.
+	if !q.Paginate {
+		return c.JSON(http.StatusOK, expenses)
+	}
+
+	page := ExpensesPage{Data: []Expenses{}}
+	if expenses != nil {
+		page.Data = expenses
+	}
+	page.Data, page.NextCursor = q.nextCursor(page.Data)
+
+	page.Total, err = h.repo.Count(c.Request().Context(), q.listFilter)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	return c.JSON(http.StatusOK, page)
 }
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/RTae/assessment/app/src/apperr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,7 +43,7 @@ func TestGetExpenseByID(t *testing.T) {
 		expectedMessage := "Param id must be integer"
 
 		// Act
-		var errRes ErrorResponse
+		var errRes apperr.ErrorResponse
 		res := Request(t, http.MethodGet, Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%s", expenseId)), nil)
 		err := res.Decode(&errRes)
 
@@ -60,7 +61,7 @@ func TestGetExpenseByID(t *testing.T) {
 		expectedMessage := "Record not found"
 
 		// Act
-		var errRes ErrorResponse
+		var errRes apperr.ErrorResponse
 		res := Request(t, http.MethodGet, Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%s", expenseId)), nil)
 		err := res.Decode(&errRes)
 
@@ -96,6 +97,32 @@ func TestGetExpenses(t *testing.T) {
 		}
 	})
 
+	t.Run("Should get paginated expenses successfully", func(t *testing.T) {
+
+		// Arrange
+		SeedExpense(t, settings)
+		SeedExpense(t, settings)
+
+		// Act
+		var first, second ExpensesPage
+		res := Request(t, http.MethodGet, Uri(fmt.Sprint(settings.Port), "expenses?limit=1&tag=debt"), nil)
+		err := res.Decode(&first)
+		if assert.NoError(t, err) {
+			res = Request(t, http.MethodGet, Uri(fmt.Sprint(settings.Port), "expenses?limit=1&tag=debt&cursor="+first.NextCursor), nil)
+			err = res.Decode(&second)
+		}
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Len(t, first.Data, 1)
+			assert.Len(t, second.Data, 1)
+			assert.NotEqual(t, "", first.NextCursor)
+			assert.Less(t, first.Data[0].ID, second.Data[0].ID)
+			assert.GreaterOrEqual(t, first.Total, 2)
+		}
+	})
+
 	// teardown echo server
 	TeardownServer(t, e, close)
 }
@@ -3,15 +3,17 @@
 package expenses
 
 import (
-	"errors"
+	"database/sql"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/services/users"
 	"github.com/labstack/echo/v4"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
@@ -29,25 +31,28 @@ func TestGetExpenseHandler(t *testing.T) {
 		db, mock, close := handlers.MockDatabase(t)
 		defer close()
 
-		getMockRows := mock.NewRows([]string{"ID", "Title", "Amount", "Note", "Tags"}).
+		getMockRows := mock.NewRows([]string{"ID", "Title", "Amount", "Currency", "Note", "Tags", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).
 			AddRow(
 				"1",
 				"strawberry smoothie",
 				79.00,
+				"THB",
 				"night market promotion discount 10 bath",
 				pq.Array([]string{"food", "beverage"}),
+				testTime, testTime, testTime, 1,
 			)
 
 		mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = ?").
-			WithArgs(expenseID).
+			WithArgs(expenseID, testUser.ID).
 			WillReturnRows(getMockRows)
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
 		c.SetPath("/expense/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(expenseID)
-		expected := "{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"note\":\"night market promotion discount 10 bath\",\"tags\":[\"food\",\"beverage\"]}"
+		expected := "{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"currency\":\"THB\",\"note\":\"night market promotion discount 10 bath\",\"tags\":[\"food\",\"beverage\"]" + testTimestamps + "}"
 
 		// Act
 		err := h.GetExpenseByID(c)
@@ -60,6 +65,40 @@ func TestGetExpenseHandler(t *testing.T) {
 
 	})
 
+	t.Run("Should get expense of the owner chosen by admin", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		expenseID := "1"
+		req := httptest.NewRequest(http.MethodGet, "/expenses?owner=5", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		getMockRows := mock.NewRows([]string{"ID", "Title", "Amount", "Currency", "Note", "Tags", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).
+			AddRow("1", "strawberry smoothie", 79.00, "THB", "", pq.Array([]string{"food"}), testTime, testTime, testTime, 1)
+		mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = (.+) AND owner_id = (.+)").
+			WithArgs(expenseID, 5).
+			WillReturnRows(getMockRows)
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, users.User{ID: 2, Username: "admin", Role: users.RoleAdmin})
+		c.SetPath("/expense/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(expenseID)
+
+		// Act
+		err := h.GetExpenseByID(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+
 	t.Run("Should return unprocessable entity error if expense id is empty", func(t *testing.T) {
 		// Arrange
 		e := echo.New()
@@ -71,8 +110,9 @@ func TestGetExpenseHandler(t *testing.T) {
 		db, _, close := handlers.MockDatabase(t)
 		defer close()
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
 		c.SetPath("/expense/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(expenseID)
@@ -101,11 +141,12 @@ func TestGetExpenseHandler(t *testing.T) {
 		defer close()
 
 		mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = ?").
-			WithArgs(expenseID).
-			WillReturnError(errors.New("invalid input syntax"))
+			WithArgs(expenseID, testUser.ID).
+			WillReturnError(&pq.Error{Code: "22P02", Message: "invalid input syntax for type integer"})
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
 		c.SetPath("/expense/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(expenseID)
@@ -134,11 +175,12 @@ func TestGetExpenseHandler(t *testing.T) {
 		defer close()
 
 		mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = ?").
-			WithArgs(expenseID).
-			WillReturnError(errors.New("no rows in result set"))
+			WithArgs(expenseID, testUser.ID).
+			WillReturnError(sql.ErrNoRows)
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
 		c.SetPath("/expense/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(expenseID)
@@ -167,15 +209,16 @@ func TestGetExpenseHandler(t *testing.T) {
 		defer close()
 
 		mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = ?").
-			WithArgs(expenseID).
+			WithArgs(expenseID, testUser.ID).
 			WillReturnError(sqlmock.ErrCancelled)
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
 		c.SetPath("/expense/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(expenseID)
-		expected := "{\"statusCode\":500,\"message\":\"canceling query due to user request\"}"
+		expected := "{\"statusCode\":500,\"message\":\"Internal Server Error\"}"
 
 		// Act
 		err := h.GetExpenseByID(c)
@@ -198,20 +241,24 @@ func TestGetExpensesHandler(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		res := httptest.NewRecorder()
 
-		getMockRows := sqlmock.NewRows([]string{"ID", "Title", "Amount", "Note", "Tags"}).
+		getMockRows := sqlmock.NewRows([]string{"ID", "Title", "Amount", "Currency", "Note", "Tags", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).
 			AddRow(
 				"1",
 				"strawberry smoothie",
 				79.00,
+				"THB",
 				"night market promotion discount 10 bath",
 				pq.Array([]string{"food", "beverage"}),
+				testTime, testTime, testTime, 1,
 			).
 			AddRow(
 				"2",
 				"Grill pork",
 				100.00,
+				"THB",
 				"night market promotion discount 50 bath",
 				pq.Array([]string{"food"}),
+				testTime, testTime, testTime, 1,
 			)
 
 		db, mock, err := sqlmock.New()
@@ -223,10 +270,11 @@ func TestGetExpensesHandler(t *testing.T) {
 		mock.ExpectQuery("SELECT (.+) FROM expenses").
 			WillReturnRows(getMockRows)
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
 		c.SetPath("/expense")
-		expected := "[{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"note\":\"night market promotion discount 10 bath\",\"tags\":[\"food\",\"beverage\"]},{\"id\":2,\"title\":\"Grill pork\",\"amount\":100,\"note\":\"night market promotion discount 50 bath\",\"tags\":[\"food\"]}]"
+		expected := "[{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"currency\":\"THB\",\"note\":\"night market promotion discount 10 bath\",\"tags\":[\"food\",\"beverage\"]" + testTimestamps + "},{\"id\":2,\"title\":\"Grill pork\",\"amount\":100,\"currency\":\"THB\",\"note\":\"night market promotion discount 50 bath\",\"tags\":[\"food\"]" + testTimestamps + "}]"
 
 		// Act
 		err = h.GetExpenses(c)
@@ -255,10 +303,11 @@ func TestGetExpensesHandler(t *testing.T) {
 		mock.ExpectQuery("SELECT (.+) FROM expenses").
 			WillReturnError(sqlmock.ErrCancelled)
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
 		c.SetPath("/expense")
-		expected := "{\"statusCode\":500,\"message\":\"canceling query due to user request\"}"
+		expected := "{\"statusCode\":500,\"message\":\"Internal Server Error\"}"
 
 		// Act
 		err = h.GetExpenses(c)
@@ -271,4 +320,152 @@ func TestGetExpensesHandler(t *testing.T) {
 
 	})
 
+	t.Run("Should filter by date range and report times in configured zone", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		bangkok := time.FixedZone("ICT", 7*60*60)
+		req := httptest.NewRequest(http.MethodGet, "/expenses?from=2024-01-01&to=2024-01-31", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		getMockRows := sqlmock.NewRows([]string{"ID", "Title", "Amount", "Currency", "Note", "Tags", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).
+			AddRow("1", "strawberry smoothie", 79.00, "THB", "", pq.Array([]string{"food"}), testTime, testTime, testTime, 1)
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("SELECT (.+) FROM expenses WHERE owner_id = (.+) AND deleted_at IS NULL AND spent_at >= (.+) AND spent_at < (.+) ORDER BY id ASC").
+			WithArgs(testUser.ID, time.Date(2024, 1, 1, 0, 0, 0, 0, bangkok), time.Date(2024, 2, 1, 0, 0, 0, 0, bangkok)).
+			WillReturnRows(getMockRows)
+
+		h := handler{repo: NewPostgresRepository(db), location: bangkok}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		expected := "[{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"currency\":\"THB\",\"note\":\"\",\"tags\":[\"food\"],\"spent_at\":\"2024-01-02T10:04:05+07:00\",\"created_at\":\"2024-01-02T10:04:05+07:00\",\"updated_at\":\"2024-01-02T10:04:05+07:00\"}]"
+
+		// Act
+		err := h.GetExpenses(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("Should get paginated expenses with next cursor", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/expenses?limit=1&tag=food&sort=-amount", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		getMockRows := sqlmock.NewRows([]string{"ID", "Title", "Amount", "Currency", "Note", "Tags", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).
+			AddRow("2", "Grill pork", 100.00, "THB", "night market promotion discount 50 bath", pq.Array([]string{"food"}), testTime, testTime, testTime, 1).
+			AddRow("1", "strawberry smoothie", 79.00, "THB", "night market promotion discount 10 bath", pq.Array([]string{"food", "beverage"}), testTime, testTime, testTime, 1)
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("SELECT (.+) FROM expenses WHERE owner_id = (.+) AND deleted_at IS NULL AND tags @> (.+) ORDER BY amount DESC, id DESC LIMIT").
+			WithArgs(testUser.ID, pq.Array([]string{"food"}), 2).
+			WillReturnRows(getMockRows)
+		mock.ExpectQuery("SELECT COUNT(.+) FROM expenses").
+			WithArgs(testUser.ID, pq.Array([]string{"food"})).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/expense")
+		expected := "{\"data\":[{\"id\":2,\"title\":\"Grill pork\",\"amount\":100,\"currency\":\"THB\",\"note\":\"night market promotion discount 50 bath\",\"tags\":[\"food\"]" + testTimestamps + "}],\"next_cursor\":\"" + encodeCursor(listCursor{Sort: "-amount", ID: 2, Value: "100"}) + "\",\"total\":2}"
+
+		// Act
+		err := h.GetExpenses(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("Should continue from cursor", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		cursor := encodeCursor(listCursor{Sort: "-amount", ID: 2, Value: "100"})
+		req := httptest.NewRequest(http.MethodGet, "/expenses?limit=1&sort=-amount&cursor="+cursor, nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("SELECT (.+) FROM expenses WHERE owner_id = \\$1 AND deleted_at IS NULL AND \\(amount, id\\) < \\(\\$2, \\$3\\)").
+			WithArgs(testUser.ID, "100", 2, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"ID", "Title", "Amount", "Currency", "Note", "Tags", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).
+				AddRow("1", "strawberry smoothie", 79.00, "THB", "night market promotion discount 10 bath", pq.Array([]string{"food", "beverage"}), testTime, testTime, testTime, 1))
+		mock.ExpectQuery("SELECT COUNT(.+) FROM expenses").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/expense")
+		expected := "{\"data\":[{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"currency\":\"THB\",\"note\":\"night market promotion discount 10 bath\",\"tags\":[\"food\",\"beverage\"]" + testTimestamps + "}],\"next_cursor\":\"\",\"total\":2}"
+
+		// Act
+		err := h.GetExpenses(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"Should return unprocessable entity error if limit is not integer", "limit=abc", "Query param limit must be integer between 1 and 100"},
+		{"Should return unprocessable entity error if limit is too large", "limit=1000", "Query param limit must be integer between 1 and 100"},
+		{"Should return unprocessable entity error if sort is unknown", "sort=note", "Query param sort must be one of id, amount, title, spent_at"},
+		{"Should return unprocessable entity error if from is not a date", "from=yesterday", "Query param from must be RFC 3339 time or YYYY-MM-DD date"},
+		{"Should return unprocessable entity error if amount is not number", "min_amount=ten", "Query param min_amount must be number"},
+		{"Should return unprocessable entity error if cursor is invalid", "cursor=***", "Query param cursor is invalid"},
+		{"Should return unprocessable entity error if cursor value does not fit the sort", "sort=amount&cursor=" + encodeCursor(listCursor{Sort: "amount", ID: 2, Value: "abc"}), "Query param cursor is invalid"},
+		{"Should return unprocessable entity error if cursor belongs to another sort", "sort=title&cursor=" + encodeCursor(listCursor{Sort: "-amount", ID: 2, Value: "100"}), "Query param cursor belongs to another sort"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/expenses?"+tt.query, nil)
+			res := httptest.NewRecorder()
+
+			db, _, close := handlers.MockDatabase(t)
+			defer close()
+
+			h := handler{repo: NewPostgresRepository(db)}
+			c := e.NewContext(req, res)
+			users.SetCurrentUser(c, testUser)
+			c.SetPath("/expense")
+			expected := "{\"statusCode\":422,\"message\":\"" + tt.expected + "\"}"
+
+			// Act
+			err := h.GetExpenses(c)
+
+			// Assertions
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+				assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+			}
+		})
+	}
+
 }
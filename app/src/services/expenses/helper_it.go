@@ -13,9 +13,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/RTae/assessment/app/src/apperr"
 	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/services/budgets"
+	"github.com/RTae/assessment/app/src/services/users"
 	"github.com/RTae/assessment/app/src/settings"
+	"github.com/RTae/assessment/app/src/storage"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,21 +29,59 @@ type Response struct {
 	err error
 }
 
+var (
+	userRepo    users.UserRepository
+	budgetRepo  budgets.BudgetRepository
+	tokens      = users.NewTokens("integration test secret", 15*time.Minute, time.Hour)
+	seededUser  users.User
+	accessToken string
+)
+
 func SetupServer(t *testing.T) (*echo.Echo, settings.Config, func()) {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
+	e.HTTPErrorHandler = apperr.HTTPErrorHandler
+	e.Use(middleware.RequestID(), apperr.ProblemDetails)
 	var settings = settings.Setting()
-	database, close := handlers.InitDB(settings)
+	database, close := handlers.InitDB(settings, nil)
+	userRepo = users.NewPostgresRepository(database)
+	budgetRepo = budgets.NewPostgresRepository(database)
+	seededUser, accessToken = SeedUser(t, users.RoleUser)
 
 	go func(c *echo.Echo) {
-		expensesHandler := CreateHandler(database)
+		repo := NewPostgresRepository(database)
+		expensesHandler := CreateHandler(repo, Options{
+			Location:    time.UTC,
+			Budgets:     budgets.NewTracker(budgetRepo, repo, time.UTC),
+			Attachments: NewPostgresAttachmentRepository(database),
+			Files:       storage.NewMemory(),
+			Idempotency: NewPostgresIdempotencyRepository(database),
+			Audit:       true,
+		})
 
-		g := c.Group("expenses")
+		g := c.Group("expenses", tokens.Authenticate)
 		g.POST("", expensesHandler.CreateExpense)
+		g.POST("/import", expensesHandler.ImportExpenses)
+		g.POST("/import/statement", expensesHandler.ImportStatement)
+		g.GET("/summary", expensesHandler.GetExpensesSummary)
+		g.GET("/export", expensesHandler.ExportExpenses)
 		g.GET("/:id", expensesHandler.GetExpenseByID)
 		g.PUT("/:id", expensesHandler.UpdateExpenseByID)
+		g.PATCH("/:id", expensesHandler.PatchExpenseByID)
 		g.GET("", expensesHandler.GetExpenses)
+		g.DELETE("/:id", expensesHandler.DeleteExpenseByID)
+		g.POST("/:id/restore", expensesHandler.RestoreExpenseByID)
+		g.GET("/:id/history", expensesHandler.GetExpenseHistory)
+		g.GET("/:id/history/:revision", expensesHandler.GetExpenseRevision)
+		g.POST("/:id/history/:revision/revert", expensesHandler.RevertExpense)
+		g.POST("/:id/attachments", expensesHandler.CreateAttachment)
+		g.GET("/:id/attachments", expensesHandler.GetAttachments)
+		g.GET("/:id/attachments/:attachment_id", expensesHandler.GetAttachmentByID)
+		g.DELETE("/:id/attachments/:attachment_id", expensesHandler.DeleteAttachmentByID)
+		c.GET("/admin/expenses/unowned", expensesHandler.GetUnownedExpenses)
+		c.DELETE("/admin/expenses/:id", expensesHandler.PurgeExpenseByID)
+		c.PUT("/admin/expenses/:id/owner", expensesHandler.AssignExpenseOwner)
 
 		c.GET("/health", func(c echo.Context) error {
 			return c.JSON(http.StatusOK, "OK")
@@ -83,7 +126,33 @@ func (res *Response) Decode(v interface{}) error {
 	return json.Unmarshal(result, v)
 }
 
+// SeedUser creates a user with a unique name and returns it with its access
+// token.
+func SeedUser(t *testing.T, role string) (users.User, string) {
+	user := users.User{
+		Username:     fmt.Sprintf("it_%d", time.Now().UnixNano()),
+		PasswordHash: "not used",
+		Role:         role,
+	}
+	err := userRepo.Create(context.Background(), &user)
+	assert.NoError(t, err)
+
+	pair, err := tokens.Issue(user)
+	assert.NoError(t, err)
+	return user, pair.AccessToken
+}
+
 func Request(t *testing.T, method, url string, body io.Reader) *Response {
+	return RequestAs(t, accessToken, method, url, body)
+}
+
+func RequestAs(t *testing.T, token, method, url string, body io.Reader) *Response {
+	return RequestWith(t, token, method, url, body, nil)
+}
+
+// RequestWith sends a request with extra headers, which may override the
+// default content type.
+func RequestWith(t *testing.T, token, method, url string, body io.Reader, header map[string]string) *Response {
 
 	if body == nil {
 		body = bytes.NewBufferString("")
@@ -93,6 +162,10 @@ func Request(t *testing.T, method, url string, body io.Reader) *Response {
 	assert.NoError(t, err)
 
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
 
 	client := http.Client{}
 	resp, err := client.Do(req)
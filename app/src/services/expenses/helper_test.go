@@ -0,0 +1,18 @@
+//go:build unit
+
+package expenses
+
+import (
+	"time"
+
+	"github.com/RTae/assessment/app/src/services/users"
+)
+
+var (
+	testUser = users.User{ID: 1, Username: "somchai", Role: users.RoleUser}
+	testTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+)
+
+// testTimestamps is how testTime is rendered for spent_at, created_at and
+// updated_at in a response.
+const testTimestamps = `,"spent_at":"2024-01-02T03:04:05Z","created_at":"2024-01-02T03:04:05Z","updated_at":"2024-01-02T03:04:05Z"`
@@ -0,0 +1,302 @@
+package expenses
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+)
+
+// The actions a revision records.
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionRevert  = "revert"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+)
+
+// Audit tells the repository who makes a change, so it stores a revision
+// together with the change.
+type Audit struct {
+	ActorID   int
+	RequestID string
+	// Reverts is the revision a revert goes back to.
+	Reverts int
+}
+
+type auditKey struct{}
+
+// WithAudit makes the writes done with ctx record revisions.
+func WithAudit(ctx context.Context, a Audit) context.Context {
+	return context.WithValue(ctx, auditKey{}, a)
+}
+
+func auditFrom(ctx context.Context) (Audit, bool) {
+	a, ok := ctx.Value(auditKey{}).(Audit)
+	return a, ok
+}
+
+// Snapshot is the state of the fields of an expense clients can change,
+// and of its owner, which only an admin changes. Revisions recorded before
+// owners were snapshotted have no OwnerID.
+type Snapshot struct {
+	Title    string       `json:"title"`
+	Amount   money.Amount `json:"amount"`
+	Currency string       `json:"currency"`
+	Note     string       `json:"note"`
+	Tags     []string     `json:"tags"`
+	SpentAt  time.Time    `json:"spent_at"`
+	OwnerID  int          `json:"owner_id,omitempty"`
+}
+
+func snapshot(exp *Expenses) *Snapshot {
+	if exp == nil {
+		return nil
+	}
+	return &Snapshot{
+		Title:    exp.Title,
+		Amount:   exp.Amount,
+		Currency: exp.Currency,
+		Note:     exp.Note,
+		Tags:     append([]string(nil), exp.Tags...),
+		SpentAt:  exp.SpentAt.UTC(),
+		OwnerID:  exp.OwnerID,
+	}
+}
+
+// apply sets the fields of exp to the snapshot. A revert never changes the
+// owner, so OwnerID is left alone.
+func (s Snapshot) apply(exp *Expenses) {
+	exp.Title = s.Title
+	exp.Amount = s.Amount
+	exp.Currency = s.Currency
+	exp.Note = s.Note
+	exp.Tags = append([]string(nil), s.Tags...)
+	exp.SpentAt = s.SpentAt
+}
+
+// Revision is an immutable record of one change to an expense. Before is
+// nil for a create or restore and After is nil for a delete or purge.
+type Revision struct {
+	ID        int       `json:"id"`
+	ExpenseID int       `json:"expense_id"`
+	OwnerID   int       `json:"-"`
+	Action    string    `json:"action"`
+	ActorID   int       `json:"actor_id"`
+	RequestID string    `json:"request_id,omitempty"`
+	Reverts   int       `json:"reverts,omitempty"`
+	Before    *Snapshot `json:"before"`
+	After     *Snapshot `json:"after"`
+	Changes   []Change  `json:"changes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Change is a field whose value differs between the two sides of a
+// revision.
+type Change struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+func (s *Snapshot) fields() map[string]interface{} {
+	if s == nil {
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"title":    s.Title,
+		"amount":   s.Amount,
+		"currency": s.Currency,
+		"note":     s.Note,
+		"tags":     s.Tags,
+		"spent_at": s.SpentAt,
+		"owner_id": s.OwnerID,
+	}
+}
+
+var snapshotFields = []string{"title", "amount", "currency", "note", "tags", "spent_at", "owner_id"}
+
+// diff lists the fields that differ between before and after, in the order
+// they appear in an expense.
+func diff(before, after *Snapshot) []Change {
+	from, to := before.fields(), after.fields()
+	changes := []Change{}
+	for _, field := range snapshotFields {
+		if !jsonEqual(from[field], to[field]) {
+			changes = append(changes, Change{Field: field, From: from[field], To: to[field]})
+		}
+	}
+	return changes
+}
+
+// encodeSnapshot and decodeSnapshot store a snapshot as JSON, NULL when
+// there is none.
+func encodeSnapshot(s *Snapshot) ([]byte, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+func decodeSnapshot(raw []byte) (*Snapshot, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	var s Snapshot
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// newRevision describes a change from before to after made under a. An
+// update made by a revert is recorded as a revert.
+func newRevision(a Audit, action string, before, after *Expenses) Revision {
+	if action == ActionUpdate && a.Reverts != 0 {
+		action = ActionRevert
+	}
+	r := Revision{
+		Action:    action,
+		ActorID:   a.ActorID,
+		RequestID: a.RequestID,
+		Reverts:   a.Reverts,
+		Before:    snapshot(before),
+		After:     snapshot(after),
+	}
+	for _, exp := range []*Expenses{after, before} {
+		if exp != nil {
+			r.ExpenseID, r.OwnerID = exp.ID, exp.OwnerID
+			break
+		}
+	}
+	return r
+}
+
+// context is the context of the writes of a request, audited as the
+// current user when the handler records revisions.
+func (h *handler) context(c echo.Context) context.Context {
+	ctx := c.Request().Context()
+	if !h.audit {
+		return ctx
+	}
+	user, _ := users.CurrentUser(c)
+	return WithAudit(ctx, Audit{ActorID: user.ID, RequestID: apperr.RequestID(c)})
+}
+
+func (h *handler) localizeRevision(r *Revision) {
+	r.CreatedAt = r.CreatedAt.In(h.zone())
+	for _, s := range []*Snapshot{r.Before, r.After} {
+		if s != nil {
+			s.SpentAt = s.SpentAt.In(h.zone())
+		}
+	}
+	r.Changes = diff(r.Before, r.After)
+}
+
+// GetExpenseHistory lists the revisions of an expense, oldest first. The
+// history outlives a deleted expense.
+func (h *handler) GetExpenseHistory(c echo.Context) error {
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	revisions, err := h.repo.History(c.Request().Context(), ownerID, c.Param("id"))
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	for i := range revisions {
+		h.localizeRevision(&revisions[i])
+	}
+	return c.JSON(http.StatusOK, revisions)
+}
+
+func (h *handler) revision(c echo.Context, ownerID int) (Revision, error) {
+	revisionID, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		return Revision{}, apperr.ErrInvalidID
+	}
+	revisions, err := h.repo.History(c.Request().Context(), ownerID, c.Param("id"))
+	if err != nil {
+		return Revision{}, err
+	}
+	for _, r := range revisions {
+		if r.ID == revisionID {
+			return r, nil
+		}
+	}
+	return Revision{}, sql.ErrNoRows
+}
+
+func (h *handler) GetExpenseRevision(c echo.Context) error {
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	r, err := h.revision(c, ownerID)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	h.localizeRevision(&r)
+	return c.JSON(http.StatusOK, r)
+}
+
+// RevertExpense sets the expense back to its state after a revision,
+// recording the revert as a revision of its own. A deleted expense has to be
+// restored first.
+func (h *handler) RevertExpense(c echo.Context) error {
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	ifMatch, ok, err := h.ifMatch(c)
+	if !ok {
+		return err
+	}
+
+	r, err := h.revision(c, ownerID)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	if r.After == nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: fmt.Sprintf("Revision %d left no expense to revert to", r.ID)},
+		)
+	}
+
+	ctx := h.context(c)
+	if a, ok := auditFrom(ctx); ok {
+		a.Reverts = r.ID
+		ctx = WithAudit(ctx, a)
+	}
+	exp, err := h.repo.Patch(ctx, ownerID, c.Param("id"), func(exp *Expenses) error {
+		if ifMatch != "" && !etagMatches(ifMatch, etag(*exp), false) {
+			return errVersionMismatch
+		}
+		r.After.apply(exp)
+		return nil
+	})
+	if errors.Is(err, errVersionMismatch) {
+		return preconditionFailedResponse(c)
+	}
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	setETag(c, exp)
+	return c.JSON(http.StatusOK, h.written(c, exp))
+}
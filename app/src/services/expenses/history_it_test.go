@@ -0,0 +1,74 @@
+//go:build it
+
+package expenses
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpenseHistory(t *testing.T) {
+	// setup echo server
+	e, settings, close := SetupServer(t)
+	PingServer()
+
+	created := SeedExpense(t, settings)
+	uri := Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%d", created.ID))
+
+	t.Run("Should list the revisions of an expense", func(t *testing.T) {
+		// Arrange
+		res := Request(t, http.MethodPatch, uri, strings.NewReader(`{"note":"history"}`))
+		res.Body.Close()
+
+		// Act
+		var revisions []Revision
+		err := Request(t, http.MethodGet, uri+"/history", nil).Decode(&revisions)
+
+		// Assert
+		if assert.NoError(t, err) && assert.Len(t, revisions, 2) {
+			assert.Equal(t, ActionCreate, revisions[0].Action)
+			assert.Equal(t, ActionUpdate, revisions[1].Action)
+			assert.Equal(t, []Change{{Field: "note", From: "clear debt", To: "history"}}, revisions[1].Changes)
+		}
+	})
+
+	t.Run("Should revert to the first revision", func(t *testing.T) {
+		// Arrange
+		var revisions []Revision
+		err := Request(t, http.MethodGet, uri+"/history", nil).Decode(&revisions)
+		assert.NoError(t, err)
+
+		// Act
+		var reverted Expenses
+		err = Request(t, http.MethodPost, fmt.Sprintf("%s/history/%d/revert", uri, revisions[0].ID), nil).Decode(&reverted)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, "clear debt", reverted.Note)
+		}
+	})
+
+	t.Run("Should keep the history of a deleted expense", func(t *testing.T) {
+		// Arrange
+		res := Request(t, http.MethodDelete, uri, nil)
+		res.Body.Close()
+
+		// Act
+		var revisions []Revision
+		err := Request(t, http.MethodGet, uri+"/history", nil).Decode(&revisions)
+
+		// Assert
+		if assert.NoError(t, err) && assert.Len(t, revisions, 4) {
+			assert.Equal(t, ActionRevert, revisions[2].Action)
+			assert.Equal(t, ActionDelete, revisions[3].Action)
+			assert.Nil(t, revisions[3].After)
+		}
+	})
+
+	// teardown echo server
+	TeardownServer(t, e, close)
+}
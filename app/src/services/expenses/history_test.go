@@ -0,0 +1,260 @@
+//go:build unit
+
+package expenses
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func historyRequest(t *testing.T, user users.User, action echo.HandlerFunc, body string, params ...string) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.Validator = NewValidator()
+	req := httptest.NewRequest(http.MethodPost, "/expenses/1", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	res := httptest.NewRecorder()
+	c := e.NewContext(req, res)
+	users.SetCurrentUser(c, user)
+	var names, values []string
+	for i := 0; i < len(params); i += 2 {
+		names = append(names, params[i])
+		values = append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+
+	assert.NoError(t, action(c))
+	return res
+}
+
+func newHistoryHandler(t *testing.T) *handler {
+	h := &handler{repo: NewMemoryRepository(), audit: true}
+	res := historyRequest(t, testUser, h.CreateExpense, `{"title":"rice","amount":50,"tags":["food"]}`)
+	assert.Equal(t, http.StatusCreated, res.Code)
+	return h
+}
+
+func history(t *testing.T, h *handler, user users.User) ([]Revision, int) {
+	res := historyRequest(t, user, h.GetExpenseHistory, "", "id", "1")
+	var revisions []Revision
+	if res.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &revisions))
+	}
+	return revisions, res.Code
+}
+
+func TestExpenseHistory(t *testing.T) {
+	t.Run("Should record a revision for every change", func(t *testing.T) {
+		// Arrange
+		h := newHistoryHandler(t)
+
+		// Act
+		historyRequest(t, testUser, h.UpdateExpenseByID, `{"title":"rice","amount":60,"tags":["food"]}`, "id", "1")
+		historyRequest(t, testUser, h.DeleteExpenseByID, "", "id", "1")
+		historyRequest(t, testUser, h.RestoreExpenseByID, "", "id", "1")
+		revisions, code := history(t, h, testUser)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, code)
+		var actions []string
+		for _, r := range revisions {
+			actions = append(actions, r.Action)
+			assert.Equal(t, testUser.ID, r.ActorID)
+			assert.Equal(t, "req-1", r.RequestID)
+		}
+		assert.Equal(t, []string{ActionCreate, ActionUpdate, ActionDelete, ActionRestore}, actions)
+		assert.Nil(t, revisions[0].Before)
+		assert.Nil(t, revisions[2].After)
+		assert.Equal(t, []Change{{Field: "amount", From: 50.0, To: 60.0}}, revisions[1].Changes)
+	})
+
+	t.Run("Should return one revision", func(t *testing.T) {
+		// Arrange
+		h := newHistoryHandler(t)
+
+		// Act
+		found := historyRequest(t, testUser, h.GetExpenseRevision, "", "id", "1", "revision", "1")
+		missing := historyRequest(t, testUser, h.GetExpenseRevision, "", "id", "1", "revision", "9")
+		invalid := historyRequest(t, testUser, h.GetExpenseRevision, "", "id", "1", "revision", "x")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, found.Code)
+		assert.Contains(t, found.Body.String(), `"action":"create"`)
+		assert.Equal(t, http.StatusNotFound, missing.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, invalid.Code)
+	})
+
+	t.Run("Should revert to the state after a revision", func(t *testing.T) {
+		// Arrange
+		h := newHistoryHandler(t)
+		historyRequest(t, testUser, h.PatchExpenseByID, `{"title":"tea","note":"x"}`, "id", "1")
+
+		// Act
+		res := historyRequest(t, testUser, h.RevertExpense, "", "id", "1", "revision", "1")
+		revisions, _ := history(t, h, testUser)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Contains(t, res.Body.String(), `"title":"rice","amount":50,"currency":"THB","note":""`)
+		assert.Equal(t, `"3"`, res.Header().Get(HeaderETag))
+		last := revisions[len(revisions)-1]
+		assert.Equal(t, ActionRevert, last.Action)
+		assert.Equal(t, 1, last.Reverts)
+	})
+
+	t.Run("Should not revert a changed expense or to a deleted one", func(t *testing.T) {
+		// Arrange
+		h := newHistoryHandler(t)
+		historyRequest(t, testUser, h.PatchExpenseByID, `{"note":"x"}`, "id", "1")
+		historyRequest(t, testUser, h.DeleteExpenseByID, "", "id", "1")
+		historyRequest(t, testUser, h.RestoreExpenseByID, "", "id", "1")
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/expenses/1/history/1/revert", nil)
+		req.Header.Set(HeaderIfMatch, `"1"`)
+		stale := httptest.NewRecorder()
+		c := e.NewContext(req, stale)
+		users.SetCurrentUser(c, testUser)
+		c.SetParamNames("id", "revision")
+		c.SetParamValues("1", "1")
+
+		// Act
+		assert.NoError(t, h.RevertExpense(c))
+		deleted := historyRequest(t, testUser, h.RevertExpense, "", "id", "1", "revision", "3")
+
+		// Assert
+		assert.Equal(t, http.StatusPreconditionFailed, stale.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, deleted.Code)
+		assert.Equal(t, `{"statusCode":422,"message":"Revision 3 left no expense to revert to"}`, strings.TrimSpace(deleted.Body.String()))
+	})
+
+	t.Run("Should hide the history of another user's expense", func(t *testing.T) {
+		// Arrange
+		h := newHistoryHandler(t)
+		other := users.User{ID: 3, Username: "somsri", Role: users.RoleUser}
+
+		// Act
+		_, code := history(t, h, other)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Should record nothing when auditing is off", func(t *testing.T) {
+		// Arrange
+		h := newHistoryHandler(t)
+		h.audit = false
+
+		// Act
+		historyRequest(t, testUser, h.PatchExpenseByID, `{"note":"x"}`, "id", "1")
+		revisions, _ := history(t, h, testUser)
+
+		// Assert
+		assert.Len(t, revisions, 1)
+	})
+}
+
+func TestPostgresAuditedCreate(t *testing.T) {
+	t.Run("Should store the revision in the transaction of the change", func(t *testing.T) {
+		// Arrange
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO expenses").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "spent_at", "created_at", "updated_at", "version"}).
+				AddRow(1, testTime, testTime, testTime, 1))
+		mock.ExpectQuery("INSERT INTO expense_revisions").
+			WithArgs(1, testUser.ID, ActionCreate, testUser.ID, "req-1", 0, nil, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+		ctx := WithAudit(context.Background(), Audit{ActorID: testUser.ID, RequestID: "req-1"})
+
+		// Act
+		exp := Expenses{Title: "rice", Amount: 500000, Currency: "THB", OwnerID: testUser.ID}
+		err := NewPostgresRepository(db).Create(ctx, &exp)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresAuditedPurge(t *testing.T) {
+	t.Run("Should purge an expense stored before owners were kept", func(t *testing.T) {
+		// Arrange
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = (.+) FOR UPDATE").
+			WithArgs("1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "spent_at", "created_at", "updated_at", "version", "owner_id"}).
+				AddRow(1, "rice", 50, "THB", "", pq.Array([]string{}), testTime, testTime, testTime, 1, nil))
+		mock.ExpectQuery("DELETE FROM expenses").
+			WithArgs("1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("INSERT INTO expense_revisions").
+			WithArgs(1, 0, ActionPurge, testUser.ID, "req-1", 0, sqlmock.AnyArg(), nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+		ctx := WithAudit(context.Background(), Audit{ActorID: testUser.ID, RequestID: "req-1"})
+
+		// Act
+		err := NewPostgresRepository(db).Purge(ctx, "1")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// snapshotOwner matches a stored snapshot by its owner.
+type snapshotOwner int
+
+func (o snapshotOwner) Match(v driver.Value) bool {
+	raw, ok := v.(string)
+	var s Snapshot
+	return ok && json.Unmarshal([]byte(raw), &s) == nil && s.OwnerID == int(o)
+}
+
+func TestPostgresAuditedAssign(t *testing.T) {
+	t.Run("Should record the new owner of an expense", func(t *testing.T) {
+		// Arrange
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = (.+) AND owner_id IS NULL (.+) FOR UPDATE").
+			WithArgs("1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "spent_at", "created_at", "updated_at", "version", "owner_id"}).
+				AddRow(1, "rice", 50, "THB", "", pq.Array([]string{}), testTime, testTime, testTime, 1, nil))
+		mock.ExpectQuery("UPDATE expenses SET owner_id").
+			WithArgs(testUser.ID, "1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "spent_at", "created_at", "updated_at", "version"}).
+				AddRow(1, "rice", 50, "THB", "", pq.Array([]string{}), testTime, testTime, testTime, 2))
+		mock.ExpectQuery("INSERT INTO expense_revisions").
+			WithArgs(1, testUser.ID, ActionUpdate, 9, "req-1", 0, snapshotOwner(0), snapshotOwner(testUser.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+		ctx := WithAudit(context.Background(), Audit{ActorID: 9, RequestID: "req-1"})
+
+		// Act
+		exp, err := NewPostgresRepository(db).Assign(ctx, "1", testUser.ID)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, testUser.ID, exp.OwnerID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,146 @@
+package expenses
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	// DefaultIdempotencyTTL is how long a key is remembered when no TTL is
+	// configured.
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	maxIdempotencyKey = 255
+	// idempotencyTimeout bounds storing the outcome of a request, which is
+	// done even when the client has gone away so its retry can be answered.
+	idempotencyTimeout = 5 * time.Second
+)
+
+// replayedHeaders are the response headers a replay sends again with the
+// body, so it answers like the first response did.
+var replayedHeaders = []string{HeaderETag, echo.HeaderLocation}
+
+// replayable picks the headers of header a replay sends again.
+func replayable(header http.Header) http.Header {
+	kept := http.Header{}
+	for _, name := range replayedHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			kept[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	return kept
+}
+
+// bodyRecorder copies what is written to the response.
+type bodyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (h *handler) idempotencyLifetime() time.Duration {
+	if h.idempotencyTTL <= 0 {
+		return DefaultIdempotencyTTL
+	}
+	return h.idempotencyTTL
+}
+
+// requestHash identifies a request by its method, path and body so a key
+// reused for another request can be told apart from a retry.
+func requestHash(req *http.Request, body []byte) string {
+	sum := sha256.New()
+	io.WriteString(sum, req.Method+" "+req.URL.Path+"\n")
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// idempotent runs next at most once per Idempotency-Key of the current user
+// and answers a retry with the response to the first request. Only
+// successful responses are remembered, so a failed request can be retried
+// with the same key. Requests without the header always run next.
+func (h *handler) idempotent(c echo.Context, next echo.HandlerFunc) error {
+	key := c.Request().Header.Get(HeaderIdempotencyKey)
+	if key == "" || h.idempotency == nil {
+		return next(c)
+	}
+	if len(key) > maxIdempotencyKey {
+		return c.JSON(
+			http.StatusBadRequest,
+			apperr.ErrorResponse{Code: http.StatusBadRequest, Message: "Header Idempotency-Key must be at most 255 characters"},
+		)
+	}
+
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		c.Logger().Warnf("request %s: reading body: %v", apperr.RequestID(c), err)
+		return c.JSON(
+			http.StatusBadRequest,
+			apperr.ErrorResponse{Code: http.StatusBadRequest, Message: "Invalid request body"},
+		)
+	}
+	c.Request().Body = io.NopCloser(bytes.NewReader(body))
+	hash := requestHash(c.Request(), body)
+
+	prev, reserved, err := h.idempotency.Reserve(c.Request().Context(), ownerID, key, hash, h.idempotencyLifetime())
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+	if !reserved {
+		switch {
+		case prev.Hash != hash:
+			return c.JSON(
+				http.StatusUnprocessableEntity,
+				apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Idempotency-Key was already used for a different request"},
+			)
+		case prev.Status == 0:
+			return c.JSON(
+				http.StatusConflict,
+				apperr.ErrorResponse{Code: http.StatusConflict, Message: "A request with this Idempotency-Key is still in progress"},
+			)
+		}
+		for name, values := range prev.Header {
+			c.Response().Header()[name] = values
+		}
+		c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+		return c.JSONBlob(prev.Status, prev.Body)
+	}
+
+	res := c.Response()
+	recorder := &bodyRecorder{ResponseWriter: res.Writer}
+	res.Writer = recorder
+	err = next(c)
+	res.Writer = recorder.ResponseWriter
+
+	ctx, cancel := context.WithTimeout(context.Background(), idempotencyTimeout)
+	defer cancel()
+	if err == nil && res.Committed && res.Status >= 200 && res.Status < 300 {
+		completeErr := h.idempotency.Complete(ctx, ownerID, key, res.Status, replayable(res.Header()), recorder.body.Bytes())
+		if completeErr == nil {
+			return nil
+		}
+		c.Logger().Error(completeErr)
+	}
+	if err := h.idempotency.Release(ctx, ownerID, key); err != nil {
+		c.Logger().Error(err)
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+//go:build it
+
+package expenses
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotentCreateExpense(t *testing.T) {
+	// setup echo server
+	e, settings, close := SetupServer(t)
+	PingServer()
+
+	uri := Uri(fmt.Sprint(settings.Port), "expenses")
+	key := fmt.Sprintf("it-%d", time.Now().UnixNano())
+	body := `{"title":"idempotent rice","amount":50,"tags":["food"]}`
+
+	t.Run("Should create the expense once for a retried request", func(t *testing.T) {
+		// Act
+		var first, retry Expenses
+		firstRes := RequestWith(t, accessToken, http.MethodPost, uri, strings.NewReader(body), map[string]string{HeaderIdempotencyKey: key})
+		firstErr := firstRes.Decode(&first)
+		retryRes := RequestWith(t, accessToken, http.MethodPost, uri, strings.NewReader(body), map[string]string{HeaderIdempotencyKey: key})
+		retryErr := retryRes.Decode(&retry)
+
+		// Assert
+		if assert.NoError(t, firstErr) && assert.NoError(t, retryErr) {
+			assert.Equal(t, http.StatusCreated, firstRes.StatusCode)
+			assert.Equal(t, http.StatusCreated, retryRes.StatusCode)
+			assert.Equal(t, "true", retryRes.Header.Get(HeaderIdempotentReplayed))
+			assert.Equal(t, firstRes.Header.Get(HeaderETag), retryRes.Header.Get(HeaderETag))
+			assert.Equal(t, first.ID, retry.ID)
+		}
+	})
+
+	t.Run("Should reject the key for a different body", func(t *testing.T) {
+		// Act
+		res := RequestWith(t, accessToken, http.MethodPost, uri, strings.NewReader(`{"title":"idempotent tea","amount":30,"tags":["food"]}`),
+			map[string]string{HeaderIdempotencyKey: key})
+
+		// Assert
+		if assert.NoError(t, res.err) {
+			res.Body.Close()
+			assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+		}
+	})
+
+	// teardown echo server
+	TeardownServer(t, e, close)
+}
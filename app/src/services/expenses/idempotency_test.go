@@ -0,0 +1,154 @@
+//go:build unit
+
+package expenses
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func postExpense(t *testing.T, h handler, key, body string) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.Validator = NewValidator()
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	res := httptest.NewRecorder()
+	c := e.NewContext(req, res)
+	users.SetCurrentUser(c, testUser)
+
+	assert.NoError(t, h.CreateExpense(c))
+	return res
+}
+
+func TestIdempotentCreateExpense(t *testing.T) {
+	rice := `{"title":"rice","amount":50,"tags":["food"]}`
+
+	t.Run("Should replay the first response for a retry", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		h := handler{repo: repo, idempotency: NewMemoryIdempotencyRepository()}
+		first := postExpense(t, h, "retry-1", rice)
+
+		// Act
+		res := postExpense(t, h, "retry-1", rice)
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, res.Code)
+		assert.Equal(t, first.Body.String(), res.Body.String())
+		assert.Equal(t, `"1"`, res.Header().Get(HeaderETag))
+		assert.Equal(t, "true", res.Header().Get(HeaderIdempotentReplayed))
+		assert.Empty(t, first.Header().Get(HeaderIdempotentReplayed))
+		count, _ := repo.Count(context.Background(), listFilter{OwnerID: testUser.ID})
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("Should reject a key reused for a different request", func(t *testing.T) {
+		// Arrange
+		h := handler{repo: NewMemoryRepository(), idempotency: NewMemoryIdempotencyRepository()}
+		postExpense(t, h, "retry-1", rice)
+
+		// Act
+		res := postExpense(t, h, "retry-1", `{"title":"tea","amount":30,"tags":["food"]}`)
+
+		// Assert
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+		assert.Equal(t, `{"statusCode":422,"message":"Idempotency-Key was already used for a different request"}`, strings.TrimSpace(res.Body.String()))
+	})
+
+	t.Run("Should let a failed request be retried with the same key", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		h := handler{repo: repo, idempotency: NewMemoryIdempotencyRepository()}
+		failed := postExpense(t, h, "retry-1", `{"title":"","amount":50,"tags":["food"]}`)
+
+		// Act
+		res := postExpense(t, h, "retry-1", `{"title":"","amount":50,"tags":["food"]}`)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, failed.Code)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		assert.Empty(t, res.Header().Get(HeaderIdempotentReplayed))
+	})
+
+	t.Run("Should answer conflict while the first request is in progress", func(t *testing.T) {
+		// Arrange
+		idempotency := NewMemoryIdempotencyRepository()
+		h := handler{repo: NewMemoryRepository(), idempotency: idempotency}
+		req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(rice))
+		idempotency.Reserve(context.Background(), testUser.ID, "retry-1", requestHash(req, []byte(rice)), time.Hour)
+
+		// Act
+		res := postExpense(t, h, "retry-1", rice)
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+
+	t.Run("Should create again once the key has expired", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		idempotency := NewMemoryIdempotencyRepository()
+		now := testTime
+		idempotency.(*memoryIdempotencyRepository).now = func() time.Time { return now }
+		h := handler{repo: repo, idempotency: idempotency, idempotencyTTL: time.Hour}
+		postExpense(t, h, "retry-1", rice)
+		now = now.Add(time.Hour)
+
+		// Act
+		res := postExpense(t, h, "retry-1", rice)
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, res.Code)
+		assert.Empty(t, res.Header().Get(HeaderIdempotentReplayed))
+		count, _ := repo.Count(context.Background(), listFilter{OwnerID: testUser.ID})
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("Should reject a key that is too long", func(t *testing.T) {
+		// Arrange
+		h := handler{repo: NewMemoryRepository(), idempotency: NewMemoryIdempotencyRepository()}
+
+		// Act
+		res := postExpense(t, h, strings.Repeat("k", 256), rice)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
+
+func TestPostgresIdempotencyRepository(t *testing.T) {
+	t.Run("Should return the earlier request when the key is taken", func(t *testing.T) {
+		// Arrange
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+		mock.ExpectExec("DELETE FROM idempotency_keys").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("INSERT INTO idempotency_keys").WithArgs(1, "retry-1", "abc", int64(3600000)).
+			WillReturnRows(sqlmock.NewRows([]string{"owner_id"}))
+		mock.ExpectQuery("SELECT request_hash").WithArgs(1, "retry-1").
+			WillReturnRows(sqlmock.NewRows([]string{"request_hash", "status_code", "response_headers", "response"}).
+				AddRow("abc", 201, []byte(`{"Etag":["\"1\""]}`), []byte(`{"id":1}`)))
+
+		// Act
+		req, reserved, err := NewPostgresIdempotencyRepository(db).Reserve(context.Background(), 1, "retry-1", "abc", time.Hour)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.False(t, reserved)
+			assert.Equal(t, IdempotentRequest{Hash: "abc", Status: 201, Header: http.Header{"Etag": {`"1"`}}, Body: []byte(`{"id":1}`)}, req)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
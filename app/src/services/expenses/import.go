@@ -0,0 +1,311 @@
+package expenses
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/labstack/echo/v4"
+)
+
+const maxImportRows = 10000
+
+const (
+	// ImportPartial stores the valid rows and reports the rejected ones.
+	ImportPartial = "partial"
+	// ImportAll stores nothing unless every row is valid.
+	ImportAll = "all"
+)
+
+const (
+	RowImported = "imported"
+	// RowValid marks a valid row left out because ImportAll rejected the
+	// file or the import was a dry run.
+	RowValid    = "valid"
+	RowRejected = "rejected"
+)
+
+var importFields = map[string]bool{
+	"title": true, "amount": true, "currency": true, "note": true, "tags": true, "spent_at": true,
+}
+
+type ImportRow struct {
+	Line   int                 `json:"line"`
+	Status string              `json:"status"`
+	ID     int                 `json:"id,omitempty"`
+	Errors []apperr.FieldError `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	Mode     string      `json:"mode"`
+	Imported int         `json:"imported"`
+	Rejected int         `json:"rejected"`
+	Rows     []ImportRow `json:"rows"`
+}
+
+type importOptions struct {
+	// Columns names the field of each CSV column by position, "" skipping
+	// it. Nil maps the columns by the names in the header row.
+	Columns      []string
+	Header       string
+	Delimiter    rune
+	TagSeparator string
+	Mode         string
+}
+
+func parseImportOptions(c echo.Context) (importOptions, error) {
+	o := importOptions{
+		Header:       "auto",
+		Delimiter:    ',',
+		TagSeparator: ";",
+		Mode:         ImportPartial,
+	}
+
+	if raw := c.QueryParam("columns"); raw != "" {
+		for _, field := range strings.Split(raw, ",") {
+			field = strings.ToLower(strings.TrimSpace(field))
+			if field == "-" {
+				field = ""
+			} else if !importFields[field] {
+				return o, errors.New("Query param columns must list title, amount, currency, note, tags, spent_at or - per column")
+			}
+			o.Columns = append(o.Columns, field)
+		}
+	}
+
+	switch raw := c.QueryParam("header"); raw {
+	case "":
+	case "auto", "true", "false":
+		o.Header = raw
+	default:
+		return o, errors.New("Query param header must be true, false or auto")
+	}
+
+	if raw := c.QueryParam("delimiter"); raw != "" {
+		r, size := utf8.DecodeRuneInString(raw)
+		if size != len(raw) || r == '"' || r == '\n' || r == '\r' {
+			return o, errors.New("Query param delimiter must be a single character")
+		}
+		o.Delimiter = r
+	}
+
+	if raw := c.QueryParam("tag_separator"); raw != "" {
+		o.TagSeparator = raw
+	}
+
+	if o.Columns == nil && o.Header == "false" {
+		return o, errors.New("Query param columns is required when header is false")
+	}
+
+	switch raw := c.QueryParam("mode"); raw {
+	case "":
+	case ImportPartial, ImportAll:
+		o.Mode = raw
+	default:
+		return o, errors.New("Query param mode must be partial or all")
+	}
+	return o, nil
+}
+
+// isHeader tells whether the first record names the columns. Without a
+// column mapping the header is required. Otherwise auto detection takes
+// the row as a header when its amount is not a number.
+func (o importOptions) isHeader(record []string) bool {
+	if o.Columns == nil || o.Header == "true" {
+		return true
+	}
+	if o.Header == "false" {
+		return false
+	}
+	for i, field := range o.Columns {
+		if field == "amount" && i < len(record) {
+			_, err := money.Parse(record[i])
+			return err != nil
+		}
+	}
+	for _, cell := range record {
+		if importFields[strings.ToLower(strings.TrimSpace(cell))] {
+			return true
+		}
+	}
+	return false
+}
+
+func headerColumns(record []string) []string {
+	columns := make([]string, len(record))
+	for i, cell := range record {
+		if field := strings.ToLower(strings.TrimSpace(cell)); importFields[field] {
+			columns[i] = field
+		}
+	}
+	return columns
+}
+
+// importRecord builds an expense from record. Cells that cannot be read are
+// reported next to the validation errors of the expense.
+func (h *handler) importRecord(c echo.Context, o importOptions, columns, record []string) (Expenses, []apperr.FieldError) {
+	var exp Expenses
+	var details []apperr.FieldError
+	for i, field := range columns {
+		if field == "" || i >= len(record) {
+			continue
+		}
+		value := csvUnquote(strings.TrimSpace(record[i]))
+		switch field {
+		case "title":
+			exp.Title = value
+		case "amount":
+			amount, err := money.Parse(value)
+			if err != nil {
+				details = append(details, apperr.FieldError{Field: "amount", Message: "must be a number"})
+			}
+			exp.Amount = amount
+		case "currency":
+			exp.Currency = strings.ToUpper(value)
+		case "note":
+			exp.Note = value
+		case "tags":
+			for _, tag := range strings.Split(value, o.TagSeparator) {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					exp.Tags = append(exp.Tags, tag)
+				}
+			}
+		case "spent_at":
+			if value == "" {
+				continue
+			}
+			spentAt, err := parseDate(value, h.zone(), false)
+			if err != nil {
+				details = append(details, apperr.FieldError{Field: "spent_at", Message: "must be RFC 3339 time or YYYY-MM-DD date"})
+			}
+			exp.SpentAt = spentAt
+		}
+	}
+
+	exp.defaultCurrency()
+	unreadable := map[string]bool{}
+	for _, d := range details {
+		unreadable[d.Field] = true
+	}
+	var verr *apperr.ValidationError
+	if errors.As(validate(c, &exp), &verr) {
+		for _, d := range verr.Details {
+			if !unreadable[d.Field] {
+				details = append(details, d)
+			}
+		}
+	}
+	return exp, details
+}
+
+func importBody(c echo.Context) (io.ReadCloser, error) {
+	if !strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		return c.Request().Body, nil
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		return nil, errors.New("Form field file is required")
+	}
+	return file.Open()
+}
+
+// ImportExpenses reads expenses from a CSV body or the file field of a
+// multipart form, validates every row and stores the valid ones in a single
+// transaction. In ImportAll mode one rejected row rejects the whole file
+// with 422.
+func (h *handler) ImportExpenses(c echo.Context) error {
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	o, err := parseImportOptions(c)
+	if err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: err.Error()},
+		)
+	}
+
+	body, err := importBody(c)
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			apperr.ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()},
+		)
+	}
+	defer body.Close()
+
+	reader := csv.NewReader(body)
+	reader.Comma = o.Delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	report := ImportReport{Mode: o.Mode, Rows: []ImportRow{}}
+	var valid []Expenses
+	var validRows []int
+	columns := o.Columns
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return c.JSON(
+				http.StatusBadRequest,
+				apperr.ErrorResponse{Code: http.StatusBadRequest, Message: fmt.Sprintf("Invalid CSV: %v", err)},
+			)
+		}
+		if first && o.isHeader(record) {
+			if columns == nil {
+				columns = headerColumns(record)
+			}
+			continue
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		if len(report.Rows) == maxImportRows {
+			return c.JSON(
+				http.StatusRequestEntityTooLarge,
+				apperr.ErrorResponse{Code: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("CSV must have at most %d rows", maxImportRows)},
+			)
+		}
+
+		line, _ := reader.FieldPos(0)
+		exp, details := h.importRecord(c, o, columns, record)
+		if len(details) > 0 {
+			report.Rows = append(report.Rows, ImportRow{Line: line, Status: RowRejected, Errors: details})
+			report.Rejected++
+			continue
+		}
+		exp.OwnerID = ownerID
+		valid = append(valid, exp)
+		validRows = append(validRows, len(report.Rows))
+		report.Rows = append(report.Rows, ImportRow{Line: line, Status: RowValid})
+	}
+
+	if o.Mode == ImportAll && report.Rejected > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, report)
+	}
+
+	if len(valid) > 0 {
+		if err := h.repo.CreateMany(h.context(c), valid); err != nil {
+			return apperr.Respond(c, err)
+		}
+		recordCreated(sourceImport, valid...)
+	}
+	for i, row := range validRows {
+		report.Rows[row].Status = RowImported
+		report.Rows[row].ID = valid[i].ID
+	}
+	report.Imported = len(valid)
+
+	return c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,63 @@
+//go:build it
+
+package expenses
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportExpenses(t *testing.T) {
+	// setup echo server
+	e, settings, close := SetupServer(t)
+	PingServer()
+
+	t.Run("Should import valid rows in one go and report rejected ones", func(t *testing.T) {
+		// Arrange
+		body := "title,amount,tags\nrice,50,food;lunch\n,50,food\ntea,30,beverage\n"
+		var report ImportReport
+
+		// Act
+		res := Request(t, http.MethodPost, Uri(fmt.Sprint(settings.Port), "expenses", "import"), strings.NewReader(body))
+		err := res.Decode(&report)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, 2, report.Imported)
+			assert.Equal(t, 1, report.Rejected)
+			assert.Equal(t, RowRejected, report.Rows[1].Status)
+
+			var exp Expenses
+			err := Request(t, http.MethodGet, Uri(fmt.Sprint(settings.Port), "expenses", fmt.Sprint(report.Rows[0].ID)), nil).Decode(&exp)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "rice", exp.Title)
+				assert.Equal(t, []string{"food", "lunch"}, exp.Tags)
+			}
+		}
+	})
+
+	t.Run("Should import nothing in all mode when a row is rejected", func(t *testing.T) {
+		// Arrange
+		body := "title,amount\nunique import row,50\n,50\n"
+		var report ImportReport
+
+		// Act
+		res := Request(t, http.MethodPost, Uri(fmt.Sprint(settings.Port), "expenses", "import?mode=all"), strings.NewReader(body))
+		err := res.Decode(&report)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+			assert.Equal(t, 0, report.Imported)
+			assert.Equal(t, RowValid, report.Rows[0].Status)
+		}
+	})
+
+	// teardown echo server
+	TeardownServer(t, e, close)
+}
@@ -0,0 +1,244 @@
+//go:build unit
+
+package expenses
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func importRequest(t *testing.T, h handler, query, body string) (*httptest.ResponseRecorder, ImportReport) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/expenses/import"+query, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "text/csv")
+	res := httptest.NewRecorder()
+	c := e.NewContext(req, res)
+	users.SetCurrentUser(c, testUser)
+
+	err := h.ImportExpenses(c)
+	assert.NoError(t, err)
+
+	var report ImportReport
+	json.Unmarshal(res.Body.Bytes(), &report)
+	return res, report
+}
+
+func TestImportExpensesHandler(t *testing.T) {
+	t.Run("Should import valid rows and report rejected ones", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		h := handler{repo: repo}
+		body := "Title,Amount,Tags,Spent_At,Comment\n" +
+			"rice,50,food;lunch,2024-01-02,ignored\n" +
+			",abc,food,yesterday,\n" +
+			"\n" +
+			"\"taxi, airport\",350.25,,,\n"
+
+		// Act
+		res, report := importRequest(t, h, "", body)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, ImportReport{
+			Mode:     ImportPartial,
+			Imported: 2,
+			Rejected: 1,
+			Rows: []ImportRow{
+				{Line: 2, Status: RowImported, ID: 1},
+				{Line: 3, Status: RowRejected, Errors: []apperr.FieldError{
+					{Field: "amount", Message: "must be a number"},
+					{Field: "spent_at", Message: "must be RFC 3339 time or YYYY-MM-DD date"},
+					{Field: "title", Message: "is required"},
+				}},
+				{Line: 5, Status: RowImported, ID: 2},
+			},
+		}, report)
+
+		rice, _ := repo.Get(context.Background(), testUser.ID, "1")
+		assert.Equal(t, money.Amount(500000), rice.Amount)
+		assert.Equal(t, []string{"food", "lunch"}, rice.Tags)
+		assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), rice.SpentAt.UTC())
+		taxi, _ := repo.Get(context.Background(), testUser.ID, "2")
+		assert.Equal(t, "taxi, airport", taxi.Title)
+		assert.Nil(t, taxi.Tags)
+	})
+
+	t.Run("Should import nothing in all mode when a row is rejected", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		h := handler{repo: repo}
+		body := "title,amount\nrice,50\ntea,-1\n"
+
+		// Act
+		res, report := importRequest(t, h, "?mode=all", body)
+
+		// Assert
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+		assert.Equal(t, 0, report.Imported)
+		assert.Equal(t, []ImportRow{
+			{Line: 2, Status: RowValid},
+			{Line: 3, Status: RowRejected, Errors: []apperr.FieldError{{Field: "amount", Message: "must be greater than 0"}}},
+		}, report.Rows)
+		total, _ := repo.Count(context.Background(), listFilter{OwnerID: testUser.ID})
+		assert.Equal(t, 0, total)
+	})
+
+	tests := []struct {
+		name  string
+		query string
+		body  string
+	}{
+		{"Should map columns by position and detect the header", "?columns=-,title,amount&delimiter=%3B", "date;what;cost\n2024-01-01;rice;50\n"},
+		{"Should map columns by position without header", "?columns=title,amount,tags&tag_separator=|", "rice,50,food|lunch\n"},
+		{"Should skip the header row when told so", "?columns=title,amount&header=true", "50,50\nrice,50\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo := NewMemoryRepository()
+			h := handler{repo: repo}
+
+			// Act
+			res, report := importRequest(t, h, tt.query, tt.body)
+
+			// Assert
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, 1, report.Imported)
+			exp, err := repo.Get(context.Background(), testUser.ID, "1")
+			if assert.NoError(t, err) {
+				assert.Equal(t, "rice", exp.Title)
+				assert.Equal(t, money.Amount(500000), exp.Amount)
+			}
+		})
+	}
+
+	t.Run("Should read the file field of a multipart form", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		file, _ := form.CreateFormFile("file", "expenses.csv")
+		file.Write([]byte("title,amount\nrice,50\n"))
+		form.Close()
+		req := httptest.NewRequest(http.MethodPost, "/expenses/import", &body)
+		req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+		res := httptest.NewRecorder()
+
+		h := handler{repo: NewMemoryRepository()}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+
+		// Act
+		err := h.ImportExpenses(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Contains(t, res.Body.String(), `"imported":1`)
+		}
+	})
+
+	errorTests := []struct {
+		name     string
+		query    string
+		body     string
+		code     int
+		expected string
+	}{
+		{
+			"Should return unprocess entity error if column is unknown",
+			"?columns=title,price", "",
+			http.StatusUnprocessableEntity,
+			`{"statusCode":422,"message":"Query param columns must list title, amount, currency, note, tags, spent_at or - per column"}`,
+		},
+		{
+			"Should return unprocess entity error if columns are missing without header",
+			"?header=false", "",
+			http.StatusUnprocessableEntity,
+			`{"statusCode":422,"message":"Query param columns is required when header is false"}`,
+		},
+		{
+			"Should return unprocess entity error if mode is unknown",
+			"?mode=some", "",
+			http.StatusUnprocessableEntity,
+			`{"statusCode":422,"message":"Query param mode must be partial or all"}`,
+		},
+		{
+			"Should return bad request error if csv is malformed",
+			"", "title,amount\n\"rice,50\n",
+			http.StatusBadRequest,
+			`{"statusCode":400,"message":"Invalid CSV: (.+) extraneous or missing \\" in quoted-field"}`,
+		},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			res, _ := importRequest(t, handler{repo: NewMemoryRepository()}, tt.query, tt.body)
+
+			// Assert
+			assert.Equal(t, tt.code, res.Code)
+			assert.Regexp(t, tt.expected, strings.TrimSpace(res.Body.String()))
+		})
+	}
+}
+
+func TestPostgresCreateMany(t *testing.T) {
+	t.Run("Should insert every expense in one transaction", func(t *testing.T) {
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO expenses").WithArgs("rice", money.Amount(500000), "THB", "", sqlmock.AnyArg(), testUser.ID, nil).
+			WillReturnRows(mock.NewRows([]string{"id", "spent_at", "created_at", "updated_at", "version"}).AddRow(1, testTime, testTime, testTime, 1))
+		mock.ExpectQuery("INSERT INTO expenses").
+			WillReturnRows(mock.NewRows([]string{"id", "spent_at", "created_at", "updated_at", "version"}).AddRow(2, testTime, testTime, testTime, 1))
+		mock.ExpectCommit()
+
+		exps := []Expenses{
+			{Title: "rice", Amount: 500000, Currency: "THB", OwnerID: testUser.ID},
+			{Title: "tea", Amount: 300000, Currency: "THB", OwnerID: testUser.ID},
+		}
+		err := NewPostgresRepository(db).CreateMany(context.Background(), exps)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, exps[0].ID)
+			assert.Equal(t, 2, exps[1].ID)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("Should roll back when an insert fails", func(t *testing.T) {
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO expenses").
+			WillReturnRows(mock.NewRows([]string{"id", "spent_at", "created_at", "updated_at", "version"}).AddRow(1, testTime, testTime, testTime, 1))
+		mock.ExpectQuery("INSERT INTO expenses").WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		exps := []Expenses{{Title: "rice"}, {Title: "tea"}}
+		err := NewPostgresRepository(db).CreateMany(context.Background(), exps)
+
+		assert.EqualError(t, err, "connection reset")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
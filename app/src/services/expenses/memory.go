@@ -0,0 +1,698 @@
+package expenses
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+)
+
+type memoryRecord struct {
+	exp     Expenses
+	deleted bool
+}
+
+// memoryRepository keeps expenses in process memory. It is meant for demos
+// and tests and loses everything on restart.
+type memoryRepository struct {
+	mu      sync.RWMutex
+	lastID  int
+	records map[int]*memoryRecord
+	// imported holds the statement keys each owner has imported.
+	imported  map[int]map[string]bool
+	revisions []Revision
+	now       func() time.Time
+}
+
+func NewMemoryRepository() ExpenseRepository {
+	return &memoryRepository{records: map[int]*memoryRecord{}, imported: map[int]map[string]bool{}, now: time.Now}
+}
+
+func parseID(id string) (int, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, apperr.ErrInvalidID
+	}
+	return n, nil
+}
+
+// clone copies exp so callers never share the tags slice with the store.
+func clone(exp Expenses) Expenses {
+	if exp.Tags != nil {
+		exp.Tags = append([]string{}, exp.Tags...)
+	}
+	return exp
+}
+
+// find returns the record with id owned by owner, whether deleted or not.
+func (r *memoryRepository) find(owner int, id string) (*memoryRecord, error) {
+	n, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	rec, ok := r.records[n]
+	if !ok || rec.exp.OwnerID != owner {
+		return nil, sql.ErrNoRows
+	}
+	return rec, nil
+}
+
+func (r *memoryRepository) live(owner int, id string) (*memoryRecord, error) {
+	rec, err := r.find(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.deleted {
+		return nil, sql.ErrNoRows
+	}
+	return rec, nil
+}
+
+func (r *memoryRepository) Create(ctx context.Context, exp *Expenses) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.create(exp)
+	r.record(ctx, ActionCreate, nil, exp)
+	return nil
+}
+
+func (r *memoryRepository) CreateMany(ctx context.Context, exps []Expenses) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range exps {
+		r.create(&exps[i])
+		r.record(ctx, ActionCreate, nil, &exps[i])
+	}
+	return nil
+}
+
+func (r *memoryRepository) Imported(ctx context.Context, owner int, keys []string) (map[string]bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	imported := map[string]bool{}
+	for _, key := range keys {
+		if r.imported[owner][key] {
+			imported[key] = true
+		}
+	}
+	return imported, nil
+}
+
+func (r *memoryRepository) CreateImported(ctx context.Context, keys []string, exps []Expenses) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range exps {
+		owner := exps[i].OwnerID
+		if r.imported[owner][keys[i]] {
+			continue
+		}
+		if r.imported[owner] == nil {
+			r.imported[owner] = map[string]bool{}
+		}
+		r.imported[owner][keys[i]] = true
+		r.create(&exps[i])
+		r.record(ctx, ActionCreate, nil, &exps[i])
+	}
+	return nil
+}
+
+// record keeps a revision of a change when ctx is audited. Callers hold the
+// write lock, so the revision is stored with the change.
+func (r *memoryRepository) record(ctx context.Context, action string, before, after *Expenses) {
+	a, ok := auditFrom(ctx)
+	if !ok {
+		return
+	}
+	rev := newRevision(a, action, before, after)
+	rev.ID = len(r.revisions) + 1
+	rev.CreatedAt = r.now()
+	r.revisions = append(r.revisions, rev)
+}
+
+func (r *memoryRepository) create(exp *Expenses) {
+	r.lastID++
+	exp.ID = r.lastID
+	exp.CreatedAt = r.now()
+	exp.UpdatedAt = exp.CreatedAt
+	exp.Version = 1
+	if exp.SpentAt.IsZero() {
+		exp.SpentAt = exp.CreatedAt
+	}
+	r.records[exp.ID] = &memoryRecord{exp: clone(*exp)}
+}
+
+func (r *memoryRepository) Get(ctx context.Context, owner int, id string) (Expenses, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rec, err := r.live(owner, id)
+	if err != nil {
+		return Expenses{}, err
+	}
+	return clone(rec.exp), nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, owner int, id string, exp *Expenses) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, err := r.live(owner, id)
+	if err != nil {
+		return err
+	}
+	if exp.Version != 0 && exp.Version != rec.exp.Version {
+		return errVersionMismatch
+	}
+	before := clone(rec.exp)
+	exp.keepServerFields(rec.exp)
+	exp.UpdatedAt = r.now()
+	exp.Version++
+	if exp.SpentAt.IsZero() {
+		exp.SpentAt = rec.exp.SpentAt
+	}
+	rec.exp = clone(*exp)
+	r.record(ctx, ActionUpdate, &before, exp)
+	return nil
+}
+
+func (r *memoryRepository) Patch(ctx context.Context, owner int, id string, apply func(exp *Expenses) error) (Expenses, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, err := r.live(owner, id)
+	if err != nil {
+		return Expenses{}, err
+	}
+	exp := clone(rec.exp)
+	if err := apply(&exp); err != nil {
+		return exp, err
+	}
+	exp.keepServerFields(rec.exp)
+	exp.UpdatedAt = r.now()
+	exp.Version++
+	if exp.SpentAt.IsZero() {
+		exp.SpentAt = rec.exp.SpentAt
+	}
+	before := rec.exp
+	rec.exp = clone(exp)
+	r.record(ctx, ActionUpdate, &before, &exp)
+	return exp, nil
+}
+
+func (f listFilter) match(exp Expenses) bool {
+	if exp.OwnerID != f.OwnerID {
+		return false
+	}
+	for _, tag := range f.Tags {
+		found := false
+		for _, t := range exp.Tags {
+			found = found || t == tag
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.MinAmount != nil && exp.Amount < *f.MinAmount {
+		return false
+	}
+	if f.MaxAmount != nil && exp.Amount > *f.MaxAmount {
+		return false
+	}
+	if f.Title != "" && !strings.Contains(strings.ToLower(exp.Title), strings.ToLower(f.Title)) {
+		return false
+	}
+	if f.Note != "" && !strings.Contains(strings.ToLower(exp.Note), strings.ToLower(f.Note)) {
+		return false
+	}
+	if f.SpentFrom != nil && exp.SpentAt.Before(*f.SpentFrom) {
+		return false
+	}
+	if f.SpentTo != nil && !exp.SpentAt.Before(*f.SpentTo) {
+		return false
+	}
+	return true
+}
+
+// compare orders a before b by the sort column of q with id as tie breaker,
+// returning a negative, zero or positive number like strings.Compare.
+func (q listQuery) compare(a, b Expenses) int {
+	c := 0
+	switch q.SortColumn {
+	case "amount":
+		c = compareInt(int64(a.Amount), int64(b.Amount))
+	case "title":
+		c = strings.Compare(a.Title, b.Title)
+	case "spent_at":
+		switch {
+		case a.SpentAt.Before(b.SpentAt):
+			c = -1
+		case a.SpentAt.After(b.SpentAt):
+			c = 1
+		}
+	}
+	if c == 0 {
+		c = compareInt(int64(a.ID), int64(b.ID))
+	}
+	if q.Descending {
+		c = -c
+	}
+	return c
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// cursorExpense rebuilds the sort key the cursor points at.
+func (q listQuery) cursorExpense() (Expenses, bool) {
+	exp := Expenses{ID: q.Cursor.ID}
+	switch q.SortColumn {
+	case "amount":
+		amount, err := money.Parse(q.Cursor.Value)
+		if err != nil {
+			return exp, false
+		}
+		exp.Amount = amount
+	case "title":
+		exp.Title = q.Cursor.Value
+	case "spent_at":
+		spentAt, err := time.Parse(time.RFC3339Nano, q.Cursor.Value)
+		if err != nil {
+			return exp, false
+		}
+		exp.SpentAt = spentAt
+	}
+	return exp, true
+}
+
+func (r *memoryRepository) List(ctx context.Context, q listQuery) ([]Expenses, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var after *Expenses
+	if q.Cursor != nil {
+		exp, ok := q.cursorExpense()
+		if !ok {
+			return nil, nil
+		}
+		after = &exp
+	}
+
+	var expenses []Expenses
+	for _, rec := range r.records {
+		if rec.deleted || !q.match(rec.exp) {
+			continue
+		}
+		if after != nil && q.compare(rec.exp, *after) <= 0 {
+			continue
+		}
+		expenses = append(expenses, clone(rec.exp))
+	}
+
+	sort.Slice(expenses, func(i, j int) bool { return q.compare(expenses[i], expenses[j]) < 0 })
+	if q.Paginate && len(expenses) > q.Limit+1 {
+		expenses = expenses[:q.Limit+1]
+	}
+	return expenses, nil
+}
+
+// Stream works on a snapshot taken by List so fn runs without the lock held.
+func (r *memoryRepository) Stream(ctx context.Context, q listQuery, fn func(Expenses) error) error {
+	expenses, err := r.List(ctx, q)
+	if err != nil {
+		return err
+	}
+	for _, exp := range expenses {
+		if err := fn(exp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) Count(ctx context.Context, f listFilter) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	total := 0
+	for _, rec := range r.records {
+		if !rec.deleted && f.match(rec.exp) {
+			total++
+		}
+	}
+	return total, nil
+}
+
+type summaryKey struct {
+	tag, period, currency string
+}
+
+// add folds amount into row, keeping the total exact and rounding the
+// average half away from zero like Postgres.
+func (row *SummaryRow) add(amount money.Amount) {
+	if row.Count == 0 || amount < row.Min {
+		row.Min = amount
+	}
+	if row.Count == 0 || amount > row.Max {
+		row.Max = amount
+	}
+	row.Count++
+	row.Total += amount
+	row.Average = (2*row.Total + money.Amount(row.Count)) / (2 * money.Amount(row.Count))
+}
+
+func (r *memoryRepository) Summary(ctx context.Context, q summaryQuery) (Summary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	totals := map[summaryKey]*SummaryRow{}
+	groups := map[summaryKey]*SummaryRow{}
+	// row finds the row of key, which names its tag when it is a group of a
+	// summary by tag. Totals never do, like the Postgres ones.
+	row := func(rows map[summaryKey]*SummaryRow, key summaryKey, tagged bool) *SummaryRow {
+		if rows[key] == nil {
+			rows[key] = &SummaryRow{Currency: key.currency, Period: key.period}
+			if tagged {
+				rows[key].Tag = &key.tag
+			}
+		}
+		return rows[key]
+	}
+
+	for _, rec := range r.records {
+		if rec.deleted || !q.match(rec.exp) {
+			continue
+		}
+		row(totals, summaryKey{currency: rec.exp.Currency}, false).add(rec.exp.Amount)
+
+		key := summaryKey{currency: rec.exp.Currency}
+		if q.Period != "" {
+			key.period = q.label(rec.exp.SpentAt)
+		}
+		tags := []string{""}
+		if q.ByTag && len(rec.exp.Tags) > 0 {
+			tags = rec.exp.Tags
+		}
+		for _, tag := range tags {
+			key.tag = tag
+			row(groups, key, q.ByTag).add(rec.exp.Amount)
+		}
+	}
+
+	summary := Summary{GroupBy: q.groupBy(), Totals: sortedRows(totals), Groups: []SummaryRow{}}
+	if len(summary.GroupBy) > 0 {
+		summary.Groups = sortedRows(groups)
+	}
+	return summary, nil
+}
+
+func sortedRows(rows map[summaryKey]*SummaryRow) []SummaryRow {
+	keys := make([]summaryKey, 0, len(rows))
+	for key := range rows {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.tag != b.tag {
+			return a.tag < b.tag
+		}
+		if a.period != b.period {
+			return a.period < b.period
+		}
+		return a.currency < b.currency
+	})
+
+	result := make([]SummaryRow, len(keys))
+	for i, key := range keys {
+		result[i] = *rows[key]
+	}
+	return result
+}
+
+func (r *memoryRepository) Spent(ctx context.Context, owner int, tag, currency string, from, to time.Time) (money.Amount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	f := listFilter{OwnerID: owner, Tags: []string{tag}, SpentFrom: &from, SpentTo: &to}
+	var spent money.Amount
+	for _, rec := range r.records {
+		if !rec.deleted && rec.exp.Currency == currency && f.match(rec.exp) {
+			spent += rec.exp.Amount
+		}
+	}
+	return spent, nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, owner int, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, err := r.live(owner, id)
+	if err != nil {
+		return err
+	}
+	rec.deleted = true
+	r.record(ctx, ActionDelete, &rec.exp, nil)
+	return nil
+}
+
+func (r *memoryRepository) Touch(ctx context.Context, owner int, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, err := r.live(owner, id)
+	if err != nil {
+		return err
+	}
+	rec.exp.Version++
+	return nil
+}
+
+func (r *memoryRepository) Restore(ctx context.Context, owner int, id string) (Expenses, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, err := r.find(owner, id)
+	if err != nil {
+		return Expenses{}, err
+	}
+	if !rec.deleted {
+		return Expenses{}, sql.ErrNoRows
+	}
+	rec.deleted = false
+	r.record(ctx, ActionRestore, nil, &rec.exp)
+	return clone(rec.exp), nil
+}
+
+func (r *memoryRepository) Purge(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	rec, ok := r.records[n]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.records, n)
+	r.record(ctx, ActionPurge, &rec.exp, nil)
+	return nil
+}
+
+func (r *memoryRepository) Unowned(ctx context.Context) ([]Expenses, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	expenses := []Expenses{}
+	for _, rec := range r.records {
+		if rec.exp.OwnerID == 0 && !rec.deleted {
+			expenses = append(expenses, clone(rec.exp))
+		}
+	}
+	sort.Slice(expenses, func(i, j int) bool { return expenses[i].ID < expenses[j].ID })
+	return expenses, nil
+}
+
+func (r *memoryRepository) Assign(ctx context.Context, id string, owner int) (Expenses, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, err := r.live(0, id)
+	if err != nil {
+		return Expenses{}, err
+	}
+	before := clone(rec.exp)
+	rec.exp.OwnerID = owner
+	rec.exp.UpdatedAt = r.now()
+	rec.exp.Version++
+	r.record(ctx, ActionUpdate, &before, &rec.exp)
+	return clone(rec.exp), nil
+}
+
+func (r *memoryRepository) History(ctx context.Context, owner int, id string) ([]Revision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	revisions := []Revision{}
+	for _, rev := range r.revisions {
+		if rev.ExpenseID == n && rev.OwnerID == owner {
+			revisions = append(revisions, rev)
+		}
+	}
+	if len(revisions) == 0 {
+		if _, err := r.find(owner, id); err != nil {
+			return nil, err
+		}
+	}
+	return revisions, nil
+}
+
+type memoryAttachmentRepository struct {
+	mu          sync.RWMutex
+	lastID      int
+	attachments map[int]Attachment
+	now         func() time.Time
+}
+
+func NewMemoryAttachmentRepository() AttachmentRepository {
+	return &memoryAttachmentRepository{attachments: map[int]Attachment{}, now: time.Now}
+}
+
+func (r *memoryAttachmentRepository) find(expenseID int, id string) (Attachment, error) {
+	n, err := parseID(id)
+	if err != nil {
+		return Attachment{}, err
+	}
+	a, ok := r.attachments[n]
+	if !ok || a.ExpenseID != expenseID {
+		return Attachment{}, sql.ErrNoRows
+	}
+	return a, nil
+}
+
+func (r *memoryAttachmentRepository) Create(ctx context.Context, a *Attachment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	a.ID = r.lastID
+	a.CreatedAt = r.now()
+	r.attachments[a.ID] = *a
+	return nil
+}
+
+func (r *memoryAttachmentRepository) List(ctx context.Context, expenseID int) ([]Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := []Attachment{}
+	for _, a := range r.attachments {
+		if a.ExpenseID == expenseID {
+			list = append(list, a)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (r *memoryAttachmentRepository) Get(ctx context.Context, expenseID int, id string) (Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.find(expenseID, id)
+}
+
+func (r *memoryAttachmentRepository) Delete(ctx context.Context, expenseID int, id string) (Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, err := r.find(expenseID, id)
+	if err != nil {
+		return Attachment{}, err
+	}
+	delete(r.attachments, a.ID)
+	return a, nil
+}
+
+type memoryIdempotencyEntry struct {
+	req       IdempotentRequest
+	expiresAt time.Time
+}
+
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	entries map[int]map[string]*memoryIdempotencyEntry
+	now     func() time.Time
+}
+
+func NewMemoryIdempotencyRepository() IdempotencyRepository {
+	return &memoryIdempotencyRepository{entries: map[int]map[string]*memoryIdempotencyEntry{}, now: time.Now}
+}
+
+func (r *memoryIdempotencyRepository) Reserve(ctx context.Context, owner int, key, hash string, ttl time.Duration) (IdempotentRequest, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	keys := r.entries[owner]
+	if keys == nil {
+		keys = map[string]*memoryIdempotencyEntry{}
+		r.entries[owner] = keys
+	}
+	for k, e := range keys {
+		if !e.expiresAt.After(now) {
+			delete(keys, k)
+		}
+	}
+	if e, ok := keys[key]; ok {
+		req := e.req
+		req.Header = req.Header.Clone()
+		req.Body = append([]byte(nil), req.Body...)
+		return req, false, nil
+	}
+	keys[key] = &memoryIdempotencyEntry{req: IdempotentRequest{Hash: hash}, expiresAt: now.Add(ttl)}
+	return IdempotentRequest{Hash: hash}, true, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(ctx context.Context, owner int, key string, status int, header http.Header, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.entries[owner][key]; ok {
+		e.req.Status = status
+		e.req.Header = header.Clone()
+		e.req.Body = append([]byte(nil), body...)
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Release(ctx context.Context, owner int, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.entries[owner], key)
+	return nil
+}
@@ -0,0 +1,229 @@
+//go:build unit
+
+package expenses
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func seedMemory(t *testing.T, repo ExpenseRepository, ownerID int, expenses ...Expenses) {
+	for i := range expenses {
+		expenses[i].OwnerID = ownerID
+		if err := repo.Create(context.Background(), &expenses[i]); err != nil {
+			t.Fatalf("an error '%s' was not expected when seeding expenses", err)
+		}
+	}
+}
+
+func TestMemoryRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should create, get, update and patch expense", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		exp := Expenses{Title: "strawberry smoothie", Amount: 790000, Currency: "THB", Tags: []string{"food"}, OwnerID: testUser.ID}
+
+		// Act
+		errCreate := repo.Create(ctx, &exp)
+		exp.Title = "apple smoothie"
+		errUpdate := repo.Update(ctx, testUser.ID, "1", &exp)
+		patched, errPatch := repo.Patch(ctx, testUser.ID, "1", func(e *Expenses) error {
+			e.Note = "no discount"
+			return nil
+		})
+		got, errGet := repo.Get(ctx, testUser.ID, "1")
+
+		// Assert
+		assert.NoError(t, errCreate)
+		assert.NoError(t, errUpdate)
+		assert.NoError(t, errPatch)
+		if assert.NoError(t, errGet) {
+			assert.Equal(t, 1, got.ID)
+			assert.Equal(t, "apple smoothie", got.Title)
+			assert.Equal(t, "no discount", got.Note)
+			assert.Equal(t, patched, got)
+		}
+	})
+
+	t.Run("Should not store a failed patch", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		seedMemory(t, repo, testUser.ID, Expenses{Title: "tea"})
+
+		// Act
+		_, err := repo.Patch(ctx, testUser.ID, "1", func(e *Expenses) error {
+			e.Title = "coffee"
+			return errPatchTestFailed
+		})
+		got, _ := repo.Get(ctx, testUser.ID, "1")
+
+		// Assert
+		assert.ErrorIs(t, err, errPatchTestFailed)
+		assert.Equal(t, "tea", got.Title)
+	})
+
+	t.Run("Should hide deleted expense until restored and forget purged one", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		seedMemory(t, repo, testUser.ID, Expenses{Title: "tea"})
+
+		// Act
+		errDelete := repo.Delete(ctx, testUser.ID, "1")
+		_, errGetDeleted := repo.Get(ctx, testUser.ID, "1")
+		_, errRestore := repo.Restore(ctx, testUser.ID, "1")
+		_, errGetRestored := repo.Get(ctx, testUser.ID, "1")
+		errPurge := repo.Purge(ctx, "1")
+		_, errRestorePurged := repo.Restore(ctx, testUser.ID, "1")
+
+		// Assert
+		assert.NoError(t, errDelete)
+		assert.ErrorIs(t, errGetDeleted, sql.ErrNoRows)
+		assert.NoError(t, errRestore)
+		assert.NoError(t, errGetRestored)
+		assert.NoError(t, errPurge)
+		assert.ErrorIs(t, errRestorePurged, sql.ErrNoRows)
+	})
+
+	t.Run("Should reject id that is not integer", func(t *testing.T) {
+		// Act
+		_, err := NewMemoryRepository().Get(ctx, testUser.ID, "dw2")
+
+		// Assert
+		assert.True(t, errors.Is(err, apperr.ErrInvalidID))
+	})
+
+	t.Run("Should list filtered expenses page by page", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		seedMemory(t, repo, testUser.ID,
+			Expenses{Title: "Grill pork", Amount: 1000000, Tags: []string{"food"}},
+			Expenses{Title: "Strawberry smoothie", Amount: 790000, Tags: []string{"food", "beverage"}},
+			Expenses{Title: "iPhone", Amount: 669000000, Tags: []string{"gadget"}},
+			Expenses{Title: "Mango sticky rice", Amount: 1000000, Tags: []string{"food"}},
+		)
+		min := money.Amount(800000)
+		q := listQuery{
+			listFilter: listFilter{OwnerID: testUser.ID, Tags: []string{"food"}, MinAmount: &min},
+			SortColumn: "amount",
+			Descending: true,
+			Limit:      1,
+			Paginate:   true,
+		}
+
+		// Act
+		first, errFirst := repo.List(ctx, q)
+		page, cursor := q.nextCursor(first)
+		q.Cursor, _ = decodeCursor(cursor)
+		second, errSecond := repo.List(ctx, q)
+		total, errCount := repo.Count(ctx, q.listFilter)
+
+		// Assert
+		assert.NoError(t, errFirst)
+		assert.NoError(t, errSecond)
+		assert.NoError(t, errCount)
+		assert.Equal(t, []int{4}, ids(page))
+		assert.Equal(t, []int{1}, ids(second))
+		assert.Equal(t, 2, total)
+	})
+
+	t.Run("Should manage timestamps and keep spent_at unless given", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository().(*memoryRepository)
+		now := testTime
+		repo.now = func() time.Time { return now }
+		exp := Expenses{Title: "tea", OwnerID: testUser.ID}
+
+		// Act
+		repo.Create(ctx, &exp)
+		now = testTime.Add(time.Hour)
+		update := Expenses{Title: "coffee", CreatedAt: testTime.AddDate(1, 0, 0)}
+		repo.Update(ctx, testUser.ID, "1", &update)
+		got, err := repo.Get(ctx, testUser.ID, "1")
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, testTime, got.SpentAt)
+			assert.Equal(t, testTime, got.CreatedAt)
+			assert.Equal(t, testTime.Add(time.Hour), got.UpdatedAt)
+		}
+	})
+
+	t.Run("Should filter by spent_at range", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		seedMemory(t, repo, testUser.ID,
+			Expenses{Title: "before", SpentAt: testTime.Add(-time.Second)},
+			Expenses{Title: "from", SpentAt: testTime},
+			Expenses{Title: "to", SpentAt: testTime.Add(time.Hour)},
+		)
+		from, to := testTime, testTime.Add(time.Hour)
+
+		// Act
+		expenses, err := repo.List(ctx, listQuery{
+			listFilter: listFilter{OwnerID: testUser.ID, SpentFrom: &from, SpentTo: &to},
+			SortColumn: "spent_at",
+		})
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, []int{2}, ids(expenses))
+		}
+	})
+
+	t.Run("Should not find expenses of another owner", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		seedMemory(t, repo, testUser.ID+1, Expenses{Title: "tea"})
+
+		// Act
+		_, errGet := repo.Get(ctx, testUser.ID, "1")
+		errDelete := repo.Delete(ctx, testUser.ID, "1")
+		expenses, _ := repo.List(ctx, listQuery{listFilter: listFilter{OwnerID: testUser.ID}, SortColumn: "id"})
+
+		// Assert
+		assert.ErrorIs(t, errGet, sql.ErrNoRows)
+		assert.ErrorIs(t, errDelete, sql.ErrNoRows)
+		assert.Empty(t, expenses)
+	})
+
+	t.Run("Should be safe for concurrent use", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		var wg sync.WaitGroup
+
+		// Act
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				exp := Expenses{Title: "tea", Tags: []string{"beverage"}, OwnerID: testUser.ID}
+				repo.Create(ctx, &exp)
+				repo.List(ctx, listQuery{listFilter: listFilter{OwnerID: testUser.ID}, SortColumn: "id"})
+			}()
+		}
+		wg.Wait()
+		total, err := repo.Count(ctx, listFilter{OwnerID: testUser.ID})
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, 50, total)
+		}
+	})
+}
+
+func ids(expenses []Expenses) []int {
+	result := make([]int, len(expenses))
+	for i, e := range expenses {
+		result[i] = e.ID
+	}
+	return result
+}
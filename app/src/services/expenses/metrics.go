@@ -0,0 +1,29 @@
+package expenses
+
+import "github.com/RTae/assessment/app/src/metrics"
+
+// Sources an expense is recorded through, as labelled in metrics.
+const (
+	sourceAPI       = "api"
+	sourceImport    = "import"
+	sourceStatement = "statement"
+)
+
+var (
+	expensesCreated = metrics.Default.NewCounter("expenses_created_total",
+		"Expenses recorded, by how they were recorded and currency.", "source", "currency")
+	amountRecorded = metrics.Default.NewCounter("expenses_amount_recorded_total",
+		"Sum of the amounts of the expenses recorded, by currency.", "currency")
+)
+
+// recordCreated counts the stored expenses of exps as recorded through
+// source. Expenses without an id were skipped and are not counted.
+func recordCreated(source string, exps ...Expenses) {
+	for _, exp := range exps {
+		if exp.ID == 0 {
+			continue
+		}
+		expensesCreated.Inc(source, exp.Currency)
+		amountRecorded.Add(exp.Amount.Float64(), exp.Currency)
+	}
+}
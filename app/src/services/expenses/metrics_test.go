@@ -0,0 +1,26 @@
+//go:build unit
+
+package expenses
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordCreated(t *testing.T) {
+	// Arrange
+	created := expensesCreated.Value(sourceStatement, "JPY")
+	amount := amountRecorded.Value("JPY")
+
+	// Act
+	recordCreated(sourceStatement,
+		Expenses{ID: 1, Amount: 5000000, Currency: "JPY"},
+		Expenses{ID: 0, Amount: 1000000, Currency: "JPY"},
+		Expenses{ID: 2, Amount: 2500000, Currency: "JPY"},
+	)
+
+	// Assert
+	assert.Equal(t, created+2, expensesCreated.Value(sourceStatement, "JPY"))
+	assert.Equal(t, amount+750, amountRecorded.Value("JPY"))
+}
@@ -0,0 +1,79 @@
+package expenses
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+)
+
+// owner resolves whose expenses the request works on. Callers act on their
+// own expenses; admins may read those of another user with the owner query
+// param, but never write them, so a change is never made, or recorded, in
+// another user's name.
+func owner(c echo.Context) (int, *apperr.ErrorResponse) {
+	user, ok := users.CurrentUser(c)
+	if !ok {
+		return 0, &apperr.ErrorResponse{Code: http.StatusUnauthorized, Message: "Authentication required"}
+	}
+
+	raw := c.QueryParam("owner")
+	if raw == "" {
+		return user.ID, nil
+	}
+	if user.Role != users.RoleAdmin {
+		return 0, &apperr.ErrorResponse{Code: http.StatusForbidden, Message: "Admin permission required"}
+	}
+	if m := c.Request().Method; m != http.MethodGet && m != http.MethodHead {
+		return 0, &apperr.ErrorResponse{Code: http.StatusForbidden, Message: "Query param owner is only allowed when reading"}
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, &apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Query param owner must be integer"}
+	}
+	return id, nil
+}
+
+// ownerInput is the body that assigns an expense to a user.
+type ownerInput struct {
+	OwnerID int `json:"owner_id"`
+}
+
+// GetUnownedExpenses lists the expenses stored before owners were kept, so
+// an admin can find the ones to assign.
+func (h *handler) GetUnownedExpenses(c echo.Context) error {
+	expenses, err := h.repo.Unowned(c.Request().Context())
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	for i := range expenses {
+		h.localize(&expenses[i])
+	}
+	return c.JSON(http.StatusOK, expenses)
+}
+
+// AssignExpenseOwner gives an expense without an owner to the user named in
+// the body, who sees it from then on. The assignment is recorded as an
+// update revision made by the admin.
+func (h *handler) AssignExpenseOwner(c echo.Context) error {
+	var in ownerInput
+	if err := c.Bind(&in); err != nil {
+		return apperr.InvalidBody(c, err)
+	}
+	if in.OwnerID <= 0 {
+		return apperr.Respond(c, &apperr.ValidationError{Details: []apperr.FieldError{
+			{Field: "owner_id", Message: "must be the id of a user"},
+		}})
+	}
+
+	exp, err := h.repo.Assign(h.context(c), c.Param("id"), in.OwnerID)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	h.localize(&exp)
+	return c.JSON(http.StatusOK, exp)
+}
@@ -0,0 +1,66 @@
+//go:build it
+
+package expenses
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpenseOwnership(t *testing.T) {
+	// setup echo server
+	e, settings, close := SetupServer(t)
+	PingServer()
+	t.Run("Should hide expense from another user", func(t *testing.T) {
+
+		// Arrange
+		createExpense := SeedExpense(t, settings)
+		_, otherToken := SeedUser(t, users.RoleUser)
+		uri := Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%d", createExpense.ID))
+
+		// Act
+		var errRes apperr.ErrorResponse
+		getRes := RequestAs(t, otherToken, http.MethodGet, uri, nil)
+		err := getRes.Decode(&errRes)
+
+		var expenses []Expenses
+		listRes := RequestAs(t, otherToken, http.MethodGet, Uri(fmt.Sprint(settings.Port), "expenses"), nil)
+		errList := listRes.Decode(&expenses)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNotFound, errRes.Code)
+			assert.Equal(t, "Record not found", errRes.Message)
+		}
+		if assert.NoError(t, errList) {
+			assert.Empty(t, expenses)
+		}
+	})
+
+	t.Run("Should let admin read another user's expense with owner filter", func(t *testing.T) {
+
+		// Arrange
+		createExpense := SeedExpense(t, settings)
+		_, adminToken := SeedUser(t, users.RoleAdmin)
+		uri := Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%d?owner=%d", createExpense.ID, seededUser.ID))
+
+		// Act
+		var exp Expenses
+		res := RequestAs(t, adminToken, http.MethodGet, uri, nil)
+		err := res.Decode(&exp)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, createExpense.ID, exp.ID)
+		}
+	})
+
+	// teardown echo server
+	TeardownServer(t, e, close)
+}
@@ -0,0 +1,146 @@
+//go:build unit
+
+package expenses
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwner(t *testing.T) {
+	admin := users.User{ID: 2, Username: "admin", Role: users.RoleAdmin}
+
+	tests := []struct {
+		name     string
+		user     *users.User
+		method   string
+		query    string
+		expected int
+		code     int
+	}{
+		{"Should scope to the caller", &testUser, http.MethodGet, "", testUser.ID, 0},
+		{"Should scope admin to the caller without owner filter", &admin, http.MethodGet, "", admin.ID, 0},
+		{"Should let admin read another owner", &admin, http.MethodGet, "?owner=5", 5, 0},
+		{"Should let admin write their own expenses", &admin, http.MethodPost, "", admin.ID, 0},
+		{"Should return forbidden error if admin writes as another owner", &admin, http.MethodPut, "?owner=5", 0, http.StatusForbidden},
+		{"Should return forbidden error if user picks another owner", &testUser, http.MethodGet, "?owner=5", 0, http.StatusForbidden},
+		{"Should return unprocess entity error if owner is not integer", &admin, http.MethodGet, "?owner=me", 0, http.StatusUnprocessableEntity},
+		{"Should return unauthorized error without user", nil, http.MethodGet, "", 0, http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			req := httptest.NewRequest(test.method, "/expenses"+test.query, nil)
+			c := e.NewContext(req, httptest.NewRecorder())
+			if test.user != nil {
+				users.SetCurrentUser(c, *test.user)
+			}
+
+			// Act
+			got, errRes := owner(c)
+
+			// Assert
+			assert.Equal(t, test.expected, got)
+			if test.code == 0 {
+				assert.Nil(t, errRes)
+			} else if assert.NotNil(t, errRes) {
+				assert.Equal(t, test.code, errRes.Code)
+			}
+		})
+	}
+}
+
+func TestAssignExpenseOwner(t *testing.T) {
+	// Arrange
+	repo := NewMemoryRepository()
+	seedMemory(t, repo, 0, Expenses{Title: "rice", Amount: 500000, Currency: "THB"})
+	seedMemory(t, repo, testUser.ID, Expenses{Title: "tea", Amount: 300000, Currency: "THB"})
+	h := handler{repo: repo, location: time.UTC, audit: true}
+	admin := users.User{ID: 9, Username: "admin", Role: users.RoleAdmin}
+
+	assign := func(id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/admin/expenses", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.SetPath("/admin/expenses/:id/owner")
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		users.SetCurrentUser(c, admin)
+		assert.NoError(t, h.AssignExpenseOwner(c))
+		return res
+	}
+	unowned := func() []Expenses {
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/admin/expenses/unowned", nil), res)
+		assert.NoError(t, h.GetUnownedExpenses(c))
+		var list []Expenses
+		json.Unmarshal(res.Body.Bytes(), &list)
+		return list
+	}
+
+	t.Run("Should list the expenses without owner", func(t *testing.T) {
+		// Act
+		list := unowned()
+
+		// Assert
+		if assert.Len(t, list, 1) {
+			assert.Equal(t, "rice", list[0].Title)
+		}
+	})
+
+	t.Run("Should reject a missing owner", func(t *testing.T) {
+		// Act
+		res := assign("1", `{}`)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+
+	t.Run("Should not take an expense from its owner", func(t *testing.T) {
+		// Act
+		res := assign("2", `{"owner_id": 3}`)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("Should give the expense to the user", func(t *testing.T) {
+		// Act
+		res := assign("1", fmt.Sprintf(`{"owner_id": %d}`, testUser.ID))
+		got, err := repo.Get(context.Background(), testUser.ID, "1")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Version)
+		assert.Empty(t, unowned())
+	})
+
+	t.Run("Should record the assignment as a revision by the admin", func(t *testing.T) {
+		// Act
+		revisions, err := repo.History(context.Background(), testUser.ID, "1")
+
+		// Assert
+		assert.NoError(t, err)
+		if assert.Len(t, revisions, 1) {
+			r := revisions[0]
+			assert.Equal(t, ActionUpdate, r.Action)
+			assert.Equal(t, admin.ID, r.ActorID)
+			assert.Equal(t, 0, r.Before.OwnerID)
+			assert.Equal(t, testUser.ID, r.After.OwnerID)
+		}
+	})
+}
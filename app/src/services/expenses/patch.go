@@ -0,0 +1,479 @@
+package expenses
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	MIMEMergePatch = "application/merge-patch+json"
+	MIMEJSONPatch  = "application/json-patch+json"
+)
+
+var errPatchTestFailed = errors.New("test operation failed")
+
+func (h *handler) PatchExpenseByID(c echo.Context) error {
+	expenseId := c.Param("id")
+	if expenseId == "" {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Param id is empty"},
+		)
+	}
+
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	ifMatch, ok, err := h.ifMatch(c)
+	if !ok {
+		return err
+	}
+
+	mediaType, _, err := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if err != nil || (mediaType != MIMEMergePatch && mediaType != MIMEJSONPatch && mediaType != echo.MIMEApplicationJSON) {
+		return c.JSON(
+			http.StatusUnsupportedMediaType,
+			apperr.ErrorResponse{Code: http.StatusUnsupportedMediaType, Message: "Content-Type must be application/merge-patch+json or application/json-patch+json"},
+		)
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Invalid request body"},
+		)
+	}
+
+	// The patch is applied inside the repository so the read and the write
+	// happen atomically. patchErr tells a bad patch apart from a storage
+	// failure.
+	var patchErr error
+	exp, err := h.repo.Patch(h.context(c), ownerID, expenseId, func(exp *Expenses) error {
+		if ifMatch != "" && !etagMatches(ifMatch, etag(*exp), false) {
+			patchErr = errVersionMismatch
+			return patchErr
+		}
+		if mediaType == MIMEJSONPatch {
+			patchErr = applyJSONPatch(exp, body)
+		} else {
+			patchErr = applyMergePatch(exp, body)
+		}
+		if patchErr == nil {
+			exp.defaultCurrency()
+			patchErr = validate(c, exp)
+		}
+		return patchErr
+	})
+
+	var verr *apperr.ValidationError
+	switch {
+	case patchErr == nil && err != nil:
+		return apperr.Respond(c, err)
+	case errors.Is(patchErr, errVersionMismatch):
+		return preconditionFailedResponse(c)
+	case errors.As(patchErr, &verr):
+		return validationErrorResponse(c, patchErr)
+	case errors.Is(patchErr, errPatchTestFailed):
+		return c.JSON(
+			http.StatusConflict,
+			apperr.ErrorResponse{Code: http.StatusConflict, Message: patchErr.Error()},
+		)
+	case patchErr != nil:
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: patchErr.Error()},
+		)
+	}
+
+	setETag(c, exp)
+	return c.JSON(http.StatusOK, h.written(c, exp))
+}
+
+// applyMergePatch applies an RFC 7396 merge patch to exp. Server managed
+// fields are never taken from the patch.
+func applyMergePatch(exp *Expenses, body []byte) error {
+	var patch interface{}
+	if err := unmarshalJSON(body, &patch); err != nil {
+		return fmt.Errorf("invalid merge patch: %w", err)
+	}
+	if _, ok := patch.(map[string]interface{}); !ok {
+		return errors.New("invalid merge patch: document must be an object")
+	}
+
+	return patchDocument(exp, func(doc interface{}) (interface{}, error) {
+		return mergePatch(doc, patch), nil
+	})
+}
+
+// applyJSONPatch applies an RFC 6902 JSON patch to exp. Server managed
+// fields are never taken from the patch.
+func applyJSONPatch(exp *Expenses, body []byte) error {
+	var ops []jsonPatchOperation
+	if err := json.Unmarshal(body, &ops); err != nil {
+		return fmt.Errorf("invalid json patch: %w", err)
+	}
+
+	return patchDocument(exp, func(doc interface{}) (interface{}, error) {
+		var err error
+		for i, op := range ops {
+			doc, err = op.apply(doc)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+		}
+		return doc, nil
+	})
+}
+
+func patchDocument(exp *Expenses, apply func(doc interface{}) (interface{}, error)) error {
+	raw, err := json.Marshal(exp)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := unmarshalJSON(raw, &doc); err != nil {
+		return err
+	}
+
+	doc, err = apply(doc)
+	if err != nil {
+		return err
+	}
+
+	raw, err = json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	patched := Expenses{}
+	if err := json.Unmarshal(raw, &patched); err != nil {
+		return fmt.Errorf("invalid patched document: %w", err)
+	}
+	patched.keepServerFields(*exp)
+	*exp = patched
+	return nil
+}
+
+// unmarshalJSON keeps numbers as json.Number so amounts survive the round
+// trip through a generic document without float rounding.
+func unmarshalJSON(data []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+	if d.More() {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+type jsonPatchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+func (o jsonPatchOperation) value() (interface{}, error) {
+	if o.Value == nil {
+		return nil, fmt.Errorf("%s requires a value", o.Op)
+	}
+	var v interface{}
+	err := unmarshalJSON(*o.Value, &v)
+	return v, err
+}
+
+func (o jsonPatchOperation) apply(doc interface{}) (interface{}, error) {
+	switch o.Op {
+	case "add":
+		v, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, o.Path, v)
+	case "remove":
+		doc, _, err := pointerRemove(doc, o.Path)
+		return doc, err
+	case "replace":
+		v, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+		doc, _, err = pointerRemove(doc, o.Path)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, o.Path, v)
+	case "move":
+		if strings.HasPrefix(o.Path, o.From+"/") {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		doc, v, err := pointerRemove(doc, o.From)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, o.Path, v)
+	case "copy":
+		v, err := pointerGet(doc, o.From)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := unmarshalJSON(raw, &v); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, o.Path, v)
+	case "test":
+		expected, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := pointerGet(doc, o.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(expected, actual) {
+			return nil, fmt.Errorf("%w at %s", errPatchTestFailed, o.Path)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", o.Op)
+}
+
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		tokens[i] = strings.ReplaceAll(token, "~0", "~")
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > length || (!allowEnd && i == length) || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return i, nil
+}
+
+func pointerGet(doc interface{}, path string) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %s does not exist", path)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("path %s does not exist", path)
+		}
+	}
+	return doc, nil
+}
+
+// pointerUpdate walks to the parent of path and replaces it with the value
+// returned by update, rebuilding the containers on the way back up.
+func pointerUpdate(doc interface{}, tokens []string, update func(parent interface{}, last string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return update(doc, tokens[0])
+	}
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("path /%s does not exist", strings.Join(tokens, "/"))
+		}
+		child, err := pointerUpdate(child, tokens[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		node[tokens[0]] = child
+		return node, nil
+	case []interface{}:
+		i, err := arrayIndex(tokens[0], len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		child, err := pointerUpdate(node[i], tokens[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+	}
+	return nil, fmt.Errorf("path /%s does not exist", strings.Join(tokens, "/"))
+}
+
+func pointerAdd(doc interface{}, path string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return pointerUpdate(doc, tokens, func(parent interface{}, last string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[last] = value
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(last, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("path %s does not exist", path)
+	})
+}
+
+func pointerRemove(doc interface{}, path string) (interface{}, interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+	var removed interface{}
+	doc, err = pointerUpdate(doc, tokens, func(parent interface{}, last string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			v, ok := node[last]
+			if !ok {
+				return nil, fmt.Errorf("path %s does not exist", path)
+			}
+			removed = v
+			delete(node, last)
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(last, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[i]
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("path %s does not exist", path)
+	})
+	return doc, removed, err
+}
+
+func jsonEqual(a, b interface{}) bool {
+	na, errA := normalizeJSON(a)
+	nb, errB := normalizeJSON(b)
+	return errA == nil && errB == nil && equalJSON(na, nb)
+}
+
+// normalizeJSON turns v into the generic document unmarshalJSON reads.
+func normalizeJSON(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	err = unmarshalJSON(raw, &doc)
+	return doc, err
+}
+
+// equalJSON compares two generic documents as RFC 6902 section 4.6 does:
+// numbers by value, so 79 equals 79.0, objects by their members whatever
+// their order and arrays element by element.
+func equalJSON(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		ra, okA := new(big.Rat).SetString(a.String())
+		rb, okB := new(big.Rat).SetString(b.String())
+		return okA && okB && ra.Cmp(rb) == 0
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			w, ok := b[k]
+			if !ok || !equalJSON(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalJSON(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,86 @@
+//go:build it
+
+package expenses
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchExpenseByID(t *testing.T) {
+	// setup echo server
+	e, settings, close := SetupServer(t)
+	PingServer()
+	t.Run("Should patch only the supplied fields", func(t *testing.T) {
+
+		// Arrange
+		createExpense := SeedExpense(t, settings)
+		body := `{"note": "paid"}`
+
+		// Act
+		var exp Expenses
+		res := Request(t, http.MethodPatch, Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%d", createExpense.ID)), strings.NewReader(body))
+		err := res.Decode(&exp)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, createExpense.ID, exp.ID)
+			assert.Equal(t, createExpense.Title, exp.Title)
+			assert.Equal(t, createExpense.Amount, exp.Amount)
+			assert.Equal(t, "paid", exp.Note)
+			assert.Equal(t, createExpense.Tags, exp.Tags)
+		}
+	})
+
+	t.Run("Should return not found error if the request expense id is not exist", func(t *testing.T) {
+
+		// Arrange
+		expenseId := "1000000"
+		expectedMessage := "Record not found"
+
+		// Act
+		var errRes apperr.ErrorResponse
+		res := Request(t, http.MethodPatch, Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%s", expenseId)), strings.NewReader(`{"note": "paid"}`))
+		err := res.Decode(&errRes)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNotFound, errRes.Code)
+			assert.Equal(t, expectedMessage, errRes.Message)
+		}
+	})
+
+	t.Run("Should accept json patch documents", func(t *testing.T) {
+
+		// Arrange
+		createExpense := SeedExpense(t, settings)
+		body := `[{"op": "replace", "path": "/title", "value": "rent"}]`
+		req, err := http.NewRequest(http.MethodPatch, Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%d", createExpense.ID)), strings.NewReader(body))
+		assert.NoError(t, err)
+		req.Header.Set(echo.HeaderContentType, MIMEJSONPatch)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+accessToken)
+
+		// Act
+		var exp Expenses
+		resp, err := http.DefaultClient.Do(req)
+		res := &Response{resp, err}
+		err = res.Decode(&exp)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, "rent", exp.Title)
+			assert.Equal(t, createExpense.Note, exp.Note)
+		}
+	})
+
+	// teardown echo server
+	TeardownServer(t, e, close)
+}
@@ -0,0 +1,301 @@
+//go:build unit
+
+package expenses
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockPatchSelect(mock sqlmock.Sqlmock, expenseID string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = (.+) FOR UPDATE").
+		WithArgs(expenseID, testUser.ID).
+		WillReturnRows(
+			mock.NewRows([]string{"ID", "Title", "Amount", "Currency", "Note", "Tags", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).
+				AddRow(
+					expenseID,
+					"strawberry smoothie",
+					79.00,
+					"THB",
+					"night market promotion discount 10 bath",
+					pq.Array([]string{"food", "beverage"}),
+					testTime, testTime, testTime, 1,
+				),
+		)
+}
+
+func TestPatchExpenseHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    string
+	}{
+		{
+			"Should merge patch only the supplied fields",
+			MIMEMergePatch,
+			`{"note": "x"}`,
+			"{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"currency\":\"THB\",\"note\":\"x\",\"tags\":[\"food\",\"beverage\"]" + testTimestamps + "}",
+		},
+		{
+			"Should treat application/json as merge patch",
+			echo.MIMEApplicationJSON,
+			`{"amount": 89, "tags": null}`,
+			"{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":89,\"currency\":\"THB\",\"note\":\"night market promotion discount 10 bath\",\"tags\":null" + testTimestamps + "}",
+		},
+		{
+			"Should apply json patch operations",
+			MIMEJSONPatch,
+			`[
+				{"op": "test", "path": "/title", "value": "strawberry smoothie"},
+				{"op": "replace", "path": "/title", "value": "apple smoothie"},
+				{"op": "remove", "path": "/tags/0"},
+				{"op": "add", "path": "/tags/-", "value": "drink"}
+			]`,
+			"{\"id\":1,\"title\":\"apple smoothie\",\"amount\":79,\"currency\":\"THB\",\"note\":\"night market promotion discount 10 bath\",\"tags\":[\"beverage\",\"drink\"]" + testTimestamps + "}",
+		},
+		{
+			"Should never change server managed fields",
+			MIMEMergePatch,
+			`{"id": 99, "created_at": "2030-01-01T00:00:00Z", "updated_at": "2030-01-01T00:00:00Z"}`,
+			"{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"currency\":\"THB\",\"note\":\"night market promotion discount 10 bath\",\"tags\":[\"food\",\"beverage\"]" + testTimestamps + "}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			expenseID := "1"
+			req := httptest.NewRequest(http.MethodPatch, "/expenses", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, tt.contentType)
+			res := httptest.NewRecorder()
+
+			db, mock, close := handlers.MockDatabase(t)
+			defer close()
+
+			mockPatchSelect(mock, expenseID)
+			mock.ExpectQuery("UPDATE expenses").WillReturnRows(mock.NewRows([]string{"spent_at", "updated_at", "version"}).AddRow(testTime, testTime, 2))
+			mock.ExpectCommit()
+
+			h := handler{repo: NewPostgresRepository(db)}
+			c := e.NewContext(req, res)
+			users.SetCurrentUser(c, testUser)
+			c.SetPath("/expenses/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(expenseID)
+
+			// Act
+			err := h.PatchExpenseByID(c)
+
+			// Assertions
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusOK, res.Code)
+				assert.Equal(t, tt.expected, strings.TrimSpace(res.Body.String()))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			}
+		})
+	}
+
+	t.Run("Should return conflict error if json patch test operation fails", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		expenseID := "1"
+		body := `[{"op": "test", "path": "/amount", "value": 1}]`
+		req := httptest.NewRequest(http.MethodPatch, "/expenses", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, MIMEJSONPatch)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mockPatchSelect(mock, expenseID)
+		mock.ExpectRollback()
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(expenseID)
+		expected := "{\"statusCode\":409,\"message\":\"operation 0: test operation failed at /amount\"}"
+
+		// Act
+		err := h.PatchExpenseByID(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusConflict, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+
+	t.Run("Should return unprocessable entity error if patched field has wrong type", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		expenseID := "1"
+		body := `{"amount": "79"}`
+		req := httptest.NewRequest(http.MethodPatch, "/expenses", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, MIMEMergePatch)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mockPatchSelect(mock, expenseID)
+		mock.ExpectRollback()
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(expenseID)
+
+		// Act
+		err := h.PatchExpenseByID(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+			assert.Regexp(t, "invalid patched document", strings.TrimSpace(res.Body.String()))
+		}
+	})
+
+	t.Run("Should return unsupported media type error if content type is not a patch", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPatch, "/expenses", strings.NewReader("note=x"))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		res := httptest.NewRecorder()
+
+		db, _, close := handlers.MockDatabase(t)
+		defer close()
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		// Act
+		err := h.PatchExpenseByID(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnsupportedMediaType, res.Code)
+		}
+	})
+
+	t.Run("Should return not found error if the request expense id is not exist", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		expenseID := "9"
+		req := httptest.NewRequest(http.MethodPatch, "/expenses", strings.NewReader(`{"note": "x"}`))
+		req.Header.Set(echo.HeaderContentType, MIMEMergePatch)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = (.+) FOR UPDATE").
+			WithArgs(expenseID, testUser.ID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(expenseID)
+		expected := "{\"statusCode\":404,\"message\":\"Record not found\"}"
+
+		// Act
+		err := h.PatchExpenseByID(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNotFound, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+}
+
+func TestJSONPatchOperations(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		expected Expenses
+	}{
+		{
+			"Should move a value",
+			`[{"op": "move", "from": "/title", "path": "/note"}]`,
+			Expenses{ID: 1, Note: "tea", Tags: []string{"a", "b"}},
+		},
+		{
+			"Should copy a value",
+			`[{"op": "copy", "from": "/tags/1", "path": "/tags/0"}]`,
+			Expenses{ID: 1, Title: "tea", Tags: []string{"b", "a", "b"}},
+		},
+		{
+			"Should test numbers by value and arrays by element",
+			`[{"op": "test", "path": "/amount", "value": 0.0}, {"op": "test", "path": "/tags", "value": ["a", "b"]}]`,
+			Expenses{ID: 1, Title: "tea", Tags: []string{"a", "b"}},
+		},
+		{
+			"Should unescape pointer tokens",
+			`[{"op": "add", "path": "/ti~1tle", "value": "x"}, {"op": "remove", "path": "/ti~1tle"}]`,
+			Expenses{ID: 1, Title: "tea", Tags: []string{"a", "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := Expenses{ID: 1, Title: "tea", Tags: []string{"a", "b"}}
+
+			err := applyJSONPatch(&exp, []byte(tt.patch))
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected, exp)
+			}
+		})
+	}
+
+	t.Run("Should match a stored amount written with a fraction or exponent", func(t *testing.T) {
+		exp := Expenses{ID: 1, Title: "tea", Amount: 790000}
+
+		err := applyJSONPatch(&exp, []byte(`[{"op": "test", "path": "/amount", "value": 79.0}, {"op": "test", "path": "/amount", "value": 7.9e1}]`))
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Should fail a test against arrays in another order", func(t *testing.T) {
+		exp := Expenses{ID: 1, Title: "tea", Tags: []string{"a", "b"}}
+
+		err := applyJSONPatch(&exp, []byte(`[{"op": "test", "path": "/tags", "value": ["b", "a"]}]`))
+
+		assert.ErrorIs(t, err, errPatchTestFailed)
+	})
+
+	t.Run("Should return error if path does not exist", func(t *testing.T) {
+		exp := Expenses{ID: 1, Title: "tea"}
+
+		err := applyJSONPatch(&exp, []byte(`[{"op": "remove", "path": "/missing"}]`))
+
+		assert.EqualError(t, err, "operation 0: path /missing does not exist")
+	})
+}
@@ -0,0 +1,740 @@
+package expenses
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/lib/pq"
+)
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) ExpenseRepository {
+	return &postgresRepository{db}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// scanExpense reads the columns id, title, amount, currency, note, tags,
+// spent_at, created_at, updated_at and version in that order.
+func scanExpense(row scanner, e *Expenses) error {
+	return row.Scan(&e.ID, &e.Title, &e.Amount, &e.Currency, &e.Note, pq.Array(&e.Tags), &e.SpentAt, &e.CreatedAt, &e.UpdatedAt, &e.Version)
+}
+
+// nullTime turns an unset time into NULL so the query can fall back to a
+// default.
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+const insertSQL = `
+	INSERT INTO
+		expenses (title, amount, currency, note, tags, owner_id, spent_at)
+	VALUES
+		($1, $2, $3, $4, $5, $6, COALESCE($7, NOW()))
+	RETURNING id, spent_at, created_at, updated_at, version;
+	`
+
+func insert(ctx context.Context, q rowQuerier, exp *Expenses) error {
+	row := q.QueryRowContext(ctx, insertSQL, exp.Title, exp.Amount, exp.Currency, exp.Note, pq.Array(&exp.Tags), exp.OwnerID, nullTime(exp.SpentAt))
+	return row.Scan(&exp.ID, &exp.SpentAt, &exp.CreatedAt, &exp.UpdatedAt, &exp.Version)
+}
+
+// write runs fn in a transaction when ctx is audited, so the revision fn
+// records is stored with the change, and on the database otherwise.
+func (r *postgresRepository) write(ctx context.Context, fn func(q rowQuerier) error) error {
+	if _, ok := auditFrom(ctx); !ok {
+		return fn(r.db)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockForAudit loads and locks the expense a write is about to change, so
+// its revision knows the state before. It loads nothing when ctx is not
+// audited. An expense stored before owners were kept has OwnerID 0.
+func lockForAudit(ctx context.Context, q rowQuerier, where string, args ...interface{}) (*Expenses, error) {
+	if _, ok := auditFrom(ctx); !ok {
+		return nil, nil
+	}
+	var e Expenses
+	var owner sql.NullInt64
+	sql := `
+	SELECT id, title, amount, currency, note, tags, spent_at, created_at, updated_at, version, owner_id
+	FROM expenses
+	WHERE ` + where + `
+	FOR UPDATE
+	`
+	err := q.QueryRowContext(ctx, sql, args...).Scan(&e.ID, &e.Title, &e.Amount, &e.Currency, &e.Note, pq.Array(&e.Tags),
+		&e.SpentAt, &e.CreatedAt, &e.UpdatedAt, &e.Version, &owner)
+	e.OwnerID = int(owner.Int64)
+	return &e, err
+}
+
+// record stores a revision of a change when ctx is audited.
+func record(ctx context.Context, q rowQuerier, action string, before, after *Expenses) error {
+	a, ok := auditFrom(ctx)
+	if !ok {
+		return nil
+	}
+	rev := newRevision(a, action, before, after)
+	snapshots := make([]interface{}, 2)
+	for i, s := range []*Snapshot{rev.Before, rev.After} {
+		raw, err := encodeSnapshot(s)
+		if err != nil {
+			return err
+		}
+		if raw != nil {
+			snapshots[i] = string(raw)
+		}
+	}
+	sql := `
+	INSERT INTO
+		expense_revisions (expense_id, owner_id, action, actor_id, request_id, reverts, before, after)
+	VALUES
+		($1, NULLIF($2, 0), $3, $4, $5, NULLIF($6, 0), $7::jsonb, $8::jsonb)
+	RETURNING id
+	`
+	return q.QueryRowContext(ctx, sql, rev.ExpenseID, rev.OwnerID, rev.Action, rev.ActorID, rev.RequestID, rev.Reverts,
+		snapshots[0], snapshots[1]).Scan(&rev.ID)
+}
+
+func (r *postgresRepository) Create(ctx context.Context, exp *Expenses) error {
+	return r.write(ctx, func(q rowQuerier) error {
+		if err := insert(ctx, q, exp); err != nil {
+			return err
+		}
+		return record(ctx, q, ActionCreate, nil, exp)
+	})
+}
+
+func (r *postgresRepository) CreateMany(ctx context.Context, exps []Expenses) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := range exps {
+		if err := insert(ctx, tx, &exps[i]); err != nil {
+			return err
+		}
+		if err := record(ctx, tx, ActionCreate, nil, &exps[i]); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *postgresRepository) Imported(ctx context.Context, owner int, keys []string) (map[string]bool, error) {
+	imported := map[string]bool{}
+	sql := `
+	SELECT external_id
+	FROM imported_transactions
+	WHERE owner_id = $1 AND external_id = ANY($2)
+	`
+	rows, err := r.db.QueryContext(ctx, sql, owner, pq.Array(keys))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		imported[key] = true
+	}
+	return imported, rows.Err()
+}
+
+// CreateImported claims each key and inserts its expense in one statement,
+// so concurrent imports of overlapping statements add every transaction
+// once.
+func (r *postgresRepository) CreateImported(ctx context.Context, keys []string, exps []Expenses) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insert := `
+	WITH claim AS (
+		INSERT INTO
+			imported_transactions (owner_id, external_id)
+		VALUES
+			($6, $8)
+		ON CONFLICT DO NOTHING
+		RETURNING owner_id
+	)
+	INSERT INTO
+		expenses (title, amount, currency, note, tags, owner_id, spent_at)
+	SELECT
+		$1::text, $2::numeric, $3::text, $4::text, $5::text[], owner_id, COALESCE($7::timestamptz, NOW())
+	FROM claim
+	RETURNING id, spent_at, created_at, updated_at, version;
+	`
+	for i := range exps {
+		exp := &exps[i]
+		row := tx.QueryRowContext(ctx, insert, exp.Title, exp.Amount, exp.Currency, exp.Note, pq.Array(&exp.Tags), exp.OwnerID,
+			nullTime(exp.SpentAt), keys[i])
+		err := row.Scan(&exp.ID, &exp.SpentAt, &exp.CreatedAt, &exp.UpdatedAt, &exp.Version)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := record(ctx, tx, ActionCreate, nil, exp); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *postgresRepository) Get(ctx context.Context, owner int, id string) (Expenses, error) {
+	e := Expenses{OwnerID: owner}
+	sql := `
+	SELECT id, title, amount, currency, note, tags, spent_at, created_at, updated_at, version
+	FROM expenses
+	WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL
+	`
+	err := scanExpense(r.db.QueryRowContext(ctx, sql, id, owner), &e)
+	return e, apperr.ByID(err)
+}
+
+// Update replaces the expense. An unset SpentAt keeps the stored one.
+func (r *postgresRepository) Update(ctx context.Context, owner int, id string, exp *Expenses) error {
+	expected := exp.Version
+	exp.OwnerID = owner
+	err := r.write(ctx, func(q rowQuerier) error {
+		before, err := lockForAudit(ctx, q, "id = $1 AND owner_id = $2 AND deleted_at IS NULL", id, owner)
+		if err != nil {
+			return err
+		}
+
+		update := `
+		UPDATE
+			expenses SET title = $1, amount = $2, currency = $3, note = $4, tags = $5,
+			spent_at = COALESCE($6, spent_at), updated_at = NOW(), version = version + 1
+		WHERE
+			id = $7 AND owner_id = $8 AND deleted_at IS NULL AND ($9 = 0 OR version = $9)
+		RETURNING id, spent_at, created_at, updated_at, version
+		`
+		row := q.QueryRowContext(ctx, update, exp.Title, exp.Amount, exp.Currency, exp.Note, pq.Array(&exp.Tags), nullTime(exp.SpentAt), id, owner, expected)
+		err = row.Scan(&exp.ID, &exp.SpentAt, &exp.CreatedAt, &exp.UpdatedAt, &exp.Version)
+		if errors.Is(err, sql.ErrNoRows) && expected != 0 {
+			// Tell a missing expense apart from one that has moved on.
+			if _, getErr := r.Get(ctx, owner, id); getErr == nil {
+				return errVersionMismatch
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return record(ctx, q, ActionUpdate, before, exp)
+	})
+	return apperr.ByID(err)
+}
+
+func (r *postgresRepository) Patch(ctx context.Context, owner int, id string, apply func(exp *Expenses) error) (Expenses, error) {
+	exp := Expenses{OwnerID: owner}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return exp, err
+	}
+	defer tx.Rollback()
+
+	sql := `
+	SELECT id, title, amount, currency, note, tags, spent_at, created_at, updated_at, version
+	FROM expenses
+	WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL
+	FOR UPDATE
+	`
+	err = scanExpense(tx.QueryRowContext(ctx, sql, id, owner), &exp)
+	if err != nil {
+		return exp, apperr.ByID(err)
+	}
+	before := clone(exp)
+
+	if err := apply(&exp); err != nil {
+		return exp, err
+	}
+
+	sql = `
+	UPDATE
+		expenses SET title = $1, amount = $2, currency = $3, note = $4, tags = $5,
+		spent_at = COALESCE($6, spent_at), updated_at = NOW(), version = version + 1
+	WHERE
+		id = $7
+	RETURNING spent_at, updated_at, version
+	`
+	row := tx.QueryRowContext(ctx, sql, exp.Title, exp.Amount, exp.Currency, exp.Note, pq.Array(&exp.Tags), nullTime(exp.SpentAt), exp.ID)
+	if err := row.Scan(&exp.SpentAt, &exp.UpdatedAt, &exp.Version); err != nil {
+		return exp, err
+	}
+	if err := record(ctx, tx, ActionUpdate, &before, &exp); err != nil {
+		return exp, err
+	}
+
+	return exp, tx.Commit()
+}
+
+func (r *postgresRepository) List(ctx context.Context, q listQuery) ([]Expenses, error) {
+	var expenses []Expenses
+	err := r.Stream(ctx, q, func(e Expenses) error {
+		expenses = append(expenses, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+// Stream hands each row to fn straight from the cursor so exports never hold
+// the whole result in memory.
+func (r *postgresRepository) Stream(ctx context.Context, q listQuery, fn func(Expenses) error) error {
+	sql, args := q.sql()
+	rows, err := r.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := Expenses{OwnerID: q.OwnerID}
+		if err := scanExpense(rows, &e); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *postgresRepository) Count(ctx context.Context, f listFilter) (int, error) {
+	var total int
+	sql, args := listQuery{listFilter: f}.countSQL()
+	err := r.db.QueryRowContext(ctx, sql, args...).Scan(&total)
+	return total, err
+}
+
+func (r *postgresRepository) summaryRows(ctx context.Context, q summaryQuery, grouped bool) ([]SummaryRow, error) {
+	result := []SummaryRow{}
+
+	sql, args := q.sql(grouped)
+	rows, err := r.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row SummaryRow
+		var dest []interface{}
+		if grouped && q.ByTag {
+			row.Tag = new(string)
+			dest = append(dest, row.Tag)
+		}
+		if grouped && q.Period != "" {
+			dest = append(dest, &row.Period)
+		}
+		dest = append(dest, &row.Currency, &row.Count, &row.Total, &row.Average, &row.Min, &row.Max)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+func (r *postgresRepository) Summary(ctx context.Context, q summaryQuery) (Summary, error) {
+	var err error
+	summary := Summary{GroupBy: q.groupBy(), Groups: []SummaryRow{}}
+
+	summary.Totals, err = r.summaryRows(ctx, q, false)
+	if err != nil || len(summary.GroupBy) == 0 {
+		return summary, err
+	}
+	summary.Groups, err = r.summaryRows(ctx, q, true)
+	return summary, err
+}
+
+func (r *postgresRepository) Spent(ctx context.Context, owner int, tag, currency string, from, to time.Time) (money.Amount, error) {
+	var spent money.Amount
+	sql := `
+	SELECT COALESCE(SUM(amount), 0)
+	FROM expenses
+	WHERE owner_id = $1 AND deleted_at IS NULL AND $2 = ANY(tags)
+		AND currency = $3 AND spent_at >= $4 AND spent_at < $5
+	`
+	err := r.db.QueryRowContext(ctx, sql, owner, tag, currency, from, to).Scan(&spent)
+	return spent, err
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, owner int, id string) error {
+	err := r.write(ctx, func(q rowQuerier) error {
+		before, err := lockForAudit(ctx, q, "id = $1 AND owner_id = $2 AND deleted_at IS NULL", id, owner)
+		if err != nil {
+			return err
+		}
+
+		sql := `
+		UPDATE
+			expenses SET deleted_at = NOW()
+		WHERE
+			id = $1 AND owner_id = $2 AND deleted_at IS NULL
+		RETURNING id
+		`
+		var deleted int
+		if err := q.QueryRowContext(ctx, sql, id, owner).Scan(&deleted); err != nil {
+			return err
+		}
+		return record(ctx, q, ActionDelete, before, nil)
+	})
+	return apperr.ByID(err)
+}
+
+func (r *postgresRepository) Restore(ctx context.Context, owner int, id string) (Expenses, error) {
+	e := Expenses{OwnerID: owner}
+	err := r.write(ctx, func(q rowQuerier) error {
+		sql := `
+		UPDATE
+			expenses SET deleted_at = NULL
+		WHERE
+			id = $1 AND owner_id = $2 AND deleted_at IS NOT NULL
+		RETURNING id, title, amount, currency, note, tags, spent_at, created_at, updated_at, version
+		`
+		if err := scanExpense(q.QueryRowContext(ctx, sql, id, owner), &e); err != nil {
+			return err
+		}
+		return record(ctx, q, ActionRestore, nil, &e)
+	})
+	return e, apperr.ByID(err)
+}
+
+func (r *postgresRepository) Touch(ctx context.Context, owner int, id string) error {
+	sql := `
+	UPDATE
+		expenses SET version = version + 1
+	WHERE
+		id = $1 AND owner_id = $2 AND deleted_at IS NULL
+	RETURNING id
+	`
+	var touched int
+	return apperr.ByID(r.db.QueryRowContext(ctx, sql, id, owner).Scan(&touched))
+}
+
+func (r *postgresRepository) Purge(ctx context.Context, id string) error {
+	err := r.write(ctx, func(q rowQuerier) error {
+		before, err := lockForAudit(ctx, q, "id = $1", id)
+		if err != nil {
+			return err
+		}
+
+		sql := `
+		DELETE FROM
+			expenses
+		WHERE
+			id = $1
+		RETURNING id
+		`
+		var purged int
+		if err := q.QueryRowContext(ctx, sql, id).Scan(&purged); err != nil {
+			return err
+		}
+		return record(ctx, q, ActionPurge, before, nil)
+	})
+	return apperr.ByID(err)
+}
+
+func (r *postgresRepository) Unowned(ctx context.Context) ([]Expenses, error) {
+	expenses := []Expenses{}
+	sql := `
+	SELECT id, title, amount, currency, note, tags, spent_at, created_at, updated_at, version
+	FROM expenses
+	WHERE owner_id IS NULL AND deleted_at IS NULL
+	ORDER BY id
+	`
+	rows, err := r.db.QueryContext(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e Expenses
+		if err := scanExpense(rows, &e); err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, e)
+	}
+	return expenses, rows.Err()
+}
+
+func (r *postgresRepository) Assign(ctx context.Context, id string, owner int) (Expenses, error) {
+	e := Expenses{OwnerID: owner}
+	err := r.write(ctx, func(q rowQuerier) error {
+		before, err := lockForAudit(ctx, q, "id = $1 AND owner_id IS NULL AND deleted_at IS NULL", id)
+		if err != nil {
+			return err
+		}
+
+		sql := `
+		UPDATE
+			expenses SET owner_id = $1, updated_at = NOW(), version = version + 1
+		WHERE
+			id = $2 AND owner_id IS NULL AND deleted_at IS NULL
+		RETURNING id, title, amount, currency, note, tags, spent_at, created_at, updated_at, version
+		`
+		if err := scanExpense(q.QueryRowContext(ctx, sql, owner, id), &e); err != nil {
+			return err
+		}
+		return record(ctx, q, ActionUpdate, before, &e)
+	})
+	return e, apperr.ByID(err)
+}
+
+// History lists the revisions of the expense. An expense without any, such
+// as one created before revisions were kept, has an empty history as long as
+// it still exists.
+func (r *postgresRepository) History(ctx context.Context, owner int, id string) ([]Revision, error) {
+	revisions := []Revision{}
+	sql := `
+	SELECT id, expense_id, owner_id, action, actor_id, request_id, COALESCE(reverts, 0), before, after, created_at
+	FROM expense_revisions
+	WHERE expense_id = $1 AND owner_id = $2
+	ORDER BY id
+	`
+	rows, err := r.db.QueryContext(ctx, sql, id, owner)
+	if err != nil {
+		return nil, apperr.ByID(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rev Revision
+		var before, after []byte
+		err := rows.Scan(&rev.ID, &rev.ExpenseID, &rev.OwnerID, &rev.Action, &rev.ActorID, &rev.RequestID, &rev.Reverts,
+			&before, &after, &rev.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if rev.Before, err = decodeSnapshot(before); err != nil {
+			return nil, err
+		}
+		if rev.After, err = decodeSnapshot(after); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		exists := `
+		SELECT id
+		FROM expenses
+		WHERE id = $1 AND owner_id = $2
+		`
+		var found int
+		if err := r.db.QueryRowContext(ctx, exists, id, owner).Scan(&found); err != nil {
+			return nil, apperr.ByID(err)
+		}
+	}
+	return revisions, nil
+}
+
+type postgresAttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAttachmentRepository(db *sql.DB) AttachmentRepository {
+	return &postgresAttachmentRepository{db}
+}
+
+const attachmentColumns = `id, expense_id, filename, content_type, size, storage_key, created_at`
+
+func scanAttachment(row scanner, a *Attachment) error {
+	return row.Scan(&a.ID, &a.ExpenseID, &a.Filename, &a.ContentType, &a.Size, &a.Key, &a.CreatedAt)
+}
+
+func (r *postgresAttachmentRepository) Create(ctx context.Context, a *Attachment) error {
+	sql := `
+	INSERT INTO
+		attachments (expense_id, filename, content_type, size, storage_key)
+	VALUES
+		($1, $2, $3, $4, $5)
+	RETURNING id, created_at;
+	`
+	return r.db.QueryRowContext(ctx, sql, a.ExpenseID, a.Filename, a.ContentType, a.Size, a.Key).Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *postgresAttachmentRepository) List(ctx context.Context, expenseID int) ([]Attachment, error) {
+	list := []Attachment{}
+	sql := `
+	SELECT ` + attachmentColumns + `
+	FROM attachments
+	WHERE expense_id = $1
+	ORDER BY id
+	`
+	rows, err := r.db.QueryContext(ctx, sql, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a Attachment
+		if err := scanAttachment(rows, &a); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+func (r *postgresAttachmentRepository) Get(ctx context.Context, expenseID int, id string) (Attachment, error) {
+	var a Attachment
+	sql := `
+	SELECT ` + attachmentColumns + `
+	FROM attachments
+	WHERE id = $1 AND expense_id = $2
+	`
+	err := scanAttachment(r.db.QueryRowContext(ctx, sql, id, expenseID), &a)
+	return a, apperr.ByID(err)
+}
+
+func (r *postgresAttachmentRepository) Delete(ctx context.Context, expenseID int, id string) (Attachment, error) {
+	var a Attachment
+	sql := `
+	DELETE FROM
+		attachments
+	WHERE
+		id = $1 AND expense_id = $2
+	RETURNING ` + attachmentColumns
+	err := scanAttachment(r.db.QueryRowContext(ctx, sql, id, expenseID), &a)
+	return a, apperr.ByID(err)
+}
+
+type postgresIdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &postgresIdempotencyRepository{db}
+}
+
+// reserveAttempts bounds how often Reserve retries when the key it found
+// claimed is released before it can be read.
+const reserveAttempts = 3
+
+// Reserve removes the expired keys of owner first, so an expired key is
+// claimed afresh.
+func (r *postgresIdempotencyRepository) Reserve(ctx context.Context, owner int, key, hash string, ttl time.Duration) (IdempotentRequest, bool, error) {
+	expire := `
+	DELETE FROM
+		idempotency_keys
+	WHERE
+		owner_id = $1 AND expires_at <= NOW()
+	`
+	if _, err := r.db.ExecContext(ctx, expire, owner); err != nil {
+		return IdempotentRequest{}, false, err
+	}
+
+	claim := `
+	INSERT INTO
+		idempotency_keys (owner_id, key, request_hash, expires_at)
+	VALUES
+		($1, $2, $3, NOW() + $4::double precision * INTERVAL '1 millisecond')
+	ON CONFLICT (owner_id, key) DO NOTHING
+	RETURNING owner_id
+	`
+	find := `
+	SELECT request_hash, COALESCE(status_code, 0), response_headers, response
+	FROM idempotency_keys
+	WHERE owner_id = $1 AND key = $2
+	`
+	for i := 0; i < reserveAttempts; i++ {
+		var claimed int
+		err := r.db.QueryRowContext(ctx, claim, owner, key, hash, ttl.Milliseconds()).Scan(&claimed)
+		if err == nil {
+			return IdempotentRequest{Hash: hash}, true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return IdempotentRequest{}, false, err
+		}
+
+		var req IdempotentRequest
+		var header []byte
+		err = r.db.QueryRowContext(ctx, find, owner, key).Scan(&req.Hash, &req.Status, &header, &req.Body)
+		if err == nil && header != nil {
+			err = json.Unmarshal(header, &req.Header)
+		}
+		if err == nil {
+			return req, false, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return IdempotentRequest{}, false, err
+		}
+	}
+	return IdempotentRequest{}, false, errors.New("idempotency key kept changing hands")
+}
+
+func (r *postgresIdempotencyRepository) Complete(ctx context.Context, owner int, key string, status int, header http.Header, body []byte) error {
+	raw, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	sql := `
+	UPDATE
+		idempotency_keys
+	SET
+		status_code = $3, response_headers = $4::jsonb, response = $5
+	WHERE
+		owner_id = $1 AND key = $2
+	`
+	_, err = r.db.ExecContext(ctx, sql, owner, key, status, string(raw), body)
+	return err
+}
+
+func (r *postgresIdempotencyRepository) Release(ctx context.Context, owner int, key string) error {
+	sql := `
+	DELETE FROM
+		idempotency_keys
+	WHERE
+		owner_id = $1 AND key = $2
+	`
+	_, err := r.db.ExecContext(ctx, sql, owner, key)
+	return err
+}
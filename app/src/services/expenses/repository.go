@@ -0,0 +1,110 @@
+package expenses
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/RTae/assessment/app/src/money"
+)
+
+// errVersionMismatch is returned by a conditional update of an expense that
+// has been changed since the version it names.
+var errVersionMismatch = errors.New("expense version does not match")
+
+// ExpenseRepository stores expenses. Ids are taken as the raw path parameter
+// so each implementation decides how to reject a malformed one, as long as
+// apperr.AsError classifies it as InvalidID. Every lookup by id is scoped to owner;
+// missing, deleted or someone else's expenses are all reported with
+// sql.ErrNoRows. Writes done with a context from WithAudit
+// store a Revision in the same transaction as the change.
+type ExpenseRepository interface {
+	// Create stores exp for exp.OwnerID.
+	Create(ctx context.Context, exp *Expenses) error
+	// CreateMany stores every expense in exps in a single transaction,
+	// filling in their ids and timestamps.
+	CreateMany(ctx context.Context, exps []Expenses) error
+	// Imported returns which of keys owner has already imported from a
+	// statement.
+	Imported(ctx context.Context, owner int, keys []string) (map[string]bool, error)
+	// CreateImported stores exps in a single transaction, each one only if
+	// its owner has not imported keys[i] before. Skipped expenses keep a
+	// zero ID. A key stays imported after its expense is deleted.
+	CreateImported(ctx context.Context, keys []string, exps []Expenses) error
+	Get(ctx context.Context, owner int, id string) (Expenses, error)
+	// Update replaces the expense and bumps its version. When exp.Version is
+	// set, only that version is replaced and any other is reported with
+	// errVersionMismatch.
+	Update(ctx context.Context, owner int, id string, exp *Expenses) error
+	// Patch loads the expense, lets apply modify it and stores the result
+	// atomically, bumping its version.
+	Patch(ctx context.Context, owner int, id string, apply func(exp *Expenses) error) (Expenses, error)
+	// List returns the expenses matching q, fetching one extra row past
+	// q.Limit when paginating so the caller can tell whether a next page
+	// exists.
+	List(ctx context.Context, q listQuery) ([]Expenses, error)
+	// Stream calls fn with every expense matching q in order as it is read,
+	// stopping at the first error fn returns.
+	Stream(ctx context.Context, q listQuery, fn func(Expenses) error) error
+	Count(ctx context.Context, f listFilter) (int, error)
+	// Summary aggregates the expenses matching q per currency, overall and
+	// per group.
+	Summary(ctx context.Context, q summaryQuery) (Summary, error)
+	// Spent sums the live expenses of owner in currency tagged with tag and
+	// spent from from (inclusive) to to (exclusive). It lets the repository
+	// serve as the ledger budgets are checked against.
+	Spent(ctx context.Context, owner int, tag, currency string, from, to time.Time) (money.Amount, error)
+	Delete(ctx context.Context, owner int, id string) error
+	Restore(ctx context.Context, owner int, id string) (Expenses, error)
+	// Touch bumps the version of the expense after a change stored outside
+	// it, such as its attachments, so cached copies are revalidated.
+	Touch(ctx context.Context, owner int, id string) error
+	// Purge removes the expense whoever owns it.
+	Purge(ctx context.Context, id string) error
+	// Unowned lists the live expenses stored before owners were kept, which
+	// no user sees until an admin assigns them.
+	Unowned(ctx context.Context) ([]Expenses, error)
+	// Assign gives a live expense without an owner to owner. An expense that
+	// already has one is reported with sql.ErrNoRows.
+	Assign(ctx context.Context, id string, owner int) (Expenses, error)
+	// History returns the revisions of an expense of owner oldest first,
+	// whether the expense is live, deleted or purged.
+	History(ctx context.Context, owner int, id string) ([]Revision, error)
+}
+
+// AttachmentRepository stores the metadata of the files attached to
+// expenses; the files themselves live in a storage.Storage. Callers check
+// that the expense belongs to them first. Missing attachments are reported
+// with sql.ErrNoRows.
+type AttachmentRepository interface {
+	Create(ctx context.Context, a *Attachment) error
+	List(ctx context.Context, expenseID int) ([]Attachment, error)
+	Get(ctx context.Context, expenseID int, id string) (Attachment, error)
+	// Delete removes the attachment and returns it so its file can be
+	// removed too.
+	Delete(ctx context.Context, expenseID int, id string) (Attachment, error)
+}
+
+// IdempotentRequest is a request remembered under an Idempotency-Key.
+// Status, Header and Body are the response sent for it, Status is 0 while it
+// is still being handled. Header only holds the headers a replay sends again.
+type IdempotentRequest struct {
+	Hash   string
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyRepository remembers the responses to requests sent with an
+// Idempotency-Key so a retry is answered without repeating the request. Keys
+// are scoped to their owner.
+type IdempotencyRepository interface {
+	// Reserve claims key for owner for ttl. When the key is already claimed
+	// and has not expired, it returns the earlier request and false instead.
+	Reserve(ctx context.Context, owner int, key, hash string, ttl time.Duration) (IdempotentRequest, bool, error)
+	// Complete stores the response to a reserved key.
+	Complete(ctx context.Context, owner int, key string, status int, header http.Header, body []byte) error
+	// Release drops a reserved key so the request can be sent again.
+	Release(ctx context.Context, owner int, key string) error
+}
@@ -0,0 +1,248 @@
+package expenses
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/statement"
+	"github.com/labstack/echo/v4"
+)
+
+const maxStatementBytes = 10 << 20
+
+const (
+	// RowDuplicate marks a transaction imported by an earlier statement or
+	// seen before in the same one.
+	RowDuplicate = "duplicate"
+	// RowSkipped marks a credit, which is money coming in rather than an
+	// expense.
+	RowSkipped = "skipped"
+)
+
+type StatementRow struct {
+	// FITID is the id the bank gave the transaction, or the one derived
+	// from its content for QIF.
+	FITID   string              `json:"fitid"`
+	Status  string              `json:"status"`
+	ID      int                 `json:"id,omitempty"`
+	Expense *Expenses           `json:"expense,omitempty"`
+	Errors  []apperr.FieldError `json:"errors,omitempty"`
+}
+
+// StatementReport tells what became of each transaction of a statement. In
+// a dry run nothing is stored; Imported counts the transactions that would
+// be and their rows are marked valid.
+type StatementReport struct {
+	Format     string         `json:"format"`
+	DryRun     bool           `json:"dry_run"`
+	Imported   int            `json:"imported"`
+	Duplicates int            `json:"duplicates"`
+	Skipped    int            `json:"skipped"`
+	Rejected   int            `json:"rejected"`
+	Rows       []StatementRow `json:"rows"`
+}
+
+type statementOptions struct {
+	Format   string
+	DryRun   bool
+	Currency string
+	DayFirst bool
+}
+
+func parseStatementOptions(c echo.Context) (statementOptions, error) {
+	o := statementOptions{Currency: money.DefaultCurrency}
+
+	switch raw := strings.ToLower(c.QueryParam("format")); raw {
+	case "":
+	case statement.FormatOFX, "qfx":
+		o.Format = statement.FormatOFX
+	case statement.FormatQIF:
+		o.Format = statement.FormatQIF
+	default:
+		return o, errors.New("Query param format must be ofx, qfx or qif")
+	}
+
+	if raw := c.QueryParam("dry_run"); raw != "" {
+		var err error
+		o.DryRun, err = strconv.ParseBool(raw)
+		if err != nil {
+			return o, errors.New("Query param dry_run must be true or false")
+		}
+	}
+
+	if raw := c.QueryParam("currency"); raw != "" {
+		o.Currency = strings.ToUpper(raw)
+	}
+
+	switch c.QueryParam("date_order") {
+	case "", "mdy":
+	case "dmy":
+		o.DayFirst = true
+	default:
+		return o, errors.New("Query param date_order must be mdy or dmy")
+	}
+	return o, nil
+}
+
+// statementExpense maps a debit to an expense. The payee becomes the title,
+// falling back to the memo when the bank leaves it out.
+func statementExpense(t statement.Transaction, currency string) Expenses {
+	exp := Expenses{
+		Title:    t.Payee,
+		Amount:   -t.Amount,
+		Currency: t.Currency,
+		Note:     t.Memo,
+		SpentAt:  t.Date,
+	}
+	if exp.Title == "" {
+		exp.Title = t.Memo
+	}
+	if exp.Currency == "" {
+		exp.Currency = currency
+	}
+	return exp
+}
+
+// ImportStatement reads an OFX, QFX or QIF bank statement from the body or
+// the file field of a multipart form and stores its debits as expenses.
+// Credits are skipped, and transactions already imported are recognized by
+// their FITID and reported as duplicates. With dry_run nothing is stored
+// and the report previews the import.
+func (h *handler) ImportStatement(c echo.Context) error {
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	o, err := parseStatementOptions(c)
+	if err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: err.Error()},
+		)
+	}
+
+	body, err := importBody(c)
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			apperr.ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()},
+		)
+	}
+	defer body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(body, maxStatementBytes+1))
+	if err != nil {
+		c.Logger().Warnf("request %s: reading statement: %v", apperr.RequestID(c), err)
+		return c.JSON(
+			http.StatusBadRequest,
+			apperr.ErrorResponse{Code: http.StatusBadRequest, Message: "Invalid request body"},
+		)
+	}
+	if len(raw) > maxStatementBytes {
+		return c.JSON(
+			http.StatusRequestEntityTooLarge,
+			apperr.ErrorResponse{Code: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("Statement must be at most %d MB", maxStatementBytes>>20)},
+		)
+	}
+
+	st, err := statement.Parse(bytes.NewReader(raw), o.Format, statement.Options{Location: h.zone(), DayFirst: o.DayFirst})
+	if errors.Is(err, statement.ErrUnknownFormat) {
+		return c.JSON(
+			http.StatusBadRequest,
+			apperr.ErrorResponse{Code: http.StatusBadRequest, Message: "Statement must be in OFX, QFX or QIF format"},
+		)
+	}
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			apperr.ErrorResponse{Code: http.StatusBadRequest, Message: fmt.Sprintf("Invalid statement: %v", err)},
+		)
+	}
+	if len(st.Transactions) > maxImportRows {
+		return c.JSON(
+			http.StatusRequestEntityTooLarge,
+			apperr.ErrorResponse{Code: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("Statement must have at most %d transactions", maxImportRows)},
+		)
+	}
+
+	keys := make([]string, len(st.Transactions))
+	for i, t := range st.Transactions {
+		keys[i] = st.Key(t)
+	}
+	imported, err := h.repo.Imported(c.Request().Context(), ownerID, keys)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	report := StatementReport{Format: st.Format, DryRun: o.DryRun, Rows: []StatementRow{}}
+	var valid []Expenses
+	var validKeys []string
+	var validRows []int
+	for i, t := range st.Transactions {
+		row := StatementRow{FITID: t.ID}
+		if !t.Debit() {
+			row.Status = RowSkipped
+			report.Skipped++
+			report.Rows = append(report.Rows, row)
+			continue
+		}
+
+		exp := statementExpense(t, o.Currency)
+		exp.OwnerID = ownerID
+		var verr *apperr.ValidationError
+		switch {
+		case errors.As(validate(c, &exp), &verr):
+			row.Status = RowRejected
+			row.Errors = verr.Details
+			report.Rejected++
+		case imported[keys[i]]:
+			row.Status = RowDuplicate
+			report.Duplicates++
+		default:
+			// A repeat later in the file is a duplicate of this one.
+			imported[keys[i]] = true
+			row.Status = RowValid
+			valid = append(valid, exp)
+			validKeys = append(validKeys, keys[i])
+			validRows = append(validRows, len(report.Rows))
+		}
+		h.localize(&exp)
+		row.Expense = &exp
+		report.Rows = append(report.Rows, row)
+	}
+
+	if !o.DryRun && len(valid) > 0 {
+		if err := h.repo.CreateImported(h.context(c), validKeys, valid); err != nil {
+			return apperr.Respond(c, err)
+		}
+		recordCreated(sourceStatement, valid...)
+	}
+	for i, row := range validRows {
+		exp := valid[i]
+		switch {
+		case o.DryRun:
+			report.Imported++
+			continue
+		case exp.ID == 0:
+			// Another import stored the transaction in the meantime.
+			report.Rows[row].Status = RowDuplicate
+			report.Duplicates++
+			continue
+		}
+		h.localize(&exp)
+		report.Rows[row].Status = RowImported
+		report.Rows[row].ID = exp.ID
+		report.Rows[row].Expense = &exp
+		report.Imported++
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
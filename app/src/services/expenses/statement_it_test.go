@@ -0,0 +1,67 @@
+//go:build it
+
+package expenses
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportStatement(t *testing.T) {
+	// setup echo server
+	e, settings, close := SetupServer(t)
+	PingServer()
+
+	body := "OFXHEADER:100\n\n<OFX><STMTRS><CURDEF>THB<BANKACCTFROM><ACCTID>it-statement</BANKACCTFROM><BANKTRANLIST>" +
+		"<STMTTRN><DTPOSTED>20240102<TRNAMT>-50<FITID>IT1<NAME>statement rice</STMTTRN>" +
+		"<STMTTRN><DTPOSTED>20240103<TRNAMT>100<FITID>IT2<NAME>refund</STMTTRN>" +
+		"</BANKTRANLIST></STMTRS></OFX>"
+
+	t.Run("Should preview without storing in a dry run", func(t *testing.T) {
+		// Arrange
+		var report StatementReport
+
+		// Act
+		res := Request(t, http.MethodPost, Uri(fmt.Sprint(settings.Port), "expenses", "import", "statement?dry_run=true"), strings.NewReader(body))
+		err := res.Decode(&report)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, 1, report.Imported)
+			assert.Equal(t, RowValid, report.Rows[0].Status)
+			assert.Equal(t, RowSkipped, report.Rows[1].Status)
+		}
+	})
+
+	t.Run("Should import a debit once", func(t *testing.T) {
+		// Arrange
+		var first, second StatementReport
+
+		// Act
+		err := Request(t, http.MethodPost, Uri(fmt.Sprint(settings.Port), "expenses", "import", "statement"), strings.NewReader(body)).Decode(&first)
+		assert.NoError(t, err)
+		err = Request(t, http.MethodPost, Uri(fmt.Sprint(settings.Port), "expenses", "import", "statement"), strings.NewReader(body)).Decode(&second)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, first.Imported)
+			assert.Equal(t, RowImported, first.Rows[0].Status)
+			assert.Equal(t, 0, second.Imported)
+			assert.Equal(t, RowDuplicate, second.Rows[0].Status)
+
+			var exp Expenses
+			err := Request(t, http.MethodGet, Uri(fmt.Sprint(settings.Port), "expenses", fmt.Sprint(first.Rows[0].ID)), nil).Decode(&exp)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "statement rice", exp.Title)
+			}
+		}
+	})
+
+	// teardown echo server
+	TeardownServer(t, e, close)
+}
@@ -0,0 +1,205 @@
+//go:build unit
+
+package expenses
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+const testStatement = `OFXHEADER:100
+DATA:OFXSGML
+
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>THB
+<BANKACCTFROM><BANKID>004<ACCTID>123456</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240102030405<TRNAMT>-50.25<FITID>T1<NAME>Tops<MEMO>groceries</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240103<TRNAMT>15000<FITID>T2<NAME>Salary</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240104<TRNAMT>-0.001<FITID>T3<NAME>Fee</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240105<TRNAMT>-30<FITID>T4<MEMO>ATM withdrawal</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240105<TRNAMT>-30<FITID>T4<MEMO>ATM withdrawal</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>
+`
+
+func statementRequest(t *testing.T, h handler, query, body string) (*httptest.ResponseRecorder, StatementReport) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/expenses/import/statement"+query, strings.NewReader(body))
+	res := httptest.NewRecorder()
+	c := e.NewContext(req, res)
+	users.SetCurrentUser(c, testUser)
+
+	err := h.ImportStatement(c)
+	assert.NoError(t, err)
+
+	var report StatementReport
+	json.Unmarshal(res.Body.Bytes(), &report)
+	return res, report
+}
+
+func TestImportStatementHandler(t *testing.T) {
+	t.Run("Should preview the import in a dry run without storing anything", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		h := handler{repo: repo}
+
+		// Act
+		res, report := statementRequest(t, h, "?dry_run=true", testStatement)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "ofx", report.Format)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, 2, report.Imported)
+		assert.Equal(t, 1, report.Skipped)
+		assert.Equal(t, 1, report.Rejected)
+		assert.Equal(t, 1, report.Duplicates)
+		statuses := []string{}
+		for _, row := range report.Rows {
+			statuses = append(statuses, row.FITID+" "+row.Status)
+		}
+		assert.Equal(t, []string{"T1 valid", "T2 skipped", "T3 rejected", "T4 valid", "T4 duplicate"}, statuses)
+		assert.Equal(t, &Expenses{Title: "Tops", Amount: 502500, Currency: "THB", Note: "groceries", SpentAt: testTime}, report.Rows[0].Expense)
+		assert.Equal(t, "ATM withdrawal", report.Rows[3].Expense.Title)
+		assert.Equal(t, []apperr.FieldError{{Field: "amount", Message: "must have at most 2 decimal places for THB"}}, report.Rows[2].Errors)
+
+		total, _ := repo.Count(context.Background(), listFilter{OwnerID: testUser.ID})
+		assert.Equal(t, 0, total)
+	})
+
+	t.Run("Should store debits once across overlapping statements", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		h := handler{repo: repo}
+
+		// Act
+		_, first := statementRequest(t, h, "", testStatement)
+		_, second := statementRequest(t, h, "?format=qfx", testStatement)
+
+		// Assert
+		assert.Equal(t, 2, first.Imported)
+		assert.Equal(t, RowImported, first.Rows[0].Status)
+		assert.Equal(t, 1, first.Rows[0].ID)
+		assert.Equal(t, 0, second.Imported)
+		assert.Equal(t, 3, second.Duplicates)
+		total, _ := repo.Count(context.Background(), listFilter{OwnerID: testUser.ID})
+		assert.Equal(t, 2, total)
+		tops, _ := repo.Get(context.Background(), testUser.ID, "1")
+		assert.Equal(t, money.Amount(502500), tops.Amount)
+		assert.True(t, testTime.Equal(tops.SpentAt))
+	})
+
+	t.Run("Should import QIF with the given currency and date order", func(t *testing.T) {
+		// Arrange
+		repo := NewMemoryRepository()
+		h := handler{repo: repo}
+		body := "!Type:Bank\nD02/01/2024\nT-12.50\nPCafe\n^\n"
+
+		// Act
+		res, report := statementRequest(t, h, "?currency=usd&date_order=dmy", body)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "qif", report.Format)
+		if assert.Equal(t, 1, report.Imported) {
+			exp := report.Rows[0].Expense
+			assert.Equal(t, "USD", exp.Currency)
+			assert.Equal(t, "2024-01-02", exp.SpentAt.Format("2006-01-02"))
+		}
+	})
+
+	tests := []struct {
+		name     string
+		query    string
+		body     string
+		code     int
+		expected string
+	}{
+		{"Should reject an unknown format", "?format=csv", testStatement, http.StatusUnprocessableEntity, `{"statusCode":422,"message":"Query param format must be ofx, qfx or qif"}`},
+		{"Should reject an invalid dry_run", "?dry_run=maybe", testStatement, http.StatusUnprocessableEntity, `{"statusCode":422,"message":"Query param dry_run must be true or false"}`},
+		{"Should reject an invalid date_order", "?date_order=ymd", testStatement, http.StatusUnprocessableEntity, `{"statusCode":422,"message":"Query param date_order must be mdy or dmy"}`},
+		{"Should reject content that is not a statement", "", "title,amount\n", http.StatusBadRequest, `{"statusCode":400,"message":"Statement must be in OFX, QFX or QIF format"}`},
+		{"Should reject a malformed statement", "?format=qif", "!Type:Bank\nDsoon\n^\n", http.StatusBadRequest, `{"statusCode":400,"message":"Invalid statement: line 2: date \"soon\" is not a valid date"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			h := handler{repo: NewMemoryRepository()}
+
+			// Act
+			res, _ := statementRequest(t, h, tt.query, tt.body)
+
+			// Assert
+			assert.Equal(t, tt.code, res.Code)
+			assert.Equal(t, tt.expected, strings.TrimSpace(res.Body.String()))
+		})
+	}
+}
+
+func TestPostgresCreateImported(t *testing.T) {
+	t.Run("Should leave the expenses of claimed keys without id", func(t *testing.T) {
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("WITH claim AS").WithArgs("rice", money.Amount(500000), "THB", "", sqlmock.AnyArg(), testUser.ID, testTime, "ofx:1:A").
+			WillReturnRows(mock.NewRows([]string{"id", "spent_at", "created_at", "updated_at", "version"}).AddRow(7, testTime, testTime, testTime, 1))
+		mock.ExpectQuery("WITH claim AS").WithArgs("tea", money.Amount(300000), "THB", "", sqlmock.AnyArg(), testUser.ID, testTime, "ofx:1:B").
+			WillReturnRows(mock.NewRows([]string{"id", "spent_at", "created_at", "updated_at", "version"}))
+		mock.ExpectCommit()
+
+		exps := []Expenses{
+			{Title: "rice", Amount: 500000, Currency: "THB", OwnerID: testUser.ID, SpentAt: testTime},
+			{Title: "tea", Amount: 300000, Currency: "THB", OwnerID: testUser.ID, SpentAt: testTime},
+		}
+		err := NewPostgresRepository(db).CreateImported(context.Background(), []string{"ofx:1:A", "ofx:1:B"}, exps)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, 7, exps[0].ID)
+			assert.Equal(t, 0, exps[1].ID)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("Should roll back when an insert fails", func(t *testing.T) {
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("WITH claim AS").WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		err := NewPostgresRepository(db).CreateImported(context.Background(), []string{"ofx:1:A"}, []Expenses{{Title: "rice"}})
+
+		assert.EqualError(t, err, "connection reset")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresImported(t *testing.T) {
+	db, mock, close := handlers.MockDatabase(t)
+	defer close()
+
+	mock.ExpectQuery("SELECT external_id FROM imported_transactions").WithArgs(testUser.ID, sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"external_id"}).AddRow("ofx:1:A"))
+
+	imported, err := NewPostgresRepository(db).Imported(context.Background(), testUser.ID, []string{"ofx:1:A", "ofx:1:B"})
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]bool{"ofx:1:A": true}, imported)
+	}
+}
@@ -0,0 +1,149 @@
+package expenses
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/labstack/echo/v4"
+)
+
+// periodFormats maps a period to how it is labelled in Go and in Postgres.
+var periodFormats = map[string]struct{ layout, pg string }{
+	"month": {"2006-01", "YYYY-MM"},
+	"week":  {"", `IYYY-"W"IW`},
+	"day":   {"2006-01-02", "YYYY-MM-DD"},
+}
+
+// SummaryRow aggregates the expenses of one currency. Tag and Period are
+// only set when the summary is grouped by them; untagged expenses are
+// reported under the empty tag.
+type SummaryRow struct {
+	Tag      *string      `json:"tag,omitempty"`
+	Period   string       `json:"period,omitempty"`
+	Currency string       `json:"currency"`
+	Count    int          `json:"count"`
+	Total    money.Amount `json:"total"`
+	Average  money.Amount `json:"average"`
+	Min      money.Amount `json:"min"`
+	Max      money.Amount `json:"max"`
+}
+
+type Summary struct {
+	GroupBy []string     `json:"group_by"`
+	Totals  []SummaryRow `json:"totals"`
+	Groups  []SummaryRow `json:"groups"`
+}
+
+type summaryQuery struct {
+	listFilter
+	ByTag    bool
+	Period   string
+	Location *time.Location
+}
+
+func (q summaryQuery) groupBy() []string {
+	groupBy := []string{}
+	if q.ByTag {
+		groupBy = append(groupBy, "tag")
+	}
+	if q.Period != "" {
+		groupBy = append(groupBy, q.Period)
+	}
+	return groupBy
+}
+
+func parseSummaryQuery(c echo.Context, loc *time.Location) (summaryQuery, error) {
+	q := summaryQuery{Location: loc}
+
+	var err error
+	q.listFilter, err = parseListFilter(c, loc)
+	if err != nil {
+		return q, err
+	}
+
+	errGroupBy := errors.New("Query param group_by must be tag and at most one of month, week, day")
+	for _, raw := range c.QueryParams()["group_by"] {
+		for _, group := range strings.Split(raw, ",") {
+			switch group = strings.TrimSpace(group); {
+			case group == "tag" && !q.ByTag:
+				q.ByTag = true
+			case periodFormats[group].pg != "" && q.Period == "":
+				q.Period = group
+			default:
+				return q, errGroupBy
+			}
+		}
+	}
+	return q, nil
+}
+
+// label formats t as the period it falls in.
+func (q summaryQuery) label(t time.Time) string {
+	t = t.In(q.Location)
+	if q.Period == "week" {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	}
+	return t.Format(periodFormats[q.Period].layout)
+}
+
+// sql renders the grouped query. With grouped false it only groups by
+// currency, which gives the totals.
+func (q summaryQuery) sql(grouped bool) (string, []interface{}) {
+	where, args := q.where(nil)
+
+	var keys []string
+	from := "expenses"
+	if grouped && q.ByTag {
+		keys = append(keys, "COALESCE(t.tag, '')")
+		from += " LEFT JOIN LATERAL unnest(tags) AS t(tag) ON TRUE"
+	}
+	if grouped && q.Period != "" {
+		args = append(args, q.Location.String())
+		keys = append(keys, fmt.Sprintf("to_char(spent_at AT TIME ZONE $%d, '%s')", len(args), periodFormats[q.Period].pg))
+	}
+	keys = append(keys, "currency")
+
+	// Group by position so the period placeholder appears only once.
+	positions := make([]string, len(keys))
+	for i := range keys {
+		positions[i] = fmt.Sprint(i + 1)
+	}
+	groupBy := strings.Join(positions, ", ")
+
+	return fmt.Sprintf(`
+	SELECT %s, COUNT(*), SUM(amount), ROUND(AVG(amount), 4), MIN(amount), MAX(amount)
+	FROM %s
+	WHERE %s
+	GROUP BY %s
+	ORDER BY %s
+	`, strings.Join(keys, ", "), from, where, groupBy, groupBy), args
+}
+
+func (h *handler) GetExpensesSummary(c echo.Context) error {
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	q, err := parseSummaryQuery(c, h.zone())
+	if err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: err.Error()},
+		)
+	}
+	q.OwnerID = ownerID
+
+	summary, err := h.repo.Summary(c.Request().Context(), q)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,168 @@
+//go:build unit
+
+package expenses
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSummaryQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		groupBy []string
+		wantErr bool
+	}{
+		{"Should summarize without groups", "", []string{}, false},
+		{"Should group by tag and month", "group_by=tag,month", []string{"tag", "month"}, false},
+		{"Should accept repeated group_by", "group_by=week&group_by=tag", []string{"tag", "week"}, false},
+		{"Should reject two periods", "group_by=month,day", nil, true},
+		{"Should reject duplicated tag", "group_by=tag,tag", nil, true},
+		{"Should reject unknown group", "group_by=note", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/expenses/summary?"+tt.query, nil)
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			// Act
+			q, err := parseSummaryQuery(c, time.UTC)
+
+			// Assert
+			if tt.wantErr {
+				assert.EqualError(t, err, "Query param group_by must be tag and at most one of month, week, day")
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.groupBy, q.groupBy())
+			}
+		})
+	}
+}
+
+func TestGetExpensesSummaryHandler(t *testing.T) {
+	t.Run("Should return totals and groups by tag and month", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		bangkok, _ := time.LoadLocation("Asia/Bangkok")
+		req := httptest.NewRequest(http.MethodGet, "/expenses/summary?group_by=tag,month&tag=food", nil)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("SELECT currency, COUNT(.+) FROM expenses WHERE owner_id = (.+) GROUP BY 1 ORDER BY 1").
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "count", "sum", "avg", "min", "max"}).
+				AddRow("THB", 3, "300.0000", "100.0000", "50.0000", "150.0000"))
+		mock.ExpectQuery("SELECT COALESCE\\(t.tag, ''\\), to_char\\(spent_at AT TIME ZONE \\$3, 'YYYY-MM'\\), currency, (.+) FROM expenses LEFT JOIN LATERAL unnest\\(tags\\) AS t\\(tag\\) ON TRUE WHERE (.+) GROUP BY 1, 2, 3 ORDER BY 1, 2, 3").
+			WithArgs(testUser.ID, sqlmock.AnyArg(), "Asia/Bangkok").
+			WillReturnRows(sqlmock.NewRows([]string{"tag", "period", "currency", "count", "sum", "avg", "min", "max"}).
+				AddRow("food", "2024-01", "THB", 2, "200.0000", "100.0000", "50.0000", "150.0000").
+				AddRow("food", "2024-02", "THB", 1, "100.0000", "100.0000", "100.0000", "100.0000"))
+
+		h := handler{repo: NewPostgresRepository(db), location: bangkok}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		expected := `{"group_by":["tag","month"],` +
+			`"totals":[{"currency":"THB","count":3,"total":300,"average":100,"min":50,"max":150}],` +
+			`"groups":[{"tag":"food","period":"2024-01","currency":"THB","count":2,"total":200,"average":100,"min":50,"max":150},` +
+			`{"tag":"food","period":"2024-02","currency":"THB","count":1,"total":100,"average":100,"min":100,"max":100}]}`
+
+		// Act
+		err := h.GetExpensesSummary(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("Should return unprocessable entity error if group_by is unknown", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/expenses/summary?group_by=year", nil)
+		res := httptest.NewRecorder()
+
+		h := handler{repo: NewMemoryRepository()}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		expected := "{\"statusCode\":422,\"message\":\"Query param group_by must be tag and at most one of month, week, day\"}"
+
+		// Act
+		err := h.GetExpensesSummary(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+}
+
+func TestMemorySummary(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	seedMemory(t, repo, testUser.ID,
+		Expenses{Title: "rice", Amount: 100000, Currency: "THB", Tags: []string{"food"}, SpentAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Expenses{Title: "tea", Amount: 150001, Currency: "THB", Tags: []string{"food", "beverage"}, SpentAt: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)},
+		Expenses{Title: "taxi", Amount: 2000000, Currency: "THB", SpentAt: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)},
+		Expenses{Title: "coffee", Amount: 45000, Currency: "USD", Tags: []string{"beverage"}, SpentAt: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)},
+	)
+
+	t.Run("Should total every currency without groups", func(t *testing.T) {
+		// Act
+		summary, err := repo.Summary(ctx, summaryQuery{listFilter: listFilter{OwnerID: testUser.ID}, Location: time.UTC})
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, []SummaryRow{
+				{Currency: "THB", Count: 3, Total: 2250001, Average: 750000, Min: 100000, Max: 2000000},
+				{Currency: "USD", Count: 1, Total: 45000, Average: 45000, Min: 45000, Max: 45000},
+			}, summary.Totals)
+			assert.Empty(t, summary.Groups)
+		}
+	})
+
+	t.Run("Should group by tag and ISO week", func(t *testing.T) {
+		// Arrange
+		min := money.Amount(100000)
+		q := summaryQuery{
+			listFilter: listFilter{OwnerID: testUser.ID, MinAmount: &min},
+			ByTag:      true,
+			Period:     "week",
+			Location:   time.UTC,
+		}
+		untagged, food, beverage := "", "food", "beverage"
+
+		// Act
+		summary, err := repo.Summary(ctx, q)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, []SummaryRow{
+				{Tag: &untagged, Period: "2024-W02", Currency: "THB", Count: 1, Total: 2000000, Average: 2000000, Min: 2000000, Max: 2000000},
+				{Tag: &beverage, Period: "2024-W02", Currency: "THB", Count: 1, Total: 150001, Average: 150001, Min: 150001, Max: 150001},
+				{Tag: &food, Period: "2024-W01", Currency: "THB", Count: 1, Total: 100000, Average: 100000, Min: 100000, Max: 100000},
+				{Tag: &food, Period: "2024-W02", Currency: "THB", Count: 1, Total: 150001, Average: 150001, Min: 150001, Max: 150001},
+			}, summary.Groups)
+			assert.Equal(t, []SummaryRow{
+				{Currency: "THB", Count: 3, Total: 2250001, Average: 750000, Min: 100000, Max: 2000000},
+			}, summary.Totals)
+		}
+	})
+}
@@ -1,11 +1,11 @@
 package expenses
 
 import (
+	"errors"
 	"net/http"
-	"regexp"
 
+	"github.com/RTae/assessment/app/src/apperr"
 	"github.com/labstack/echo/v4"
-	"github.com/lib/pq"
 )
 
 func (h *handler) UpdateExpenseByID(c echo.Context) error {
@@ -14,59 +14,53 @@ func (h *handler) UpdateExpenseByID(c echo.Context) error {
 	if expenseId == "" {
 		return c.JSON(
 			http.StatusUnprocessableEntity,
-			ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Param id is empty"},
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Param id is empty"},
 		)
 	}
 
+	ownerID, errRes := owner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	ifMatch, ok, err := h.ifMatch(c)
+	if !ok {
+		return err
+	}
+
 	if err := c.Bind(exp); err != nil {
 		return c.JSON(
 			http.StatusUnprocessableEntity,
-			ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Invalid request body"},
+			apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Invalid request body"},
 		)
 	}
 
-	sql := `
-	UPDATE 
-		expenses SET title = $1, amount = $2, note = $3, tags = $4
-	WHERE
-		id = $5
-	RETURNING id
-	`
-	row := h.db.QueryRow(sql, exp.Title, exp.Amount, exp.Note, pq.Array(&exp.Tags), expenseId)
+	exp.defaultCurrency()
+	if err := validate(c, exp); err != nil {
+		return validationErrorResponse(c, err)
+	}
 
-	err := row.Scan(&exp.ID)
-	if err != nil {
-		match, errMatch := regexp.MatchString("invalid input syntax", err.Error())
-		if match {
-			return c.JSON(
-				http.StatusUnprocessableEntity,
-				ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Param id must be integer"},
-			)
-		}
-		if errMatch != nil {
-			return c.JSON(
-				http.StatusInternalServerError,
-				ErrorResponse{Code: http.StatusInternalServerError, Message: err.Error()},
-			)
+	// The update is conditional on the version that matched, so a change
+	// made in between still fails the precondition.
+	if ifMatch != "" && ifMatch != "*" {
+		current, err := h.repo.Get(c.Request().Context(), ownerID, expenseId)
+		if err != nil {
+			return apperr.Respond(c, err)
 		}
-		match, errMatch = regexp.MatchString("no rows in result set", err.Error())
-		if match {
-			return c.JSON(
-				http.StatusNotFound,
-				ErrorResponse{Code: http.StatusNotFound, Message: "Record not found"},
-			)
+		if !etagMatches(ifMatch, etag(current), false) {
+			return preconditionFailedResponse(c)
 		}
-		if errMatch != nil {
-			return c.JSON(
-				http.StatusInternalServerError,
-				ErrorResponse{Code: http.StatusInternalServerError, Message: err.Error()},
-			)
-		}
-		return c.JSON(
-			http.StatusInternalServerError,
-			ErrorResponse{Code: http.StatusInternalServerError, Message: err.Error()},
-		)
+		exp.Version = current.Version
+	}
+
+	err = h.repo.Update(h.context(c), ownerID, expenseId, exp)
+	if errors.Is(err, errVersionMismatch) {
+		return preconditionFailedResponse(c)
+	}
+	if err != nil {
+		return apperr.Respond(c, err)
 	}
 
-	return c.JSON(http.StatusOK, exp)
+	setETag(c, *exp)
+	return c.JSON(http.StatusOK, h.written(c, *exp))
 }
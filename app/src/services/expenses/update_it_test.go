@@ -8,6 +8,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,7 +39,7 @@ func TestUpdateExpenseByID(t *testing.T) {
 			assert.Equal(t, http.StatusOK, res.StatusCode)
 			assert.Equal(t, exp.ID, createExpense.ID)
 			assert.Equal(t, exp.Title, "apple smoothie")
-			assert.Equal(t, exp.Amount, float32(89))
+			assert.Equal(t, exp.Amount, money.Amount(890000))
 			assert.Equal(t, exp.Note, "no discount")
 			assert.Equal(t, exp.Tags, []string{"beverage"})
 		}
@@ -56,7 +58,7 @@ func TestUpdateExpenseByID(t *testing.T) {
 		expectedMessage := "Param id must be integer"
 
 		// Act
-		var errRes ErrorResponse
+		var errRes apperr.ErrorResponse
 		res := Request(t, http.MethodPut, Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%s", expenseId)), strings.NewReader(body))
 		err := res.Decode(&errRes)
 
@@ -80,7 +82,7 @@ func TestUpdateExpenseByID(t *testing.T) {
 		expectedMessage := "Record not found"
 
 		// Act
-		var errRes ErrorResponse
+		var errRes apperr.ErrorResponse
 		res := Request(t, http.MethodPut, Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%s", expenseId)), strings.NewReader(body))
 		err := res.Decode(&errRes)
 
@@ -104,7 +106,7 @@ func TestUpdateExpenseByID(t *testing.T) {
 		expectedMessage := "Invalid request body"
 
 		// Act
-		var errRes ErrorResponse
+		var errRes apperr.ErrorResponse
 		res := Request(t, http.MethodPut, Uri(fmt.Sprint(settings.Port), fmt.Sprintf("expenses/%s", expenseId)), strings.NewReader(body))
 		err := res.Decode(&errRes)
 
@@ -3,7 +3,7 @@
 package expenses
 
 import (
-	"errors"
+	"database/sql"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -11,7 +11,9 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/services/users"
 	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -33,16 +35,17 @@ func TestUpdateExpense(t *testing.T) {
 		db, mock, close := handlers.MockDatabase(t)
 		defer close()
 
-		resultMockRow := mock.NewRows([]string{"ID"}).AddRow(updateExpenseID)
+		resultMockRow := mock.NewRows([]string{"ID", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).AddRow(updateExpenseID, testTime, testTime, testTime, 1)
 		mock.ExpectQuery("UPDATE expenses").
 			WillReturnRows(resultMockRow)
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
 		c.SetPath("/expense/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(updateExpenseID)
-		expected := "{\"id\":3,\"title\":\"apple smoothie\",\"amount\":89,\"note\":\"no discount\",\"tags\":[\"beverage\"]}"
+		expected := "{\"id\":3,\"title\":\"apple smoothie\",\"amount\":89,\"currency\":\"THB\",\"note\":\"no discount\",\"tags\":[\"beverage\"]" + testTimestamps + "}"
 
 		// Act
 		err := h.UpdateExpenseByID(c)
@@ -55,6 +58,41 @@ func TestUpdateExpense(t *testing.T) {
 
 	})
 
+	t.Run("Should return bad request error if expense is not valid", func(t *testing.T) {
+		// Arrange
+		body := `{
+			"title": "apple smoothie",
+			"amount": 0,
+			"note": "no discount", 
+			"tags": ["beverage"]
+		}`
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/expense", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		db, _, close := handlers.MockDatabase(t)
+		defer close()
+
+		h := handler{repo: NewPostgresRepository(db)}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		c.SetPath("/expense/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("3")
+		expected := "{\"statusCode\":400,\"message\":\"Validation failed\",\"details\":[{\"field\":\"amount\",\"message\":\"must be greater than 0\"}]}"
+
+		// Act
+		err := h.UpdateExpenseByID(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+
+	})
+
 	t.Run("Should return unprocessable entity error if expense id is empty", func(t *testing.T) {
 		// Arrange
 		updateExpenseID := ""
@@ -72,8 +110,9 @@ func TestUpdateExpense(t *testing.T) {
 		db, _, close := handlers.MockDatabase(t)
 		defer close()
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
 		c.SetPath("/expense/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(updateExpenseID)
@@ -107,13 +146,14 @@ func TestUpdateExpense(t *testing.T) {
 		db, mock, close := handlers.MockDatabase(t)
 		defer close()
 
-		resultMockRow := mock.NewRows([]string{"ID"}).AddRow(updateExpenseID)
+		resultMockRow := mock.NewRows([]string{"ID", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).AddRow(updateExpenseID, testTime, testTime, testTime, 1)
 		mock.ExpectQuery("UPDATE expenses").
 			WillReturnRows(resultMockRow).
-			WillReturnError(errors.New("invalid input syntax"))
+			WillReturnError(&pq.Error{Code: "22P02", Message: "invalid input syntax for type integer"})
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
 		c.SetPath("/expense/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(updateExpenseID)
@@ -147,13 +187,14 @@ func TestUpdateExpense(t *testing.T) {
 		db, mock, close := handlers.MockDatabase(t)
 		defer close()
 
-		resultMockRow := mock.NewRows([]string{"ID"}).AddRow(updateExpenseID)
+		resultMockRow := mock.NewRows([]string{"ID", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).AddRow(updateExpenseID, testTime, testTime, testTime, 1)
 		mock.ExpectQuery("UPDATE expenses").
 			WillReturnRows(resultMockRow).
-			WillReturnError(errors.New("no rows in result set"))
+			WillReturnError(sql.ErrNoRows)
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
 		c.SetPath("/expense/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(updateExpenseID)
@@ -187,17 +228,18 @@ func TestUpdateExpense(t *testing.T) {
 		db, mock, close := handlers.MockDatabase(t)
 		defer close()
 
-		resultMockRow := mock.NewRows([]string{"ID"}).AddRow(updateExpenseID)
+		resultMockRow := mock.NewRows([]string{"ID", "SpentAt", "CreatedAt", "UpdatedAt", "Version"}).AddRow(updateExpenseID, testTime, testTime, testTime, 1)
 		mock.ExpectQuery("UPDATE expenses").
 			WillReturnRows(resultMockRow).
 			WillReturnError(sqlmock.ErrCancelled)
 
-		h := handler{db}
+		h := handler{repo: NewPostgresRepository(db)}
 		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
 		c.SetPath("/expense/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(updateExpenseID)
-		expected := "{\"statusCode\":500,\"message\":\"canceling query due to user request\"}"
+		expected := "{\"statusCode\":500,\"message\":\"Internal Server Error\"}"
 
 		// Act
 		err := h.UpdateExpenseByID(c)
@@ -0,0 +1,117 @@
+package expenses
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/labstack/echo/v4"
+)
+
+// Validator checks an Expenses payload against the configured limits. It
+// satisfies echo.Validator so it can be registered on the server and swapped
+// for another implementation.
+type Validator struct {
+	MaxTitleLength int
+	MaxNoteLength  int
+	MaxTags        int
+	MaxTagLength   int
+}
+
+func NewValidator() *Validator {
+	return &Validator{
+		MaxTitleLength: 255,
+		MaxNoteLength:  1000,
+		MaxTags:        10,
+		MaxTagLength:   30,
+	}
+}
+
+func (v *Validator) Validate(i interface{}) error {
+	var exp *Expenses
+	switch e := i.(type) {
+	case *Expenses:
+		exp = e
+	case Expenses:
+		exp = &e
+	default:
+		return nil
+	}
+
+	var details []apperr.FieldError
+	add := func(field, format string, args ...interface{}) {
+		details = append(details, apperr.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	title := strings.TrimSpace(exp.Title)
+	if title == "" {
+		add("title", "is required")
+	} else if utf8.RuneCountInString(title) > v.MaxTitleLength {
+		add("title", "must be at most %d characters", v.MaxTitleLength)
+	}
+
+	minorUnits, knownCurrency := money.MinorUnits(exp.Currency)
+	if exp.Amount <= 0 {
+		add("amount", "must be greater than 0")
+	} else if knownCurrency && exp.Amount.Decimals() > minorUnits {
+		add("amount", "must have at most %d decimal places for %s", minorUnits, exp.Currency)
+	}
+	if !knownCurrency {
+		add("currency", "must be a supported ISO 4217 code")
+	}
+
+	if utf8.RuneCountInString(exp.Note) > v.MaxNoteLength {
+		add("note", "must be at most %d characters", v.MaxNoteLength)
+	}
+
+	if len(exp.Tags) > v.MaxTags {
+		add("tags", "must have at most %d tags", v.MaxTags)
+	}
+	seen := map[string]bool{}
+	for i, tag := range exp.Tags {
+		field := fmt.Sprintf("tags[%d]", i)
+		switch {
+		case strings.TrimSpace(tag) == "":
+			add(field, "must not be empty")
+		case utf8.RuneCountInString(tag) > v.MaxTagLength:
+			add(field, "must be at most %d characters", v.MaxTagLength)
+		case seen[tag]:
+			add(field, "is duplicated")
+		}
+		seen[tag] = true
+	}
+
+	if len(details) > 0 {
+		return &apperr.ValidationError{Details: details}
+	}
+	return nil
+}
+
+var defaultValidator = NewValidator()
+
+// validate runs the validator registered on echo, falling back to the
+// default rules when none is registered.
+func validate(c echo.Context, i interface{}) error {
+	if c.Echo().Validator == nil {
+		return defaultValidator.Validate(i)
+	}
+	return c.Validate(i)
+}
+
+func validationErrorResponse(c echo.Context, err error) error {
+	var verr *apperr.ValidationError
+	if errors.As(err, &verr) {
+		return c.JSON(
+			http.StatusBadRequest,
+			apperr.ErrorResponse{Code: http.StatusBadRequest, Message: "Validation failed", Details: verr.Details},
+		)
+	}
+	return c.JSON(
+		http.StatusBadRequest,
+		apperr.ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()},
+	)
+}
@@ -0,0 +1,99 @@
+//go:build unit
+
+package expenses
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseAmount(t *testing.T, s string) money.Amount {
+	a, err := money.Parse(s)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when parsing amount", err)
+	}
+	return a
+}
+
+func TestValidator(t *testing.T) {
+	valid := Expenses{Title: "strawberry smoothie", Amount: mustParseAmount(t, "79.5"), Currency: "THB", Note: "night market", Tags: []string{"food", "beverage"}}
+
+	t.Run("Should accept a valid expense", func(t *testing.T) {
+		assert.NoError(t, NewValidator().Validate(&valid))
+	})
+
+	tests := []struct {
+		name     string
+		modify   func(e *Expenses)
+		expected []apperr.FieldError
+	}{
+		{
+			"Should require title",
+			func(e *Expenses) { e.Title = "  " },
+			[]apperr.FieldError{{Field: "title", Message: "is required"}},
+		},
+		{
+			"Should reject long title",
+			func(e *Expenses) { e.Title = strings.Repeat("a", 256) },
+			[]apperr.FieldError{{Field: "title", Message: "must be at most 255 characters"}},
+		},
+		{
+			"Should reject negative amount",
+			func(e *Expenses) { e.Amount = -1 },
+			[]apperr.FieldError{{Field: "amount", Message: "must be greater than 0"}},
+		},
+		{
+			"Should reject amount with too many decimals",
+			func(e *Expenses) { e.Amount = mustParseAmount(t, "79.999") },
+			[]apperr.FieldError{{Field: "amount", Message: "must have at most 2 decimal places for THB"}},
+		},
+		{
+			"Should reject decimals for currency without minor unit",
+			func(e *Expenses) { e.Currency = "JPY" },
+			[]apperr.FieldError{{Field: "amount", Message: "must have at most 0 decimal places for JPY"}},
+		},
+		{
+			"Should reject unknown currency",
+			func(e *Expenses) { e.Currency = "thb" },
+			[]apperr.FieldError{{Field: "currency", Message: "must be a supported ISO 4217 code"}},
+		},
+		{
+			"Should reject long note",
+			func(e *Expenses) { e.Note = strings.Repeat("a", 1001) },
+			[]apperr.FieldError{{Field: "note", Message: "must be at most 1000 characters"}},
+		},
+		{
+			"Should reject empty and duplicated tags",
+			func(e *Expenses) { e.Tags = []string{"food", "", "food"} },
+			[]apperr.FieldError{
+				{Field: "tags[1]", Message: "must not be empty"},
+				{Field: "tags[2]", Message: "is duplicated"},
+			},
+		},
+		{
+			"Should reject too many tags",
+			func(e *Expenses) { e.Tags = strings.Split("a,b,c,d,e,f,g,h,i,j,k", ",") },
+			[]apperr.FieldError{{Field: "tags", Message: "must have at most 10 tags"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			exp := valid
+			tt.modify(&exp)
+
+			// Act
+			err := NewValidator().Validate(&exp)
+
+			// Assert
+			if assert.IsType(t, &apperr.ValidationError{}, err) {
+				assert.Equal(t, tt.expected, err.(*apperr.ValidationError).Details)
+			}
+		})
+	}
+}
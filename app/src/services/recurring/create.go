@@ -0,0 +1,29 @@
+package recurring
+
+import (
+	"net/http"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *handler) CreateRecurring(c echo.Context) error {
+	ownerID, errRes := currentOwner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	rec, errRes := h.bind(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	rec.OwnerID = ownerID
+	rec.plan(rec.StartsAt, h.location)
+	if err := h.repo.Create(c.Request().Context(), &rec); err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	rec.localize(h.location)
+	return c.JSON(http.StatusCreated, rec)
+}
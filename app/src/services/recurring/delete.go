@@ -0,0 +1,23 @@
+package recurring
+
+import (
+	"net/http"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+// DeleteRecurringByID stops the template. Expenses it already added are
+// kept.
+func (h *handler) DeleteRecurringByID(c echo.Context) error {
+	ownerID, errRes := currentOwner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	if err := h.repo.Delete(c.Request().Context(), ownerID, c.Param("id")); err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
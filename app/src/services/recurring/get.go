@@ -0,0 +1,40 @@
+package recurring
+
+import (
+	"net/http"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *handler) GetRecurringByID(c echo.Context) error {
+	ownerID, errRes := currentOwner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	rec, err := h.repo.Get(c.Request().Context(), ownerID, c.Param("id"))
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	rec.localize(h.location)
+	return c.JSON(http.StatusOK, rec)
+}
+
+func (h *handler) GetRecurring(c echo.Context) error {
+	ownerID, errRes := currentOwner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	list, err := h.repo.List(c.Request().Context(), ownerID)
+	if err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	for i := range list {
+		list[i].localize(h.location)
+	}
+	return c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,130 @@
+package recurring
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/services/expenses"
+)
+
+// memoryRepository keeps templates in process memory and adds their
+// occurrences to the in-memory expense repository.
+type memoryRepository struct {
+	mu        sync.Mutex
+	lastID    int
+	templates map[int]Recurring
+	expenses  expenses.ExpenseRepository
+	now       func() time.Time
+}
+
+func NewMemoryRepository(expenseRepo expenses.ExpenseRepository) RecurringRepository {
+	return &memoryRepository{templates: map[int]Recurring{}, expenses: expenseRepo, now: time.Now}
+}
+
+func clone(rec Recurring) Recurring {
+	if rec.Tags != nil {
+		rec.Tags = append([]string{}, rec.Tags...)
+	}
+	return rec
+}
+
+func (r *memoryRepository) find(owner int, id string) (Recurring, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return Recurring{}, apperr.ErrInvalidID
+	}
+	rec, ok := r.templates[n]
+	if !ok || rec.OwnerID != owner {
+		return Recurring{}, sql.ErrNoRows
+	}
+	return rec, nil
+}
+
+func (r *memoryRepository) Create(ctx context.Context, rec *Recurring) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	rec.ID = r.lastID
+	rec.CreatedAt = r.now()
+	rec.UpdatedAt = rec.CreatedAt
+	r.templates[rec.ID] = clone(*rec)
+	return nil
+}
+
+func (r *memoryRepository) Get(ctx context.Context, owner int, id string) (Recurring, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, err := r.find(owner, id)
+	return clone(rec), err
+}
+
+func (r *memoryRepository) List(ctx context.Context, owner int) ([]Recurring, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := []Recurring{}
+	for _, rec := range r.templates {
+		if rec.OwnerID == owner {
+			list = append(list, clone(rec))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, owner int, id string, rec *Recurring) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.find(owner, id)
+	if err != nil {
+		return err
+	}
+	rec.ID = stored.ID
+	rec.OwnerID = stored.OwnerID
+	rec.CreatedAt = stored.CreatedAt
+	rec.UpdatedAt = r.now()
+	r.templates[rec.ID] = clone(*rec)
+	return nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, owner int, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, err := r.find(owner, id)
+	if err != nil {
+		return err
+	}
+	delete(r.templates, rec.ID)
+	return nil
+}
+
+// Materialize holds the lock for the whole run, which is enough to add each
+// occurrence once within a single process.
+func (r *memoryRepository) Materialize(ctx context.Context, now time.Time, loc *time.Location) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	added := 0
+	for id, rec := range r.templates {
+		for i := 0; i < maxCatchUp && rec.NextRunAt != nil && !rec.NextRunAt.After(now); i++ {
+			at := *rec.NextRunAt
+			exp := rec.expense(at)
+			if err := r.expenses.Create(ctx, &exp); err != nil {
+				return added, err
+			}
+			added++
+			rec.NextRunAt = rec.next(at, loc)
+			r.templates[id] = rec
+		}
+	}
+	return added, nil
+}
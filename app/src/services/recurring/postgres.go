@@ -0,0 +1,179 @@
+package recurring
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/lib/pq"
+)
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) RecurringRepository {
+	return &postgresRepository{db}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const columns = `id, owner_id, title, amount, currency, note, tags, schedule, starts_at, ends_at, next_run_at, created_at, updated_at`
+
+// scanRecurring reads the columns listed in columns in that order.
+func scanRecurring(row scanner, r *Recurring) error {
+	return row.Scan(&r.ID, &r.OwnerID, &r.Title, &r.Amount, &r.Currency, &r.Note, pq.Array(&r.Tags),
+		&r.Schedule, &r.StartsAt, &r.EndsAt, &r.NextRunAt, &r.CreatedAt, &r.UpdatedAt)
+}
+
+func (r *postgresRepository) Create(ctx context.Context, rec *Recurring) error {
+	sql := `
+	INSERT INTO
+		recurring_expenses (owner_id, title, amount, currency, note, tags, schedule, starts_at, ends_at, next_run_at)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING id, created_at, updated_at;
+	`
+	row := r.db.QueryRowContext(ctx, sql, rec.OwnerID, rec.Title, rec.Amount, rec.Currency, rec.Note, pq.Array(&rec.Tags),
+		rec.Schedule, rec.StartsAt, rec.EndsAt, rec.NextRunAt)
+	return row.Scan(&rec.ID, &rec.CreatedAt, &rec.UpdatedAt)
+}
+
+func (r *postgresRepository) Get(ctx context.Context, owner int, id string) (Recurring, error) {
+	var rec Recurring
+	sql := `
+	SELECT ` + columns + `
+	FROM recurring_expenses
+	WHERE id = $1 AND owner_id = $2
+	`
+	err := scanRecurring(r.db.QueryRowContext(ctx, sql, id, owner), &rec)
+	return rec, apperr.ByID(err)
+}
+
+func (r *postgresRepository) List(ctx context.Context, owner int) ([]Recurring, error) {
+	list := []Recurring{}
+	sql := `
+	SELECT ` + columns + `
+	FROM recurring_expenses
+	WHERE owner_id = $1
+	ORDER BY id
+	`
+	rows, err := r.db.QueryContext(ctx, sql, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rec Recurring
+		if err := scanRecurring(rows, &rec); err != nil {
+			return nil, err
+		}
+		list = append(list, rec)
+	}
+	return list, rows.Err()
+}
+
+func (r *postgresRepository) Update(ctx context.Context, owner int, id string, rec *Recurring) error {
+	rec.OwnerID = owner
+	sql := `
+	UPDATE
+		recurring_expenses SET title = $1, amount = $2, currency = $3, note = $4, tags = $5,
+		schedule = $6, starts_at = $7, ends_at = $8, next_run_at = $9, updated_at = NOW()
+	WHERE
+		id = $10 AND owner_id = $11
+	RETURNING id, created_at, updated_at
+	`
+	row := r.db.QueryRowContext(ctx, sql, rec.Title, rec.Amount, rec.Currency, rec.Note, pq.Array(&rec.Tags),
+		rec.Schedule, rec.StartsAt, rec.EndsAt, rec.NextRunAt, id, owner)
+	return apperr.ByID(row.Scan(&rec.ID, &rec.CreatedAt, &rec.UpdatedAt))
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, owner int, id string) error {
+	sql := `
+	DELETE FROM
+		recurring_expenses
+	WHERE
+		id = $1 AND owner_id = $2
+	RETURNING id
+	`
+	var deleted int
+	return apperr.ByID(r.db.QueryRowContext(ctx, sql, id, owner).Scan(&deleted))
+}
+
+// Materialize claims the due templates with SKIP LOCKED so replicas work on
+// different ones, and relies on the unique (recurring_id, occurrence_at)
+// index to never add an occurrence twice, even when a run is retried after
+// a crash.
+func (r *postgresRepository) Materialize(ctx context.Context, now time.Time, loc *time.Location) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	sql := `
+	SELECT ` + columns + `
+	FROM recurring_expenses
+	WHERE next_run_at <= $1
+	ORDER BY next_run_at
+	LIMIT $2
+	FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.QueryContext(ctx, sql, now, maxDueTemplates)
+	if err != nil {
+		return 0, err
+	}
+	var due []Recurring
+	for rows.Next() {
+		var rec Recurring
+		if err := scanRecurring(rows, &rec); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, rec)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	insert := `
+	INSERT INTO
+		expenses (title, amount, currency, note, tags, owner_id, spent_at, recurring_id, occurrence_at)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8, $7)
+	ON CONFLICT (recurring_id, occurrence_at) DO NOTHING
+	`
+	advance := `
+	UPDATE
+		recurring_expenses SET next_run_at = $1
+	WHERE
+		id = $2
+	`
+	added := 0
+	for _, rec := range due {
+		for i := 0; i < maxCatchUp && rec.NextRunAt != nil && !rec.NextRunAt.After(now); i++ {
+			at := *rec.NextRunAt
+			res, err := tx.ExecContext(ctx, insert, rec.Title, rec.Amount, rec.Currency, rec.Note, pq.Array(&rec.Tags),
+				rec.OwnerID, at, rec.ID)
+			if err != nil {
+				return 0, err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return 0, err
+			}
+			added += int(n)
+			rec.NextRunAt = rec.next(at, loc)
+		}
+		if _, err := tx.ExecContext(ctx, advance, rec.NextRunAt, rec.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	return added, tx.Commit()
+}
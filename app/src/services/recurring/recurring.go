@@ -0,0 +1,158 @@
+package recurring
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/services/expenses"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+)
+
+type handler struct {
+	repo RecurringRepository
+	// location is the zone schedules are read in, UTC when nil.
+	location *time.Location
+	now      func() time.Time
+}
+
+// Recurring is a template for an expense that is added on every occurrence
+// of Schedule from StartsAt until EndsAt. NextRunAt is the next occurrence
+// still to be added and nil once the template has ended.
+type Recurring struct {
+	ID        int          `json:"id"`
+	Title     string       `json:"title"`
+	Amount    money.Amount `json:"amount"`
+	Currency  string       `json:"currency"`
+	Note      string       `json:"note"`
+	Tags      []string     `json:"tags"`
+	Schedule  string       `json:"schedule"`
+	StartsAt  time.Time    `json:"starts_at"`
+	EndsAt    *time.Time   `json:"ends_at"`
+	NextRunAt *time.Time   `json:"next_run_at"`
+	OwnerID   int          `json:"-"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+func CreateHandler(repo RecurringRepository, location *time.Location) *handler {
+	return &handler{repo: repo, location: location, now: time.Now}
+}
+
+func zone(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// expense is the expense added for the occurrence at.
+func (r Recurring) expense(at time.Time) expenses.Expenses {
+	exp := expenses.Expenses{
+		Title:    r.Title,
+		Amount:   r.Amount,
+		Currency: r.Currency,
+		Note:     r.Note,
+		Tags:     r.Tags,
+		OwnerID:  r.OwnerID,
+		SpentAt:  at,
+	}
+	if exp.Tags != nil {
+		exp.Tags = append([]string{}, exp.Tags...)
+	}
+	return exp
+}
+
+// next returns the occurrence following after, or nil when the schedule has
+// no more occurrences before EndsAt.
+func (r Recurring) next(after time.Time, loc *time.Location) *time.Time {
+	s, err := parseSchedule(r.Schedule)
+	if err != nil {
+		return nil
+	}
+	t := s.next(after, zone(loc))
+	if t.IsZero() || (r.EndsAt != nil && t.After(*r.EndsAt)) {
+		return nil
+	}
+	return &t
+}
+
+// plan sets NextRunAt to the first occurrence at or after from, never
+// before StartsAt.
+func (r *Recurring) plan(from time.Time, loc *time.Location) {
+	if from.Before(r.StartsAt) {
+		from = r.StartsAt
+	}
+	r.NextRunAt = r.next(from.Add(-time.Nanosecond), loc)
+}
+
+func (r *Recurring) localize(loc *time.Location) {
+	loc = zone(loc)
+	r.StartsAt = r.StartsAt.In(loc)
+	r.CreatedAt = r.CreatedAt.In(loc)
+	r.UpdatedAt = r.UpdatedAt.In(loc)
+	if r.EndsAt != nil {
+		t := r.EndsAt.In(loc)
+		r.EndsAt = &t
+	}
+	if r.NextRunAt != nil {
+		t := r.NextRunAt.In(loc)
+		r.NextRunAt = &t
+	}
+}
+
+var defaultValidator = expenses.NewValidator()
+
+// validate checks the expense fields with the expense rules and the
+// schedule on top.
+func (r Recurring) validate(c echo.Context) []apperr.FieldError {
+	var details []apperr.FieldError
+	var err error
+	if c.Echo().Validator != nil {
+		err = c.Validate(r.expense(r.StartsAt))
+	} else {
+		err = defaultValidator.Validate(r.expense(r.StartsAt))
+	}
+	var verr *apperr.ValidationError
+	if errors.As(err, &verr) {
+		details = append(details, verr.Details...)
+	}
+
+	if _, err := parseSchedule(r.Schedule); err != nil {
+		details = append(details, apperr.FieldError{Field: "schedule", Message: err.Error()})
+	}
+	if r.EndsAt != nil && r.EndsAt.Before(r.StartsAt) {
+		details = append(details, apperr.FieldError{Field: "ends_at", Message: "must not be before starts_at"})
+	}
+	return details
+}
+
+// bind reads a template from the request body. StartsAt defaults to now
+// and the currency like expenses do.
+func (h *handler) bind(c echo.Context) (Recurring, *apperr.ErrorResponse) {
+	var r Recurring
+	if err := c.Bind(&r); err != nil {
+		return r, &apperr.ErrorResponse{Code: http.StatusUnprocessableEntity, Message: "Invalid request body"}
+	}
+	if r.Currency == "" {
+		r.Currency = money.DefaultCurrency
+	}
+	if r.StartsAt.IsZero() {
+		r.StartsAt = h.now()
+	}
+	if details := r.validate(c); len(details) > 0 {
+		return r, &apperr.ErrorResponse{Code: http.StatusBadRequest, Message: "Validation failed", Details: details}
+	}
+	return r, nil
+}
+
+func currentOwner(c echo.Context) (int, *apperr.ErrorResponse) {
+	user, ok := users.CurrentUser(c)
+	if !ok {
+		return 0, &apperr.ErrorResponse{Code: http.StatusUnauthorized, Message: "Authentication required"}
+	}
+	return user.ID, nil
+}
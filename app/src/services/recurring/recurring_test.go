@@ -0,0 +1,170 @@
+//go:build unit
+
+package recurring
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/RTae/assessment/app/src/services/expenses"
+	"github.com/RTae/assessment/app/src/services/users"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testUser = users.User{ID: 1, Username: "somchai", Role: users.RoleUser}
+	testTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+)
+
+func rent(startsAt time.Time) Recurring {
+	r := Recurring{
+		OwnerID:  testUser.ID,
+		Title:    "rent",
+		Amount:   80000000,
+		Currency: "THB",
+		Tags:     []string{"home"},
+		Schedule: "0 0 1 * *",
+		StartsAt: startsAt,
+	}
+	r.plan(r.StartsAt, time.UTC)
+	return r
+}
+
+func TestMemoryMaterialize(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	expenseRepo := expenses.NewMemoryRepository()
+	repo := NewMemoryRepository(expenseRepo)
+	r := rent(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ends := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	r.EndsAt = &ends
+	assert.NoError(t, repo.Create(ctx, &r))
+	now := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	// Act
+	added, err := repo.Materialize(ctx, now, time.UTC)
+	again, errAgain := repo.Materialize(ctx, now, time.UTC)
+	later, errLater := repo.Materialize(ctx, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), time.UTC)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, errAgain)
+	assert.NoError(t, errLater)
+	assert.Equal(t, 3, added)
+	assert.Equal(t, 0, again)
+	assert.Equal(t, 9, later)
+
+	stored, _ := repo.Get(ctx, testUser.ID, "1")
+	assert.Nil(t, stored.NextRunAt)
+	spent, _ := expenseRepo.Spent(ctx, testUser.ID, "home", "THB", r.StartsAt, ends)
+	assert.Equal(t, money.Amount(12*80000000), spent)
+}
+
+func TestPostgresMaterialize(t *testing.T) {
+	// Arrange
+	db, mock, close := handlers.MockDatabase(t)
+	defer close()
+
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM recurring_expenses WHERE next_run_at <= (.+) FOR UPDATE SKIP LOCKED").
+		WithArgs(now, maxDueTemplates).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "title", "amount", "currency", "note", "tags", "schedule", "starts_at", "ends_at", "next_run_at", "created_at", "updated_at"}).
+			AddRow(7, testUser.ID, "rent", "8000.0000", "THB", "", "{home}", "0 0 1 * *", testTime, nil, march, testTime, testTime))
+	mock.ExpectExec("INSERT INTO expenses (.+) ON CONFLICT \\(recurring_id, occurrence_at\\) DO NOTHING").
+		WithArgs("rent", money.Amount(80000000), "THB", "", sqlmock.AnyArg(), testUser.ID, march, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE recurring_expenses SET next_run_at").
+		WithArgs(april, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Act
+	added, err := NewPostgresRepository(db).Materialize(context.Background(), now, time.UTC)
+
+	// Assert
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, added, "an occurrence added by an earlier run is not counted again")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestWorker(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	expenseRepo := expenses.NewMemoryRepository()
+	repo := NewMemoryRepository(expenseRepo)
+	r := rent(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, repo.Create(ctx, &r))
+	w := NewWorker(repo, time.UTC, time.Hour)
+	w.now = func() time.Time { return time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC) }
+
+	// Act
+	w.Start()
+	stopCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	err := w.Stop(stopCtx)
+
+	// Assert
+	assert.NoError(t, err)
+	spent, _ := expenseRepo.Spent(ctx, testUser.ID, "home", "THB", r.StartsAt, w.now())
+	assert.Equal(t, money.Amount(2*80000000), spent)
+}
+
+func TestCreateRecurringHandler(t *testing.T) {
+	t.Run("Should create a template with its first occurrence", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		body := `{"title": "rent", "amount": 8000, "tags": ["home"], "schedule": "0 9 1 * *", "starts_at": "2024-01-02T00:00:00Z"}`
+		req := httptest.NewRequest(http.MethodPost, "/recurring-expenses", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		h := handler{repo: NewMemoryRepository(expenses.NewMemoryRepository()), now: time.Now}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+
+		// Act
+		err := h.CreateRecurring(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusCreated, res.Code)
+			assert.Contains(t, res.Body.String(), `"schedule":"0 9 1 * *","starts_at":"2024-01-02T00:00:00Z","ends_at":null,"next_run_at":"2024-02-01T09:00:00Z"`)
+		}
+	})
+
+	t.Run("Should return bad request error if schedule is not valid", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		body := `{"title": "rent", "amount": 8000, "schedule": "monthly"}`
+		req := httptest.NewRequest(http.MethodPost, "/recurring-expenses", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		h := handler{repo: NewMemoryRepository(expenses.NewMemoryRepository()), now: time.Now}
+		c := e.NewContext(req, res)
+		users.SetCurrentUser(c, testUser)
+		expected := `{"statusCode":400,"message":"Validation failed","details":[{"field":"schedule","message":"must have 5 fields: minute hour day-of-month month day-of-week"}]}`
+
+		// Act
+		err := h.CreateRecurring(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+}
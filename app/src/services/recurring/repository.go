@@ -0,0 +1,31 @@
+package recurring
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// maxDueTemplates is how many templates one Materialize call claims.
+	maxDueTemplates = 100
+	// maxCatchUp caps the occurrences added per template in one call so a
+	// long outage is caught up over several runs.
+	maxCatchUp = 100
+)
+
+// RecurringRepository stores recurring expense templates. Lookups by id are
+// scoped to owner and report missing or someone else's templates with
+// sql.ErrNoRows.
+type RecurringRepository interface {
+	// Create stores r for r.OwnerID.
+	Create(ctx context.Context, r *Recurring) error
+	Get(ctx context.Context, owner int, id string) (Recurring, error)
+	List(ctx context.Context, owner int) ([]Recurring, error)
+	Update(ctx context.Context, owner int, id string, r *Recurring) error
+	Delete(ctx context.Context, owner int, id string) error
+	// Materialize adds an expense for every occurrence due by now and moves
+	// the templates past them, reading schedules in loc. Each occurrence is
+	// added at most once however often and wherever it runs. It returns the
+	// number of expenses added.
+	Materialize(ctx context.Context, now time.Time, loc *time.Location) (int, error)
+}
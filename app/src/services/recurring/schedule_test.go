@@ -0,0 +1,67 @@
+//go:build unit
+
+package recurring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleNext(t *testing.T) {
+	bangkok, _ := time.LoadLocation("Asia/Bangkok")
+	after := time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expr     string
+		loc      *time.Location
+		expected time.Time
+	}{
+		{"Should fire every minute", "* * * * *", time.UTC, time.Date(2024, 1, 31, 10, 31, 0, 0, time.UTC)},
+		{"Should fire on steps", "*/20 * * * *", time.UTC, time.Date(2024, 1, 31, 10, 40, 0, 0, time.UTC)},
+		{"Should fire on the first of next month", "0 9 1 * *", time.UTC, time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"Should read fields in the configured zone", "0 9 1 * *", bangkok, time.Date(2024, 2, 1, 9, 0, 0, 0, bangkok)},
+		{"Should skip months without the day", "0 0 31 * *", time.UTC, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"Should accept leap days", "0 0 29 2 *", time.UTC, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"Should fire on weekdays in a range", "15 8 * * 1-5", time.UTC, time.Date(2024, 2, 1, 8, 15, 0, 0, time.UTC)},
+		{"Should treat 7 as sunday", "0 0 * * 7", time.UTC, time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"Should fire when either day field matches", "0 0 1 * 5", time.UTC, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"Should accept lists", "0 6,18 * * *", time.UTC, time.Date(2024, 1, 31, 18, 0, 0, 0, time.UTC)},
+		{"Should accept descriptors", "@monthly", time.UTC, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"Should never fire on a missing date", "0 0 30 2 *", time.UTC, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseSchedule(tt.expr)
+
+			if assert.NoError(t, err) {
+				assert.True(t, tt.expected.Equal(s.next(after, tt.loc)), "got %s", s.next(after, tt.loc))
+			}
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"* * * *", "must have 5 fields: minute hour day-of-month month day-of-week"},
+		{"60 * * * *", "minute must be between 0 and 59"},
+		{"* * 0 * *", "day of month must be between 1 and 31"},
+		{"* * * 5-1 *", "month must be between 1 and 12"},
+		{"*/0 * * * *", `minute has invalid step in "*/0"`},
+		{"* x * * *", `hour has invalid value "x"`},
+	}
+
+	for _, tt := range tests {
+		t.Run("Should reject "+tt.expr, func(t *testing.T) {
+			_, err := parseSchedule(tt.expr)
+
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
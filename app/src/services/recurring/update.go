@@ -0,0 +1,30 @@
+package recurring
+
+import (
+	"net/http"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+// UpdateRecurringByID replaces the template. The schedule restarts from now
+// so occurrences that already passed are not added again.
+func (h *handler) UpdateRecurringByID(c echo.Context) error {
+	ownerID, errRes := currentOwner(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	rec, errRes := h.bind(c)
+	if errRes != nil {
+		return c.JSON(errRes.Code, errRes)
+	}
+
+	rec.plan(h.now(), h.location)
+	if err := h.repo.Update(c.Request().Context(), ownerID, c.Param("id"), &rec); err != nil {
+		return apperr.Respond(c, err)
+	}
+
+	rec.localize(h.location)
+	return c.JSON(http.StatusOK, rec)
+}
@@ -0,0 +1,80 @@
+package recurring
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/RTae/assessment/app/src/metrics"
+)
+
+var expensesAdded = metrics.Default.NewCounter("recurring_expenses_added_total",
+	"Expenses added from recurring expense templates.")
+
+// Worker periodically turns due recurring expenses into expenses. Several
+// workers may run against the same database.
+type Worker struct {
+	repo     RecurringRepository
+	location *time.Location
+	interval time.Duration
+	now      func() time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewWorker(repo RecurringRepository, location *time.Location, interval time.Duration) *Worker {
+	return &Worker{repo: repo, location: location, interval: interval, now: time.Now}
+}
+
+// RunOnce adds the expenses due by now and returns how many were added.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	added, err := w.repo.Materialize(ctx, w.now(), w.location)
+	if err == nil {
+		expensesAdded.Add(float64(added))
+	}
+	return added, err
+}
+
+// Start runs the worker in the background until Stop is called, starting
+// with an immediate run to catch up on anything missed while stopped.
+func (w *Worker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			if added, err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("recurring expenses: %v", err)
+			} else if added > 0 {
+				log.Printf("recurring expenses: added %d expenses", added)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels the running pass and waits for the worker to exit or for ctx
+// to end. An interrupted pass is rolled back and picked up by the next one.
+func (w *Worker) Stop(ctx context.Context) error {
+	if w.cancel == nil {
+		return nil
+	}
+	w.cancel()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,49 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+// dummyHash is compared against when the username is unknown so that a
+// failed login takes the same time whether or not the user exists.
+var dummyHash, _ = HashPassword("dummy password")
+
+func (h *handler) Login(c echo.Context) error {
+
+	var cred Credentials
+	err := c.Bind(&cred)
+	if err != nil {
+		return apperr.InvalidBody(c, err)
+	}
+
+	user, err := h.repo.GetByUsername(c.Request().Context(), cred.Username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	hash := user.PasswordHash
+	if err != nil {
+		hash = dummyHash
+	}
+	if !CheckPassword(hash, cred.Password) || err != nil {
+		return c.JSON(
+			http.StatusUnauthorized,
+			apperr.ErrorResponse{Code: http.StatusUnauthorized, Message: "Invalid username or password"},
+		)
+	}
+
+	return h.issue(c, user)
+}
+
+func (h *handler) issue(c echo.Context, user User) error {
+	pair, err := h.tokens.Issue(user)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, pair)
+}
@@ -0,0 +1,105 @@
+//go:build unit
+
+package users
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTokens() *Tokens {
+	return NewTokens("test secret", 15*time.Minute, time.Hour)
+}
+
+func TestLoginHandler(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when hashing password", err)
+	}
+
+	t.Run("Should issue token pair for valid credentials", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		body := `{"username": "somchai", "password": "correct horse"}`
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		userMockRow := mock.NewRows([]string{"id", "username", "password_hash", "role"}).
+			AddRow(1, "somchai", hash, RoleUser)
+		mock.ExpectQuery("SELECT (.+) FROM users WHERE username = (.+)").
+			WithArgs("somchai").
+			WillReturnRows(userMockRow)
+
+		tokens := newTestTokens()
+		h := handler{NewPostgresRepository(db), tokens}
+		c := e.NewContext(req, res)
+
+		// Act
+		err := h.Login(c)
+		var pair TokenPair
+		json.Unmarshal(res.Body.Bytes(), &pair)
+		user, parseErr := tokens.parse(pair.AccessToken, accessToken)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, "Bearer", pair.TokenType)
+			assert.Equal(t, 900, pair.ExpiresIn)
+			assert.NotEmpty(t, pair.RefreshToken)
+			assert.NoError(t, parseErr)
+			assert.Equal(t, User{ID: 1, Username: "somchai", Role: RoleUser}, user)
+		}
+	})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			"Should return unauthorized error if password is wrong",
+			`{"username": "somchai", "password": "wrong horse"}`,
+		},
+		{
+			"Should return unauthorized error if user does not exist",
+			`{"username": "somsak", "password": "correct horse"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(test.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			res := httptest.NewRecorder()
+
+			repo := NewMemoryRepository()
+			repo.Create(req.Context(), &User{Username: "somchai", PasswordHash: hash, Role: RoleUser})
+
+			h := handler{repo, newTestTokens()}
+			c := e.NewContext(req, res)
+			expected := "{\"statusCode\":401,\"message\":\"Invalid username or password\"}"
+
+			// Act
+			err := h.Login(c)
+
+			// Assert
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusUnauthorized, res.Code)
+				assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+			}
+		})
+	}
+}
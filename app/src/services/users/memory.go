@@ -0,0 +1,57 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// memoryRepository keeps users in process memory alongside the in-memory
+// expense repository.
+type memoryRepository struct {
+	mu     sync.RWMutex
+	lastID int
+	users  map[int]User
+}
+
+func NewMemoryRepository() UserRepository {
+	return &memoryRepository{users: map[int]User{}}
+}
+
+func (r *memoryRepository) Create(ctx context.Context, user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Username == user.Username {
+			return ErrUsernameTaken
+		}
+	}
+	r.lastID++
+	user.ID = r.lastID
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryRepository) GetByID(ctx context.Context, id int) (User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	return u, nil
+}
+
+func (r *memoryRepository) GetByUsername(ctx context.Context, username string) (User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return User{}, sql.ErrNoRows
+}
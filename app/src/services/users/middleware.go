@@ -0,0 +1,40 @@
+package users
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+const contextKey = "user"
+
+// Authenticate requires a valid access token in the Authorization header and
+// attaches its user to the context. The role is also set on its own so that
+// role checks do not need to know about users.
+func (t *Tokens) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Request().Header.Get(echo.HeaderAuthorization)
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return unauthorized(c, "Missing bearer token")
+		}
+
+		user, err := t.parse(token, accessToken)
+		if err != nil {
+			return unauthorized(c, "Invalid or expired token")
+		}
+
+		SetCurrentUser(c, user)
+		return next(c)
+	}
+}
+
+func unauthorized(c echo.Context, message string) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+	return c.JSON(
+		http.StatusUnauthorized,
+		apperr.ErrorResponse{Code: http.StatusUnauthorized, Message: message},
+	)
+}
@@ -0,0 +1,98 @@
+//go:build unit
+
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticate(t *testing.T) {
+	tokens := newTestTokens()
+	user := User{ID: 7, Username: "somchai", Role: RoleUser}
+	pair, _ := tokens.Issue(user)
+
+	expired := newTestTokens()
+	expired.now = func() time.Time { return time.Now().Add(-time.Hour) }
+	expiredPair, _ := expired.Issue(user)
+
+	forged, _ := NewTokens("other secret", time.Minute, time.Minute).Issue(user)
+
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, claims{
+		Role: RoleAdmin,
+		Type: accessToken,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   "7",
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	t.Run("Should attach user to context for valid access token", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/expenses", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+pair.AccessToken)
+		res := httptest.NewRecorder()
+		c := e.NewContext(req, res)
+
+		var got User
+		next := func(c echo.Context) error {
+			got, _ = CurrentUser(c)
+			return c.NoContent(http.StatusOK)
+		}
+
+		// Act
+		err := tokens.Authenticate(next)(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, user, got)
+			assert.Equal(t, RoleUser, c.Get("role"))
+		}
+	})
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"Should return unauthorized error if header is missing", ""},
+		{"Should return unauthorized error if scheme is basic", "Basic dXNlcjoxMjNxd2Vhc2R6eGM="},
+		{"Should return unauthorized error if token is expired", "Bearer " + expiredPair.AccessToken},
+		{"Should return unauthorized error if token is signed with another secret", "Bearer " + forged.AccessToken},
+		{"Should return unauthorized error if token is not signed", "Bearer " + unsigned},
+		{"Should return unauthorized error if refresh token is used", "Bearer " + pair.RefreshToken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/expenses", nil)
+			if test.header != "" {
+				req.Header.Set(echo.HeaderAuthorization, test.header)
+			}
+			res := httptest.NewRecorder()
+			c := e.NewContext(req, res)
+			next := func(c echo.Context) error {
+				t.Fatal("next handler must not be called")
+				return nil
+			}
+
+			// Act
+			err := tokens.Authenticate(next)(c)
+
+			// Assert
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusUnauthorized, res.Code)
+				assert.Equal(t, "Bearer", res.Header().Get(echo.HeaderWWWAuthenticate))
+			}
+		})
+	}
+}
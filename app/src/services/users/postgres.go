@@ -0,0 +1,55 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) UserRepository {
+	return &postgresRepository{db}
+}
+
+func (r *postgresRepository) Create(ctx context.Context, user *User) error {
+	sql := `
+	INSERT INTO
+		users (username, password_hash, role)
+	VALUES
+		($1, $2, $3)
+	RETURNING id;
+	`
+	err := r.db.QueryRowContext(ctx, sql, user.Username, user.PasswordHash, user.Role).Scan(&user.ID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrUsernameTaken
+	}
+	return err
+}
+
+func (r *postgresRepository) GetByID(ctx context.Context, id int) (User, error) {
+	var u User
+	sql := `
+	SELECT id, username, password_hash, role
+	FROM users
+	WHERE id = $1
+	`
+	err := r.db.QueryRowContext(ctx, sql, id).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role)
+	return u, err
+}
+
+func (r *postgresRepository) GetByUsername(ctx context.Context, username string) (User, error) {
+	var u User
+	sql := `
+	SELECT id, username, password_hash, role
+	FROM users
+	WHERE username = $1
+	`
+	err := r.db.QueryRowContext(ctx, sql, username).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role)
+	return u, err
+}
@@ -0,0 +1,39 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh exchanges a refresh token for a new token pair. The user is loaded
+// again so that a changed role is picked up and removed users are refused.
+func (h *handler) Refresh(c echo.Context) error {
+
+	var req refreshRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return apperr.InvalidBody(c, err)
+	}
+
+	claimed, err := h.tokens.parse(req.RefreshToken, refreshToken)
+	if err != nil {
+		return unauthorized(c, "Invalid or expired token")
+	}
+
+	user, err := h.repo.GetByID(c.Request().Context(), claimed.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return unauthorized(c, "Invalid or expired token")
+	}
+	if err != nil {
+		return err
+	}
+
+	return h.issue(c, user)
+}
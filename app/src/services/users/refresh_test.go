@@ -0,0 +1,84 @@
+//go:build unit
+
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshHandler(t *testing.T) {
+	tokens := newTestTokens()
+	repo := NewMemoryRepository()
+	user := User{Username: "somchai", Role: RoleAdmin}
+	repo.Create(context.Background(), &user)
+	pair, err := tokens.Issue(user)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when issuing tokens", err)
+	}
+	ghost, _ := tokens.Issue(User{ID: 99, Username: "ghost", Role: RoleUser})
+
+	t.Run("Should issue new token pair for refresh token", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		body := fmt.Sprintf(`{"refresh_token": "%s"}`, pair.RefreshToken)
+		req := httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		h := handler{repo, tokens}
+		c := e.NewContext(req, res)
+
+		// Act
+		err := h.Refresh(c)
+		var refreshed TokenPair
+		json.Unmarshal(res.Body.Bytes(), &refreshed)
+		got, parseErr := tokens.parse(refreshed.AccessToken, accessToken)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.NoError(t, parseErr)
+			assert.Equal(t, User{ID: user.ID, Username: "somchai", Role: RoleAdmin}, got)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"Should return unauthorized error if access token is used", pair.AccessToken},
+		{"Should return unauthorized error if user no longer exists", ghost.RefreshToken},
+		{"Should return unauthorized error if token is malformed", "not-a-token"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			body := fmt.Sprintf(`{"refresh_token": "%s"}`, test.token)
+			req := httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			res := httptest.NewRecorder()
+
+			h := handler{repo, tokens}
+			c := e.NewContext(req, res)
+
+			// Act
+			err := h.Refresh(c)
+
+			// Assert
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusUnauthorized, res.Code)
+			}
+		})
+	}
+}
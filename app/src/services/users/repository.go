@@ -0,0 +1,41 @@
+package users
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUsernameTaken = errors.New("username is already taken")
+
+// UserRepository stores user accounts. Lookups return sql.ErrNoRows when the
+// user does not exist.
+type UserRepository interface {
+	Create(ctx context.Context, user *User) error
+	GetByID(ctx context.Context, id int) (User, error)
+	GetByUsername(ctx context.Context, username string) (User, error)
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// EnsureAdmin creates the admin account configured through the environment
+// when it does not exist yet. An existing account is left untouched.
+func EnsureAdmin(ctx context.Context, repo UserRepository, username, password string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	err = repo.Create(ctx, &User{Username: username, PasswordHash: hash, Role: RoleAdmin})
+	if errors.Is(err, ErrUsernameTaken) {
+		return nil
+	}
+	return err
+}
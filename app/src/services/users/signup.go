@@ -0,0 +1,44 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *handler) Signup(c echo.Context) error {
+
+	var cred Credentials
+	err := c.Bind(&cred)
+	if err != nil {
+		return apperr.InvalidBody(c, err)
+	}
+
+	if details := cred.validate(); len(details) > 0 {
+		return c.JSON(
+			http.StatusBadRequest,
+			apperr.ErrorResponse{Code: http.StatusBadRequest, Message: "Validation failed", Details: details},
+		)
+	}
+
+	hash, err := HashPassword(cred.Password)
+	if err != nil {
+		return err
+	}
+
+	user := User{Username: cred.Username, PasswordHash: hash, Role: RoleUser}
+	err = h.repo.Create(c.Request().Context(), &user)
+	if errors.Is(err, ErrUsernameTaken) {
+		return c.JSON(
+			http.StatusConflict,
+			apperr.ErrorResponse{Code: http.StatusConflict, Message: "Username is already taken"},
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, user)
+}
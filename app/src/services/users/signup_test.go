@@ -0,0 +1,98 @@
+//go:build unit
+
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RTae/assessment/app/src/handlers"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignupHandler(t *testing.T) {
+	t.Run("Should create new user with hashed password", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		body := `{"username": "somchai", "password": "correct horse"}`
+		req := httptest.NewRequest(http.MethodPost, "/auth/signup", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		insertMockRow := mock.NewRows([]string{"id"}).AddRow("1")
+		mock.ExpectQuery("INSERT INTO users").
+			WithArgs("somchai", sqlmock.AnyArg(), RoleUser).
+			WillReturnRows(insertMockRow)
+
+		h := handler{NewPostgresRepository(db), nil}
+		c := e.NewContext(req, res)
+		expected := "{\"id\":1,\"username\":\"somchai\",\"role\":\"user\"}"
+
+		// Act
+		err := h.Signup(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusCreated, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("Should return conflict error if username is taken", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		body := `{"username": "somchai", "password": "correct horse"}`
+		req := httptest.NewRequest(http.MethodPost, "/auth/signup", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		db, mock, close := handlers.MockDatabase(t)
+		defer close()
+
+		mock.ExpectQuery("INSERT INTO users").WillReturnError(&pq.Error{Code: "23505"})
+
+		h := handler{NewPostgresRepository(db), nil}
+		c := e.NewContext(req, res)
+		expected := "{\"statusCode\":409,\"message\":\"Username is already taken\"}"
+
+		// Act
+		err := h.Signup(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusConflict, res.Code)
+			assert.Equal(t, expected, strings.TrimSpace(res.Body.String()))
+		}
+	})
+
+	t.Run("Should return bad request error if credentials are not valid", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		body := `{"username": "a b", "password": "short"}`
+		req := httptest.NewRequest(http.MethodPost, "/auth/signup", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		h := handler{NewMemoryRepository(), nil}
+		c := e.NewContext(req, res)
+
+		// Act
+		err := h.Signup(c)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, res.Code)
+			assert.Contains(t, res.Body.String(), `"field":"username"`)
+			assert.Contains(t, res.Body.String(), `"field":"password"`)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	accessToken  = "access"
+	refreshToken = "refresh"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Tokens signs and verifies HS256 JWTs. Access tokens authenticate API
+// requests; refresh tokens can only be exchanged for a new pair.
+type Tokens struct {
+	secret     []byte
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	now        func() time.Time
+}
+
+type claims struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	Type     string `json:"typ"`
+	jwt.StandardClaims
+}
+
+func NewTokens(secret string, accessTTL, refreshTTL time.Duration) *Tokens {
+	return &Tokens{
+		secret:     []byte(secret),
+		AccessTTL:  accessTTL,
+		RefreshTTL: refreshTTL,
+		now:        time.Now,
+	}
+}
+
+func (t *Tokens) sign(user User, typ string, ttl time.Duration) (string, error) {
+	now := t.now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		Username: user.Username,
+		Role:     user.Role,
+		Type:     typ,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   strconv.Itoa(user.ID),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
+	})
+	return token.SignedString(t.secret)
+}
+
+func (t *Tokens) Issue(user User) (TokenPair, error) {
+	access, err := t.sign(user, accessToken, t.AccessTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := t.sign(user, refreshToken, t.RefreshTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(t.AccessTTL.Seconds()),
+	}, nil
+}
+
+// parse verifies token and returns the user it was issued for. It fails with
+// ErrInvalidToken when the signature, expiry or token type does not match.
+func (t *Tokens) parse(token, typ string) (User, error) {
+	var c claims
+	parser := jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg()}, SkipClaimsValidation: true}
+	_, err := parser.ParseWithClaims(token, &c, func(*jwt.Token) (interface{}, error) {
+		return t.secret, nil
+	})
+	if err != nil {
+		return User{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if !c.VerifyExpiresAt(t.now().Unix(), true) || c.Type != typ {
+		return User{}, ErrInvalidToken
+	}
+
+	id, err := strconv.Atoi(c.Subject)
+	if err != nil {
+		return User{}, ErrInvalidToken
+	}
+	return User{ID: id, Username: c.Username, Role: c.Role}, nil
+}
@@ -0,0 +1,85 @@
+package users
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/RTae/assessment/app/src/apperr"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+type handler struct {
+	repo   UserRepository
+	tokens *Tokens
+}
+
+type User struct {
+	ID           int    `json:"id"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"-"`
+	Role         string `json:"role"`
+}
+
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+func CreateHandler(repo UserRepository, tokens *Tokens) *handler {
+	return &handler{repo, tokens}
+}
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,50}$`)
+
+// bcrypt ignores everything after the 72nd byte of a password.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+func (cred Credentials) validate() []apperr.FieldError {
+	var details []apperr.FieldError
+	if !usernamePattern.MatchString(cred.Username) {
+		details = append(details, apperr.FieldError{
+			Field:   "username",
+			Message: "must be 3 to 50 letters, digits, '_', '.' or '-'",
+		})
+	}
+	if utf8.RuneCountInString(cred.Password) < minPasswordLength {
+		details = append(details, apperr.FieldError{
+			Field:   "password",
+			Message: fmt.Sprintf("must be at least %d characters", minPasswordLength),
+		})
+	} else if len(cred.Password) > maxPasswordLength {
+		details = append(details, apperr.FieldError{
+			Field:   "password",
+			Message: fmt.Sprintf("must be at most %d bytes", maxPasswordLength),
+		})
+	}
+	return details
+}
+
+// SetCurrentUser attaches user to c the way Authenticate does.
+func SetCurrentUser(c echo.Context, user User) {
+	c.Set(contextKey, user)
+	c.Set("role", user.Role)
+}
+
+// CurrentUser returns the user attached to c by Authenticate.
+func CurrentUser(c echo.Context) (User, bool) {
+	user, ok := c.Get(contextKey).(User)
+	return user, ok
+}
@@ -1,11 +1,99 @@
 package settings
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 type Config struct {
 	Port        string
 	DatabaseUrl string
 	Url         string
+	// Storage selects the expense repository, "postgres" (default) or
+	// "memory".
+	Storage string
+	// Timezone is the IANA zone expense times are reported in and date-only
+	// filters are read in.
+	Timezone string
+
+	AdminUsername string
+	AdminPassword string
+
+	// JWTSecret signs access and refresh tokens and must be set.
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// RecurringInterval is how often due recurring expenses are turned into
+	// expenses.
+	RecurringInterval time.Duration
+
+	// AttachmentStorage keeps attachment files, "local" (default) under
+	// AttachmentDir, "s3" in an S3 compatible bucket or "memory".
+	AttachmentStorage string
+	AttachmentDir     string
+	// AttachmentMaxSize is the size limit of an attachment in bytes.
+	AttachmentMaxSize int64
+
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+
+	// IdempotencyTTL is how long the response to a request sent with an
+	// Idempotency-Key is remembered.
+	IdempotencyTTL time.Duration
+
+	// RequireIfMatch makes expense updates fail unless they send If-Match.
+	RequireIfMatch bool
+
+	// TracesExporter sends traces to OTLPEndpoint with "otlp", to standard
+	// output with "stdout", to TracesFile with "file" or nowhere with
+	// "none" (default).
+	TracesExporter string
+	TracesFile     string
+	OTLPEndpoint   string
+	ServiceName    string
+	// TracesSampleRatio is the share of the traces started here that are
+	// recorded. Requests with a traceparent follow its sampled flag.
+	TracesSampleRatio float64
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return d
+	}
+	return fallback
+}
+
+func getInt64(key string, fallback int64) int64 {
+	if n, err := strconv.ParseInt(os.Getenv(key), 10, 64); err == nil {
+		return n
+	}
+	return fallback
+}
+
+func getFloat(key string, fallback float64) float64 {
+	if f, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return f
+	}
+	return fallback
+}
+
+func getBool(key string, fallback bool) bool {
+	if b, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return b
+	}
+	return fallback
 }
 
 func Setting() Config {
@@ -13,5 +101,36 @@ func Setting() Config {
 		Port:        os.Getenv("PORT"),
 		DatabaseUrl: os.Getenv("DATABASE_URL"),
 		Url:         "localhost",
+		Storage:     getEnv("STORAGE", "postgres"),
+		Timezone:    getEnv("TIMEZONE", "UTC"),
+
+		AdminUsername: os.Getenv("ADMIN_USERNAME"),
+		AdminPassword: os.Getenv("ADMIN_PASSWORD"),
+
+		JWTSecret:       os.Getenv("JWT_SECRET"),
+		AccessTokenTTL:  getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getDuration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+
+		RecurringInterval: getDuration("RECURRING_INTERVAL", time.Minute),
+
+		AttachmentStorage: getEnv("ATTACHMENT_STORAGE", "local"),
+		AttachmentDir:     getEnv("ATTACHMENT_DIR", "attachments"),
+		AttachmentMaxSize: getInt64("ATTACHMENT_MAX_SIZE", 10<<20),
+
+		S3Endpoint:        getEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+		S3Region:          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:          os.Getenv("S3_BUCKET"),
+		S3AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+
+		IdempotencyTTL: getDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		RequireIfMatch: getBool("REQUIRE_IF_MATCH", false),
+
+		TracesExporter:    getEnv("TRACES_EXPORTER", "none"),
+		TracesFile:        getEnv("TRACES_FILE", "traces.jsonl"),
+		OTLPEndpoint:      getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+		ServiceName:       getEnv("OTEL_SERVICE_NAME", "expense-tracker"),
+		TracesSampleRatio: getFloat("TRACES_SAMPLE_RATIO", 1),
 	}
 }
@@ -0,0 +1,168 @@
+package statement
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseOFX reads the bank and credit card transactions of an OFX 1.x (SGML)
+// or 2.x (XML) file, QFX included. SGML leaves their elements unclosed, so
+// an element is read as a value when text follows its start tag and as an
+// aggregate otherwise.
+func ParseOFX(r io.Reader, o Options) (Statement, error) {
+	s := Statement{Format: FormatOFX}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return s, err
+	}
+	start := bytes.Index(bytes.ToUpper(raw), []byte("<OFX>"))
+	if start < 0 {
+		return s, errors.New("missing <OFX> element")
+	}
+
+	var (
+		txn      *Transaction
+		account  string
+		currency string
+	)
+	doc := string(raw[start:])
+	for len(doc) > 0 {
+		open := strings.IndexByte(doc, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(doc[open:], '>')
+		if end < 0 {
+			return s, errors.New("unterminated tag")
+		}
+		tag := strings.ToUpper(strings.TrimSpace(doc[open+1 : open+end]))
+		doc = doc[open+end+1:]
+		text := doc
+		if next := strings.IndexByte(doc, '<'); next >= 0 {
+			text = doc[:next]
+		}
+		value := html.UnescapeString(strings.TrimSpace(text))
+
+		switch {
+		case strings.HasPrefix(tag, "?"), strings.HasPrefix(tag, "!"):
+		case tag == "/STMTTRN":
+			if txn == nil {
+				return s, errors.New("</STMTTRN> without <STMTTRN>")
+			}
+			if err := txn.finish(len(s.Transactions)+1, account, currency); err != nil {
+				return s, err
+			}
+			s.Transactions = append(s.Transactions, *txn)
+			txn = nil
+		case strings.HasPrefix(tag, "/"):
+		case tag == "STMTTRN":
+			if txn != nil {
+				return s, fmt.Errorf("transaction %d: nested <STMTTRN>", len(s.Transactions)+1)
+			}
+			txn = &Transaction{}
+		case value == "":
+		case txn == nil:
+			switch tag {
+			case "ACCTID":
+				account = value
+			case "CURDEF":
+				currency = strings.ToUpper(value)
+			}
+		default:
+			if err := txn.set(tag, value, o); err != nil {
+				return s, fmt.Errorf("transaction %d: %v", len(s.Transactions)+1, err)
+			}
+		}
+	}
+	if txn != nil {
+		return s, errors.New("unterminated <STMTTRN>")
+	}
+	return s, nil
+}
+
+func (t *Transaction) set(tag, value string, o Options) error {
+	var err error
+	switch tag {
+	case "FITID":
+		t.ID = value
+	case "DTPOSTED":
+		t.Date, err = parseOFXDate(value, o.zone())
+	case "DTUSER":
+		// The date the user initiated the transaction only stands in for a
+		// missing posting date.
+		if t.Date.IsZero() {
+			t.Date, err = parseOFXDate(value, o.zone())
+		}
+	case "TRNAMT":
+		t.Amount, err = parseAmount(value, true)
+		if err != nil {
+			err = fmt.Errorf("TRNAMT %q is not a number", value)
+		}
+	case "NAME", "PAYEE":
+		t.Payee = value
+	case "MEMO":
+		t.Memo = value
+	case "CHECKNUM":
+		t.Number = value
+	case "CURSYM":
+		t.Currency = strings.ToUpper(value)
+	}
+	return err
+}
+
+func (t *Transaction) finish(n int, account, currency string) error {
+	if t.ID == "" {
+		return fmt.Errorf("transaction %d: missing FITID", n)
+	}
+	if t.Date.IsZero() {
+		return fmt.Errorf("transaction %d: missing DTPOSTED", n)
+	}
+	t.Account = account
+	if t.Currency == "" {
+		t.Currency = currency
+	}
+	return nil
+}
+
+var ofxDateLayouts = map[int]string{
+	8:  "20060102",
+	12: "200601021504",
+	14: "20060102150405",
+}
+
+// parseOFXDate reads YYYYMMDD[HHMMSS[.XXX]][[offset:TZ]]. A date without an
+// offset is taken in loc.
+func parseOFXDate(raw string, loc *time.Location) (time.Time, error) {
+	value := raw
+	if i := strings.IndexByte(value, '['); i >= 0 {
+		zone := strings.TrimSuffix(value[i+1:], "]")
+		value = value[:i]
+		name := ""
+		if j := strings.IndexByte(zone, ':'); j >= 0 {
+			zone, name = zone[:j], zone[j+1:]
+		}
+		hours, err := strconv.ParseFloat(zone, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("date %q has an invalid offset", raw)
+		}
+		loc = time.FixedZone(name, int(hours*60*60))
+	}
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		value = value[:i]
+	}
+	layout, ok := ofxDateLayouts[len(value)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("date %q is not YYYYMMDD[HHMMSS]", raw)
+	}
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date %q is not YYYYMMDD[HHMMSS]", raw)
+	}
+	return t, nil
+}
@@ -0,0 +1,172 @@
+package statement
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// qifTypes are the QIF sections holding bank style transactions. Others,
+// such as categories or investments, are skipped.
+var qifTypes = map[string]bool{
+	"bank": true, "cash": true, "ccard": true, "oth a": true, "oth l": true,
+}
+
+// ParseQIF reads the bank, cash and credit card transactions of a QIF file.
+// QIF carries no transaction ids, so each transaction is identified by a
+// hash of its account, date, amount, payee, memo and number, counting
+// identical entries within the file apart.
+func ParseQIF(r io.Reader, o Options) (Statement, error) {
+	s := Statement{Format: FormatQIF}
+	scanner := bufio.NewScanner(r)
+
+	var (
+		txn       Transaction
+		started   bool
+		line      int
+		account   string
+		inAccount bool
+		skip      bool
+	)
+	seen := map[string]int{}
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\xef\xbb\xbf")
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "!") {
+			header := strings.ToLower(strings.TrimSpace(text))
+			switch {
+			case header == "!account":
+				inAccount = true
+			case strings.HasPrefix(header, "!type:"):
+				inAccount = false
+				skip = !qifTypes[strings.TrimSpace(strings.TrimPrefix(header, "!type:"))]
+			}
+			continue
+		}
+
+		code, value := text[0], strings.TrimSpace(text[1:])
+		if inAccount {
+			switch code {
+			case 'N':
+				account = value
+			case '^':
+				inAccount = false
+			}
+			continue
+		}
+		if skip {
+			continue
+		}
+
+		var err error
+		switch code {
+		case '^':
+			if !started {
+				continue
+			}
+			if txn.Date.IsZero() {
+				return s, fmt.Errorf("line %d: transaction has no date", line)
+			}
+			txn.Account = account
+			txn.ID = qifID(txn)
+			seen[txn.ID]++
+			if n := seen[txn.ID]; n > 1 {
+				txn.ID += "-" + strconv.Itoa(n)
+			}
+			s.Transactions = append(s.Transactions, txn)
+			txn, started = Transaction{}, false
+			continue
+		case 'D':
+			txn.Date, err = parseQIFDate(value, o)
+		case 'T', 'U':
+			if code == 'U' && txn.Amount != 0 {
+				break
+			}
+			txn.Amount, err = parseAmount(value, false)
+			if err != nil {
+				err = fmt.Errorf("amount %q is not a number", value)
+			}
+		case 'P':
+			txn.Payee = value
+		case 'M':
+			txn.Memo = value
+		case 'N':
+			txn.Number = value
+		}
+		if err != nil {
+			return s, fmt.Errorf("line %d: %v", line, err)
+		}
+		started = true
+	}
+	if err := scanner.Err(); err != nil {
+		return s, err
+	}
+	if started {
+		return s, fmt.Errorf("line %d: transaction is not ended with ^", line)
+	}
+	return s, nil
+}
+
+func qifID(t Transaction) string {
+	sum := sha1.Sum([]byte(strings.Join([]string{
+		t.Account, t.Date.Format("2006-01-02"), t.Amount.String(), t.Payee, t.Memo, t.Number,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseQIFDate reads the date styles Quicken and banks write, such as
+// 1/2/2024, 01/02'24, 1/ 2/24, 02.01.2024 or 2024-01-02. Two digit years
+// after an apostrophe or below 70 are in the 2000s.
+func parseQIFDate(raw string, o Options) (time.Time, error) {
+	millennium := strings.Contains(raw, "'")
+	fields := strings.FieldsFunc(strings.ReplaceAll(raw, " ", ""), func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '\''
+	})
+	invalid := fmt.Errorf("date %q is not a valid date", raw)
+	if len(fields) != 3 {
+		return time.Time{}, invalid
+	}
+	var parts [3]int
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		parts[i] = n
+	}
+
+	var year, month, day int
+	switch {
+	case len(fields[0]) == 4:
+		year, month, day = parts[0], parts[1], parts[2]
+	case o.DayFirst:
+		day, month, year = parts[0], parts[1], parts[2]
+	default:
+		month, day, year = parts[0], parts[1], parts[2]
+	}
+	if year < 100 {
+		if millennium || year < 70 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, o.zone())
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day {
+		return time.Time{}, invalid
+	}
+	return t, nil
+}
@@ -0,0 +1,120 @@
+// Package statement reads the transactions of bank statements in the OFX
+// and QIF formats.
+package statement
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/RTae/assessment/app/src/money"
+)
+
+const (
+	FormatOFX = "ofx"
+	FormatQIF = "qif"
+)
+
+// ErrUnknownFormat is returned by Detect for content that is neither OFX
+// nor QIF.
+var ErrUnknownFormat = errors.New("statement is neither OFX nor QIF")
+
+// Transaction is one entry of a statement. Amount is negative for money
+// leaving the account.
+type Transaction struct {
+	// ID is the FITID the bank gave the transaction. QIF has none, so one is
+	// derived from the content of the entry.
+	ID string
+	// Account is the ACCTID of the OFX statement the transaction is on,
+	// empty for QIF.
+	Account string
+	Date    time.Time
+	Amount  money.Amount
+	Payee   string
+	Memo    string
+	Number  string
+	// Currency is the ISO 4217 code of the amount, empty when the file does
+	// not say.
+	Currency string
+}
+
+// Debit tells whether the transaction takes money out of the account.
+func (t Transaction) Debit() bool {
+	return t.Amount < 0
+}
+
+type Statement struct {
+	Format       string
+	Transactions []Transaction
+}
+
+// Key identifies t across imports of statements from the same source, so a
+// transaction seen again in an overlapping statement can be recognized.
+func (s Statement) Key(t Transaction) string {
+	if s.Format == FormatOFX {
+		return s.Format + ":" + t.Account + ":" + t.ID
+	}
+	return s.Format + ":" + t.ID
+}
+
+// Options tunes how ambiguous values are read.
+type Options struct {
+	// Location is the zone of dates that carry no offset, UTC when nil.
+	Location *time.Location
+	// DayFirst reads QIF dates as day/month/year instead of the US
+	// month/day/year.
+	DayFirst bool
+}
+
+func (o Options) zone() *time.Location {
+	if o.Location == nil {
+		return time.UTC
+	}
+	return o.Location
+}
+
+// Detect sniffs the format from the start of a statement.
+func Detect(head []byte) (string, error) {
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+	upper := strings.ToUpper(string(head))
+	switch {
+	case strings.HasPrefix(upper, "OFXHEADER"), strings.HasPrefix(upper, "<?XML"), strings.HasPrefix(upper, "<OFX"):
+		return FormatOFX, nil
+	case strings.HasPrefix(upper, "!TYPE:"), strings.HasPrefix(upper, "!ACCOUNT"), strings.HasPrefix(upper, "!OPTION:"):
+		return FormatQIF, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Parse reads a statement in format, detecting it when format is empty.
+func Parse(r io.Reader, format string, o Options) (Statement, error) {
+	br := bufio.NewReader(r)
+	if format == "" {
+		head, _ := br.Peek(512)
+		var err error
+		if format, err = Detect(head); err != nil {
+			return Statement{}, err
+		}
+	}
+	switch format {
+	case FormatOFX:
+		return ParseOFX(br, o)
+	case FormatQIF:
+		return ParseQIF(br, o)
+	}
+	return Statement{}, ErrUnknownFormat
+}
+
+// parseAmount reads an amount. OFX allows a comma as the decimal separator,
+// while QIF uses it to group thousands.
+func parseAmount(raw string, decimalComma bool) (money.Amount, error) {
+	if decimalComma {
+		raw = strings.Replace(raw, ",", ".", 1)
+	} else {
+		raw = strings.ReplaceAll(raw, ",", "")
+	}
+	return money.Parse(raw)
+}
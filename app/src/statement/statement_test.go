@@ -0,0 +1,250 @@
+//go:build unit
+
+package statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RTae/assessment/app/src/money"
+	"github.com/stretchr/testify/assert"
+)
+
+var bangkok = time.FixedZone("ICT", 7*60*60)
+
+const ofxSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20240131120000<LANGUAGE>ENG</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<TRNUID>1
+<STMTRS>
+<CURDEF>thb
+<BANKACCTFROM><BANKID>004<ACCTID>123-4-56789<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240101
+<DTEND>20240131
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240102120000.000[-5:EST]
+<TRNAMT>-50.25
+<FITID>2024010201
+<NAME>Tops &amp; Co
+<MEMO>groceries
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240105
+<TRNAMT>15000,00
+<FITID>2024010501
+<NAME>Salary
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CHECK
+<DTUSER>20240107
+<TRNAMT>-1200
+<FITID>2024010701
+<CHECKNUM>1001
+<PAYEE><NAME>Landlord<ADDR1>1 Main St</PAYEE>
+<CURRENCY><CURRATE>1.0<CURSYM>usd</CURRENCY>
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxXML = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <CCSTMTRS>
+        <CURDEF>THB</CURDEF>
+        <CCACCTFROM><ACCTID>4111</ACCTID></CCACCTFROM>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240102</DTPOSTED>
+            <TRNAMT>-99.5</TRNAMT>
+            <FITID>A1</FITID>
+            <MEMO>coffee</MEMO>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+const qif = "\xef\xbb\xbf!Account\nNChecking\nTBank\n^\n!Type:Bank\n" +
+	"D01/02'24\nT-1,050.25\nPTops\nMgroceries\nN1001\nLFood\n^\n" +
+	"D1/ 5/2024\nT15,000.00\nPSalary\n^\n" +
+	"D2024-01-07\nU-20.00\nT-20.00\nPTops\n^\n" +
+	"D2024-01-07\nT-20.00\nPTops\n^\n" +
+	"!Type:Cat\nNFood\n^\n"
+
+func TestParseOFX(t *testing.T) {
+	t.Run("Should read an OFX 1.x SGML statement", func(t *testing.T) {
+		// Act
+		st, err := Parse(strings.NewReader(ofxSGML), "", Options{Location: bangkok})
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, FormatOFX, st.Format)
+			assert.Equal(t, []Transaction{
+				{ID: "2024010201", Account: "123-4-56789", Date: time.Date(2024, 1, 2, 12, 0, 0, 0, time.FixedZone("EST", -5*60*60)), Amount: money.Amount(-502500), Payee: "Tops & Co", Memo: "groceries", Currency: "THB"},
+				{ID: "2024010501", Account: "123-4-56789", Date: time.Date(2024, 1, 5, 0, 0, 0, 0, bangkok), Amount: money.Amount(150000000), Payee: "Salary", Currency: "THB"},
+				{ID: "2024010701", Account: "123-4-56789", Date: time.Date(2024, 1, 7, 0, 0, 0, 0, bangkok), Amount: money.Amount(-12000000), Payee: "Landlord", Number: "1001", Currency: "USD"},
+			}, st.Transactions)
+			assert.Equal(t, "ofx:123-4-56789:2024010201", st.Key(st.Transactions[0]))
+			assert.True(t, st.Transactions[0].Debit())
+			assert.False(t, st.Transactions[1].Debit())
+		}
+	})
+
+	t.Run("Should read an OFX 2.x XML statement", func(t *testing.T) {
+		// Act
+		st, err := Parse(strings.NewReader(ofxXML), "", Options{})
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, []Transaction{
+				{ID: "A1", Account: "4111", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Amount: money.Amount(-995000), Memo: "coffee", Currency: "THB"},
+			}, st.Transactions)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"Should return error without OFX element", "OFXHEADER:100\n", "missing <OFX> element"},
+		{"Should return error for missing FITID", "<OFX><STMTTRN><DTPOSTED>20240102<TRNAMT>-1</STMTTRN></OFX>", "transaction 1: missing FITID"},
+		{"Should return error for missing date", "<OFX><STMTTRN><FITID>1<TRNAMT>-1</STMTTRN></OFX>", "transaction 1: missing DTPOSTED"},
+		{"Should return error for invalid amount", "<OFX><STMTTRN><TRNAMT>ten</STMTTRN></OFX>", `transaction 1: TRNAMT "ten" is not a number`},
+		{"Should return error for invalid date", "<OFX><STMTTRN><DTPOSTED>2024-01-02</STMTTRN></OFX>", `transaction 1: date "2024-01-02" is not YYYYMMDD[HHMMSS]`},
+		{"Should return error for invalid offset", "<OFX><STMTTRN><DTPOSTED>20240102[x:EST]</STMTTRN></OFX>", `transaction 1: date "20240102[x:EST]" has an invalid offset`},
+		{"Should return error for unterminated transaction", "<OFX><STMTTRN><FITID>1</OFX>", "unterminated <STMTTRN>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := ParseOFX(strings.NewReader(tt.body), Options{})
+
+			// Assert
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
+
+func TestParseQIF(t *testing.T) {
+	t.Run("Should read the bank transactions of a QIF file", func(t *testing.T) {
+		// Act
+		st, err := Parse(strings.NewReader(qif), "", Options{Location: bangkok})
+
+		// Assert
+		if assert.NoError(t, err) && assert.Len(t, st.Transactions, 4) {
+			assert.Equal(t, FormatQIF, st.Format)
+			tops := st.Transactions[0]
+			assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, bangkok), tops.Date)
+			assert.Equal(t, money.Amount(-10502500), tops.Amount)
+			assert.Equal(t, "Tops", tops.Payee)
+			assert.Equal(t, "groceries", tops.Memo)
+			assert.Equal(t, "1001", tops.Number)
+			assert.Equal(t, "Checking", tops.Account)
+			assert.Len(t, tops.ID, 40)
+			assert.Equal(t, "qif:"+tops.ID, st.Key(tops))
+			assert.Equal(t, money.Amount(150000000), st.Transactions[1].Amount)
+			assert.Equal(t, time.Date(2024, 1, 5, 0, 0, 0, 0, bangkok), st.Transactions[1].Date)
+			assert.Equal(t, st.Transactions[2].ID+"-2", st.Transactions[3].ID)
+		}
+	})
+
+	t.Run("Should give the same transaction the same id in every file", func(t *testing.T) {
+		// Act
+		first, _ := ParseQIF(strings.NewReader("!Type:CCard\nD01/02/2024\nT-5\nPTea\n^\n"), Options{})
+		second, _ := ParseQIF(strings.NewReader("!Type:CCard\nD01/01/2024\nT-7\nPCake\n^\nD01/02/2024\nT-5\nPTea\n^\n"), Options{})
+
+		// Assert
+		assert.Equal(t, first.Transactions[0].ID, second.Transactions[1].ID)
+	})
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"Should return error for an invalid date", "!Type:Bank\nD13/45/2024\nT-1\n^\n", `line 2: date "13/45/2024" is not a valid date`},
+		{"Should return error for an invalid amount", "!Type:Bank\nD01/02/2024\nTten\n^\n", `line 3: amount "ten" is not a number`},
+		{"Should return error for a transaction without date", "!Type:Bank\nT-1\n^\n", "line 3: transaction has no date"},
+		{"Should return error for an unended transaction", "!Type:Bank\nD01/02/2024\nT-1\n", "line 3: transaction is not ended with ^"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := ParseQIF(strings.NewReader(tt.body), Options{})
+
+			// Assert
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
+
+func TestParseQIFDate(t *testing.T) {
+	tests := []struct {
+		raw      string
+		dayFirst bool
+		expected time.Time
+	}{
+		{"1/2/2024", false, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"1/2/2024", true, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"01/02'24", false, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"12/31/99", false, time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"31.12.2023", true, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"2024-01-02", true, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			actual, err := parseQIFDate(tt.raw, Options{DayFirst: tt.dayFirst})
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		head     string
+		expected string
+	}{
+		{"OFXHEADER:100\n", FormatOFX},
+		{"\xef\xbb\xbf<?xml version=\"1.0\"?>", FormatOFX},
+		{"  <OFX>", FormatOFX},
+		{"!Type:Bank\n", FormatQIF},
+		{"!Account\n", FormatQIF},
+	}
+	for _, tt := range tests {
+		actual, err := Detect([]byte(tt.head))
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, actual, tt.head)
+	}
+
+	_, err := Detect([]byte("title,amount\n"))
+	assert.Equal(t, ErrUnknownFormat, err)
+}
@@ -0,0 +1,101 @@
+//go:build unit
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSpans() []*Span {
+	sc, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []*Span{{
+		Name:          "SELECT",
+		Kind:          KindClient,
+		Context:       SpanContext{TraceID: sc.TraceID, SpanID: SpanID{1, 2, 3, 4, 5, 6, 7, 8}, Sampled: true},
+		Parent:        sc.SpanID,
+		StartTime:     start,
+		EndTime:       start.Add(time.Millisecond),
+		Attributes:    []Attribute{String("db.system", "postgresql"), Int("rows", 2), Bool("cached", false)},
+		Status:        StatusError,
+		StatusMessage: "deadlock detected",
+	}}
+}
+
+const otlpTestSpans = `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"expense-tracker"}}]},` +
+	`"scopeSpans":[{"scope":{"name":"github.com/RTae/assessment/app/src/tracing"},"spans":[{` +
+	`"traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"0102030405060708","parentSpanId":"00f067aa0ba902b7",` +
+	`"name":"SELECT","kind":3,"startTimeUnixNano":"1704164645000000000","endTimeUnixNano":"1704164645001000000",` +
+	`"attributes":[{"key":"db.system","value":{"stringValue":"postgresql"}},{"key":"rows","value":{"intValue":"2"}},{"key":"cached","value":{"boolValue":false}}],` +
+	`"status":{"code":2,"message":"deadlock detected"}}]}]}]}`
+
+func TestOTLPExporter(t *testing.T) {
+	t.Run("Should post spans as OTLP JSON", func(t *testing.T) {
+		// Arrange
+		var path, contentType string
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+			body, _ = io.ReadAll(r.Body)
+		}))
+		defer server.Close()
+		exporter := NewOTLPExporter(server.URL+"/", "expense-tracker")
+
+		// Act
+		err := exporter.ExportSpans(context.Background(), testSpans())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "/v1/traces", path)
+		assert.Equal(t, "application/json", contentType)
+		assert.JSONEq(t, otlpTestSpans, string(body))
+	})
+
+	t.Run("Should report a refused export", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		exporter := NewOTLPExporter(server.URL, "expense-tracker")
+
+		// Act
+		err := exporter.ExportSpans(context.Background(), testSpans())
+
+		// Assert
+		assert.ErrorContains(t, err, "503")
+	})
+}
+
+func TestFileExporter(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	exporter, err := NewFileExporter(path, "expense-tracker")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	exporter.ExportSpans(context.Background(), testSpans())
+	exporter.ExportSpans(context.Background(), testSpans())
+	err = exporter.Shutdown(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	data, _ := os.ReadFile(path)
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	assert.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.JSONEq(t, otlpTestSpans, string(line))
+	}
+}
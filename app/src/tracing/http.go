@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/RTae/assessment/app/src/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+)
+
+// Middleware starts a server span per request, continuing the trace of a
+// traceparent header, and hands it to the handler through the request
+// context so the work done for the request is traced as its children.
+func Middleware(t *Tracer) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := req.Context()
+			if sc, ok := ParseTraceparent(req.Header.Get(HeaderTraceparent)); ok {
+				sc.TraceState = req.Header.Get(HeaderTracestate)
+				ctx = ContextWithSpanContext(ctx, sc)
+			}
+
+			name := req.Method
+			attrs := []Attribute{
+				String("http.method", req.Method),
+				String("http.target", req.URL.RequestURI()),
+			}
+			if route := metrics.Route(c); route != "" {
+				name += " " + route
+				attrs = append(attrs, String("http.route", route))
+			}
+			if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+				attrs = append(attrs, String("http.request_id", id))
+			}
+			ctx, span := t.Start(ctx, name, KindServer, attrs...)
+			defer span.End()
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+			status := c.Response().Status
+			span.SetAttributes(Int("http.status_code", status))
+			if status >= http.StatusInternalServerError {
+				if err == nil {
+					err = errStatus(status)
+				}
+				span.SetError(err)
+			}
+			return nil
+		}
+	}
+}
+
+// errStatus is the error of a request answered with a server error status.
+type errStatus int
+
+func (s errStatus) Error() string { return http.StatusText(int(s)) }
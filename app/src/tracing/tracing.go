@@ -0,0 +1,335 @@
+// Package tracing records OpenTelemetry compatible traces: a span per HTTP
+// request, continued from a W3C traceparent header, with child spans for
+// the SQL statements issued while serving it.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid tells whether id is not all zeros.
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid tells whether id is not all zeros.
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// SpanContext is the part of a span that is propagated to other services.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+// IsValid tells whether sc names a trace and a span.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats sc as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent reads a W3C traceparent header value. Versions after 00
+// are read as far as version 00 goes, as the specification asks.
+func ParseTraceparent(s string) (SpanContext, bool) {
+	var sc SpanContext
+	var version, flags [1]byte
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return sc, false
+	}
+	if !decodeHex(s[:2], version[:]) || version[0] == 0xff {
+		return sc, false
+	}
+	// Version 00 is exactly 55 characters, later versions may add fields.
+	if (version[0] == 0 && len(s) != 55) || (len(s) > 55 && s[55] != '-') {
+		return sc, false
+	}
+	if !decodeHex(s[3:35], sc.TraceID[:]) || !decodeHex(s[36:52], sc.SpanID[:]) || !decodeHex(s[53:55], flags[:]) || !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, true
+}
+
+// decodeHex decodes lowercase hex s into dst.
+func decodeHex(s string, dst []byte) bool {
+	if strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// SpanKind is the role of a span, numbered as in OTLP.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// StatusCode is the outcome of a span, numbered as in OTLP.
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Attribute is a key and a string, int, int64, float64 or bool value.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key, value string) Attribute    { return Attribute{Key: key, Value: value} }
+func Int(key string, value int) Attribute   { return Attribute{Key: key, Value: int64(value)} }
+func Bool(key string, value bool) Attribute { return Attribute{Key: key, Value: value} }
+
+// Span is a timed operation. The methods of a nil Span do nothing, so code
+// can trace whether or not a Tracer is configured. Its fields must not be
+// changed once it has ended.
+type Span struct {
+	Name          string
+	Kind          SpanKind
+	Context       SpanContext
+	Parent        SpanID
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    []Attribute
+	Status        StatusCode
+	StatusMessage string
+
+	tracer *Tracer
+	mu     sync.Mutex
+	ended  bool
+}
+
+// SetAttributes adds attrs to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.Attributes = append(s.Attributes, attrs...)
+	}
+}
+
+// SetError marks the span as failed with err.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.Status, s.StatusMessage = StatusError, err.Error()
+	}
+}
+
+// End records the end of the span and hands it to the exporter if it is
+// sampled. Only the first call counts.
+func (s *Span) End() {
+	if s == nil || s.tracer == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = s.tracer.now()
+	s.mu.Unlock()
+	if s.Context.Sampled {
+		s.tracer.enqueue(s)
+	}
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns ctx carrying span as the parent of the spans
+// started from it.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// ContextWithSpanContext returns ctx carrying a span of another service,
+// such as one read from a traceparent header, as the parent of the spans
+// started from it.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return ContextWithSpan(ctx, &Span{Context: sc})
+}
+
+// SpanFromContext returns the span carried by ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+const (
+	maxQueue       = 2048
+	maxBatch       = 512
+	exportInterval = 5 * time.Second
+)
+
+// Tracer starts spans and exports the sampled ones in batches in the
+// background. A nil Tracer starts no spans.
+type Tracer struct {
+	exporter Exporter
+	ratio    float64
+	now      func() time.Time
+
+	queue chan *Span
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewTracer returns a Tracer that records the share ratio of the traces it
+// starts and every trace continued from a sampled parent.
+func NewTracer(exporter Exporter, ratio float64) *Tracer {
+	t := &Tracer{
+		exporter: exporter,
+		ratio:    ratio,
+		now:      time.Now,
+		queue:    make(chan *Span, maxQueue),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Start starts a span named name as a child of the span carried by ctx,
+// or as the root of a new trace, and returns ctx carrying it.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{Name: name, Kind: kind, Attributes: attrs, StartTime: t.now(), tracer: t}
+	if parent := SpanFromContext(ctx); parent != nil && parent.Context.IsValid() {
+		span.Context = parent.Context
+		span.Parent = parent.Context.SpanID
+	} else {
+		span.Context.TraceID = newTraceID()
+		span.Context.Sampled = t.sample(span.Context.TraceID)
+	}
+	span.Context.SpanID = newSpanID()
+	return ContextWithSpan(ctx, span), span
+}
+
+// sample decides from the trace id so every service sampling at the same
+// ratio keeps the same traces.
+func (t *Tracer) sample(id TraceID) bool {
+	if t.ratio >= 1 {
+		return true
+	}
+	return float64(binary.BigEndian.Uint64(id[8:])>>11)/(1<<53) < t.ratio
+}
+
+func (t *Tracer) enqueue(s *Span) {
+	select {
+	case t.queue <- s:
+	default:
+		// The exporter is falling behind; drop the span rather than block
+		// the request.
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), exportInterval)
+		defer cancel()
+		if err := t.exporter.ExportSpans(ctx, batch); err != nil {
+			log.Printf("tracing: dropped %d spans: %v", len(batch), err)
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case s := <-t.queue:
+			if batch = append(batch, s); len(batch) == maxBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stop:
+			for {
+				select {
+				case s := <-t.queue:
+					batch = append(batch, s)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// Shutdown exports the spans ended so far and shuts the exporter down,
+// giving up when ctx ends.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.once.Do(func() { close(t.stop) })
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+func newTraceID() (id TraceID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,291 @@
+//go:build unit
+
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder keeps the spans exported to it.
+type recorder struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (r *recorder) ExportSpans(ctx context.Context, spans []*Span) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func (r *recorder) Shutdown(ctx context.Context) error { return nil }
+
+func (r *recorder) byName(name string) *Span {
+	for _, s := range r.spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func attribute(s *Span, key string) interface{} {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return nil
+}
+
+// connector opens connections of a registered driver such as sqlmock.
+type connector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+func (c connector) Driver() driver.Driver                            { return c.driver }
+
+func mockDB(t *testing.T, tracer *Tracer) (*sql.DB, sqlmock.Sqlmock) {
+	dsn := t.Name()
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { mockDB.Close() })
+	db := OpenDB(tracer, "postgresql", connector{driver: mockDB.Driver(), dsn: dsn})
+	t.Cleanup(func() { db.Close() })
+	return db, mock
+}
+
+func TestParseTraceparent(t *testing.T) {
+	t.Run("Should read a traceparent and write it back", func(t *testing.T) {
+		for _, header := range []string{
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		} {
+			// Act
+			sc, ok := ParseTraceparent(header)
+
+			// Assert
+			assert.True(t, ok, header)
+			assert.Equal(t, header, sc.Traceparent())
+		}
+	})
+
+	t.Run("Should read what it knows of a later version", func(t *testing.T) {
+		// Act
+		sc, ok := ParseTraceparent("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-09-what-comes-next")
+
+		// Assert
+		assert.True(t, ok)
+		assert.True(t, sc.Sampled)
+		assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	})
+
+	t.Run("Should reject a malformed traceparent", func(t *testing.T) {
+		for _, header := range []string{
+			"",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01",
+		} {
+			// Act
+			_, ok := ParseTraceparent(header)
+
+			// Assert
+			assert.False(t, ok, header)
+		}
+	})
+}
+
+func TestTracer(t *testing.T) {
+	t.Run("Should sample by ratio and keep whole traces", func(t *testing.T) {
+		// Arrange
+		exporter := &recorder{}
+		tracer := NewTracer(exporter, 0)
+
+		// Act
+		ctx, root := tracer.Start(context.Background(), "root", KindInternal)
+		_, child := tracer.Start(ctx, "child", KindInternal)
+		child.End()
+		root.End()
+		tracer.Shutdown(context.Background())
+
+		// Assert
+		assert.False(t, root.Context.Sampled)
+		assert.Equal(t, root.Context.TraceID, child.Context.TraceID)
+		assert.Equal(t, root.Context.SpanID, child.Parent)
+		assert.Empty(t, exporter.spans)
+	})
+
+	t.Run("Should do nothing without a tracer", func(t *testing.T) {
+		// Arrange
+		var tracer *Tracer
+
+		// Act
+		ctx, span := tracer.Start(context.Background(), "root", KindInternal)
+		span.SetAttributes(String("key", "value"))
+		span.SetError(errors.New("boom"))
+		span.End()
+
+		// Assert
+		assert.Nil(t, span)
+		assert.Nil(t, SpanFromContext(ctx))
+		assert.NoError(t, tracer.Shutdown(context.Background()))
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	serve := func(t *testing.T, header string) (*recorder, sqlmock.Sqlmock, *httptest.ResponseRecorder) {
+		exporter := &recorder{}
+		tracer := NewTracer(exporter, 1)
+		db, mock := mockDB(t, tracer)
+
+		e := echo.New()
+		e.Use(Middleware(tracer))
+		e.GET("/expenses/:id", func(c echo.Context) error {
+			ctx := c.Request().Context()
+			var title string
+			if err := db.QueryRowContext(ctx, "SELECT title FROM expenses WHERE id = $1", c.Param("id")).Scan(&title); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, "UPDATE expenses SET title = $1", title); err != nil {
+				return err
+			}
+			return c.String(http.StatusOK, title)
+		})
+		mock.ExpectQuery("SELECT title FROM expenses").WithArgs("1").
+			WillReturnRows(sqlmock.NewRows([]string{"title"}).AddRow("rice"))
+		mock.ExpectExec("UPDATE expenses").WithArgs("rice").WillReturnError(errors.New("deadlock detected"))
+
+		req := httptest.NewRequest(http.MethodGet, "/expenses/1", nil)
+		req.Header.Set(HeaderTraceparent, header)
+		req.Header.Set(HeaderTracestate, "vendor=value")
+		res := httptest.NewRecorder()
+		e.ServeHTTP(res, req)
+		tracer.Shutdown(context.Background())
+		return exporter, mock, res
+	}
+
+	t.Run("Should continue the trace of the request with a span per SQL statement", func(t *testing.T) {
+		// Act
+		exporter, mock, res := serve(t, traceparent)
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Len(t, exporter.spans, 3)
+
+		server := exporter.byName("GET /expenses/:id")
+		if assert.NotNil(t, server) {
+			assert.Equal(t, KindServer, server.Kind)
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.Context.TraceID.String())
+			assert.Equal(t, "00f067aa0ba902b7", server.Parent.String())
+			assert.Equal(t, "vendor=value", server.Context.TraceState)
+			assert.Equal(t, "/expenses/:id", attribute(server, "http.route"))
+			assert.Equal(t, int64(http.StatusInternalServerError), attribute(server, "http.status_code"))
+			assert.Equal(t, StatusError, server.Status)
+		}
+
+		query := exporter.byName("SELECT")
+		if assert.NotNil(t, query) {
+			assert.Equal(t, KindClient, query.Kind)
+			assert.Equal(t, server.Context.SpanID, query.Parent)
+			assert.Equal(t, "SELECT title FROM expenses WHERE id = $1", attribute(query, "db.statement"))
+			assert.Equal(t, "postgresql", attribute(query, "db.system"))
+			assert.Equal(t, StatusUnset, query.Status)
+		}
+
+		update := exporter.byName("UPDATE")
+		if assert.NotNil(t, update) {
+			assert.Equal(t, server.Context.SpanID, update.Parent)
+			assert.Equal(t, StatusError, update.Status)
+			assert.Equal(t, "deadlock detected", update.StatusMessage)
+		}
+	})
+
+	t.Run("Should not record a trace its caller did not sample", func(t *testing.T) {
+		// Act
+		exporter, _, _ := serve(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+		// Assert
+		assert.Empty(t, exporter.spans)
+	})
+
+	t.Run("Should start a trace for a request without traceparent", func(t *testing.T) {
+		// Act
+		exporter, _, _ := serve(t, "")
+
+		// Assert
+		server := exporter.byName("GET /expenses/:id")
+		if assert.NotNil(t, server) {
+			assert.False(t, server.Parent.IsValid())
+			assert.NotEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.Context.TraceID.String())
+		}
+	})
+}
+
+func TestOpenDB(t *testing.T) {
+	t.Run("Should not trace a statement run outside of a span", func(t *testing.T) {
+		// Arrange
+		exporter := &recorder{}
+		tracer := NewTracer(exporter, 1)
+		db, mock := mockDB(t, tracer)
+		mock.ExpectExec("DELETE FROM idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 3))
+
+		// Act
+		_, err := db.ExecContext(context.Background(), "DELETE FROM idempotency_keys")
+		tracer.Shutdown(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, exporter.spans)
+	})
+
+	t.Run("Should trace each run of a prepared statement", func(t *testing.T) {
+		// Arrange
+		exporter := &recorder{}
+		tracer := NewTracer(exporter, 1)
+		db, mock := mockDB(t, tracer)
+		mock.ExpectPrepare("INSERT INTO tags").
+			ExpectExec().WithArgs("food").WillReturnResult(sqlmock.NewResult(1, 1))
+		ctx, span := tracer.Start(context.Background(), "import", KindInternal)
+
+		// Act
+		stmt, err := db.PrepareContext(ctx, "INSERT INTO tags VALUES ($1)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = stmt.ExecContext(ctx, "food")
+		stmt.Close()
+		span.End()
+		tracer.Shutdown(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+		insert := exporter.byName("INSERT")
+		if assert.NotNil(t, insert) {
+			assert.Equal(t, span.Context.SpanID, insert.Parent)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+//go:build unit
+
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumn(t *testing.T) {
+	tests := []struct {
+		index    int
+		expected string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, column(tt.index))
+	}
+}
+
+func TestSerial(t *testing.T) {
+	assert.Equal(t, 45293.0, serial(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 45293.5, serial(time.Date(2024, 1, 2, 12, 0, 0, 0, time.FixedZone("ICT", 7*60*60))))
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("Should write a well formed workbook", func(t *testing.T) {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, "Spending & more")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, w.WriteRow("title", "amount", "at"))
+		assert.NoError(t, w.WriteRow("<rice>", Number("50.25"), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), nil, 3))
+		assert.NoError(t, w.Close())
+
+		book, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if !assert.NoError(t, err) {
+			return
+		}
+		files := map[string]string{}
+		for _, f := range book.File {
+			r, err := f.Open()
+			if !assert.NoError(t, err) {
+				return
+			}
+			raw, _ := io.ReadAll(r)
+			files[f.Name] = string(raw)
+
+			var doc interface{}
+			assert.NoError(t, xml.Unmarshal(raw, &doc), f.Name)
+		}
+
+		assert.Contains(t, files["xl/workbook.xml"], `name="Spending &amp; more"`)
+		sheet := files["xl/worksheets/sheet1.xml"]
+		assert.Contains(t, sheet, `<row r="2"><c r="A2" t="inlineStr"><is><t xml:space="preserve">&lt;rice&gt;</t></is></c><c r="B2"><v>50.25</v></c><c r="C2" s="1"><v>45293</v></c><c r="E2"><v>3</v></c></row>`)
+		assert.Len(t, files, 6)
+	})
+
+	t.Run("Should reject an unsupported cell", func(t *testing.T) {
+		w, _ := NewWriter(io.Discard, "Sheet1")
+
+		err := w.WriteRow(struct{}{})
+
+		assert.EqualError(t, err, "xlsx: unsupported cell type struct {}")
+	})
+}